@@ -16,6 +16,7 @@ import (
 	"github.com/anisan-cli/anisan/constant"
 	"github.com/anisan-cli/anisan/filesystem"
 	"github.com/anisan-cli/anisan/icon"
+	"github.com/anisan-cli/anisan/provider"
 	"github.com/anisan-cli/anisan/style"
 	"github.com/anisan-cli/anisan/where"
 	levenshtein "github.com/ka-weihe/fast-levenshtein"
@@ -38,6 +39,7 @@ func errUnknownKey(key string) error {
 }
 
 func completionConfigKeys(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	provider.RegisterCustomOptions()
 	return lo.Keys(config.Default), cobra.ShellCompDirectiveNoFileComp
 }
 
@@ -49,6 +51,9 @@ func init() {
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Manage application configuration settings and defaults",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		provider.RegisterCustomOptions()
+	},
 }
 
 func init() {
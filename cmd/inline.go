@@ -7,18 +7,23 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"reflect"
 	"strings"
 
 	"github.com/anisan-cli/anisan/anilist"
 	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/health"
 	"github.com/anisan-cli/anisan/inline"
+	"github.com/anisan-cli/anisan/internal/tracker"
 	"github.com/anisan-cli/anisan/key"
 	"github.com/anisan-cli/anisan/mal"
 	"github.com/anisan-cli/anisan/provider"
+	"github.com/anisan-cli/anisan/provider/custom"
 	"github.com/anisan-cli/anisan/query"
 	"github.com/anisan-cli/anisan/source"
+	"github.com/anisan-cli/anisan/util"
 
 	"github.com/invopop/jsonschema"
 	"github.com/samber/lo"
@@ -41,6 +46,10 @@ func init() {
 	lo.Must0(viper.BindPFlag(key.TrackerFetchMetadata, inlineCmd.Flags().Lookup("fetch-metadata")))
 
 	inlineCmd.Flags().StringP("output", "o", "", "Specify a file path to write the command output")
+	inlineCmd.Flags().Int("page", 1, "Page of search results to fetch, for sources that paginate")
+	inlineCmd.Flags().StringArray("filter", nil, "Search filter as name=value, e.g. --filter translation=dub (repeatable)")
+	inlineCmd.Flags().String("audio", "", "Audio variant to list episodes for when a source offers several (e.g. sub, dub)")
+	lo.Must0(viper.BindPFlag(key.PlayerPreferAudio, inlineCmd.Flags().Lookup("audio")))
 
 	inlineCmd.RegisterFlagCompletionFunc("query", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return query.SuggestMany(toComplete), cobra.ShellCompDirectiveNoFileComp
@@ -64,9 +73,16 @@ Episode selectors:
   all - all episodes in the list
   [number] - select episode by index (starting from 0)
   [from]-[to] - select episodes by range
+  #[number] - select episodes by episode number, e.g. #25.5
+  #[from]-[to] - select episodes by episode number range, specials included
+  regular - regular episodes only
+  specials - specials, OVAs, recaps and movies only
   @[substring]@ - select episodes by name substring
 
-When using the json flag anime selector could be omitted. That way, it will select all animes`,
+When using the json flag anime selector could be omitted. That way, it will select all animes
+
+Use --page to fetch later pages of results and --filter name=value to apply
+the search filters a source declares`,
 
 	Example: "https://github.com/anisan-cli/anisan/wiki/Inline-mode",
 	PreRun: func(cmd *cobra.Command, args []string) {
@@ -82,6 +98,11 @@ When using the json flag anime selector could be omitted. That way, it will sele
 			err     error
 		)
 
+		// Without a terminal to ask on, loading fails and tells to run "sources grant"
+		if util.IsTerminal(os.Stdin) {
+			custom.PermissionPrompt = promptPermissions
+		}
+
 		for _, name := range viper.GetStringSlice(key.DefaultSources) {
 			if name == "" {
 				handleErr(errors.New("source not set"))
@@ -95,9 +116,16 @@ When using the json flag anime selector could be omitted. That way, it will sele
 			src, err := p.CreateSource()
 			handleErr(err)
 
+			if closer, ok := src.(io.Closer); ok {
+				defer closer.Close()
+			}
+
 			sources = append(sources, src)
 		}
 
+		// Sources that have been working lately are searched first
+		health.Sort(sources, source.Source.Name)
+
 		query := lo.Must(cmd.Flags().GetString("query"))
 
 		output := lo.Must(cmd.Flags().GetString("output"))
@@ -125,10 +153,15 @@ When using the json flag anime selector could be omitted. That way, it will sele
 			episodesFilter = mo.Some(fn)
 		}
 
+		filters, err := inline.ParseFilters(lo.Must(cmd.Flags().GetStringArray("filter")))
+		handleErr(err)
+
 		options := &inline.Options{
 			Sources:             sources,
 			Json:                lo.Must(cmd.Flags().GetBool("json")),
 			Query:               query,
+			Page:                lo.Must(cmd.Flags().GetInt("page")),
+			Filters:             filters,
 			IncludeAnilistAnime: lo.Must(cmd.Flags().GetBool("include-anilist-anime")),
 			IncludeMalAnime:     lo.Must(cmd.Flags().GetBool("include-mal-anime")),
 			AnimePicker:         animePicker,
@@ -137,7 +170,11 @@ When using the json flag anime selector could be omitted. That way, it will sele
 			Videos:              lo.Must(cmd.Flags().GetBool("include-videos")),
 		}
 
-		handleErr(inline.Run(options))
+		// Ctrl+C aborts the source calls in flight rather than leaving them to time out.
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		handleErr(inline.Run(ctx, options))
 	},
 }
 
@@ -343,6 +380,9 @@ func init() {
 
 	inlineTrackerBindCmd.Flags().StringP("name", "n", "", "The local anime title to establish a mapping for")
 	inlineTrackerBindCmd.Flags().IntP("id", "i", 0, "The remote Tracker ID to bind to the specified anime title")
+	inlineTrackerBindCmd.Flags().Int("from", 0, "First source episode counted by the tracker entry")
+	inlineTrackerBindCmd.Flags().Int("to", 0, "Last source episode counted by the tracker entry, 0 for no end")
+	inlineTrackerBindCmd.Flags().Int("offset", 0, "Number subtracted from source episodes to get the tracker episode, defaults to from - 1")
 
 	lo.Must0(inlineTrackerBindCmd.MarkFlagRequired("name"))
 	lo.Must0(inlineTrackerBindCmd.MarkFlagRequired("id"))
@@ -359,15 +399,38 @@ var inlineTrackerBindCmd = &cobra.Command{
 		animeName := lo.Must(cmd.Flags().GetString("name"))
 		searchId := lo.Must(cmd.Flags().GetInt("id"))
 
+		segment := tracker.Segment{
+			ID:     searchId,
+			From:   lo.Must(cmd.Flags().GetInt("from")),
+			To:     lo.Must(cmd.Flags().GetInt("to")),
+			Offset: lo.Must(cmd.Flags().GetInt("offset")),
+		}
+		if !cmd.Flags().Changed("offset") && segment.From > 0 {
+			segment.Offset = segment.From - 1
+		}
+		if segment.To != 0 && segment.To < segment.From {
+			handleErr(fmt.Errorf("invalid episode range %d-%d", segment.From, segment.To))
+		}
+
+		// Ranges past the first episode count towards another entry and keep the existing binding
 		if backend == "mal" {
 			malAnime, err := mal.GetByID(searchId)
 			handleErr(err)
-			handleErr(mal.SetRelation(animeName, malAnime))
+			if !segment.Split() {
+				handleErr(mal.SetRelation(animeName, malAnime))
+			}
 		} else {
 			anilistAnime, err := anilist.GetByID(searchId)
 			handleErr(err)
-			handleErr(anilist.SetRelation(animeName, anilistAnime))
+			if !segment.Split() {
+				handleErr(anilist.SetRelation(animeName, anilistAnime))
+			}
+		}
+
+		if !segment.Split() {
+			segment.ID = 0
 		}
+		handleErr(tracker.SetSegment(backend, animeName, segment))
 	},
 }
 
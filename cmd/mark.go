@@ -64,8 +64,23 @@ var markCmd = &cobra.Command{
 			log.Infof("Found %s (AniList ID: %d)", anime.Name(), mediaID)
 		}
 
+		// Translate the source episode through the ranges bound to the anime
+		targetID, targetEp, ok := tracker.Resolve(backend, query, mediaID, episodeIdx)
+		if !ok {
+			return fmt.Errorf("episode %d is not counted by any %s entry bound to %q", episodeIdx, backend, query)
+		}
+		if targetID != mediaID {
+			mediaID, totalEps = targetID, 0
+			if backend == "mal" {
+				if anime, err := mal.GetByID(mediaID); err == nil {
+					totalEps = anime.NumEpisodes
+				}
+			}
+			log.Infof("Episode %d counts as episode %d of ID %d", episodeIdx, targetEp, mediaID)
+		}
+
 		// Perform the unified sync
-		err := activeTracker.UpdateEpisodeProgress(ctx, mediaID, episodeIdx, totalEps)
+		err := activeTracker.UpdateEpisodeProgress(ctx, mediaID, targetEp, totalEps)
 		if err != nil {
 			if err.Error() == "sync_queued" {
 				fmt.Println("Network unavailable. Sync queued for background retry.")
@@ -74,7 +89,7 @@ var markCmd = &cobra.Command{
 			return err
 		}
 
-		fmt.Printf("Successfully marked episode %d on %s!\n", episodeIdx, backend)
+		fmt.Printf("Successfully marked episode %d on %s!\n", targetEp, backend)
 		return nil
 	},
 }
@@ -3,6 +3,7 @@ package cmd
 
 import (
 	"github.com/anisan-cli/anisan/mini"
+	"github.com/anisan-cli/anisan/provider/custom"
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +20,8 @@ var miniCmd = &cobra.Command{
 	Short: "Launch the application in a lightweight, minimalist terminal interface",
 	Long:  `Initialize a streamlined, minimalist terminal UI for anime selection and playback.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		custom.PermissionPrompt = promptPermissions
+
 		options := mini.Options{
 			Continue: lo.Must(cmd.Flags().GetBool("continue")),
 		}
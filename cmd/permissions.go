@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/anisan-cli/anisan/color"
+	"github.com/anisan-cli/anisan/log"
+	"github.com/anisan-cli/anisan/provider"
+	"github.com/anisan-cli/anisan/provider/custom"
+	"github.com/anisan-cli/anisan/style"
+)
+
+// promptPermissions asks whether a custom source may use the permissions it requests.
+// It writes to stderr, so that it does not mix with output piped from stdout.
+func promptPermissions(script string, requested custom.Permissions) bool {
+	fmt.Fprintf(os.Stderr, "%s requests access beyond the sandbox:\n", style.Fg(color.Yellow)(script))
+	if len(requested.Hosts) > 0 {
+		fmt.Fprintf(os.Stderr, "  hosts:   %v\n", requested.Hosts)
+	}
+	if len(requested.Modules) > 0 {
+		fmt.Fprintf(os.Stderr, "  modules: %v\n", requested.Modules)
+	}
+
+	var granted bool
+	confirm := survey.Confirm{
+		Message: fmt.Sprintf("Allow %s?", script),
+		Default: false,
+	}
+	if err := survey.AskOne(&confirm, &granted, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr)); err != nil {
+		return false
+	}
+
+	return granted
+}
+
+// reviewPermissions prompts for the permissions of every custom source that has none granted yet.
+// The TUI cannot prompt once it owns the terminal, so it is done before it starts.
+func reviewPermissions() {
+	for _, p := range provider.Customs() {
+		if p.Err != nil {
+			continue
+		}
+
+		custom.PermissionPrompt = promptPermissions
+		err := custom.ReviewPermissions(p.Path)
+		custom.PermissionPrompt = nil
+		if err != nil {
+			log.Warn(err)
+		}
+	}
+}
@@ -4,13 +4,16 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/anisan-cli/anisan/color"
 	"github.com/anisan-cli/anisan/constant"
+	"github.com/anisan-cli/anisan/health"
 	"github.com/anisan-cli/anisan/icon"
 	"github.com/anisan-cli/anisan/key"
 	"github.com/anisan-cli/anisan/log"
 	"github.com/anisan-cli/anisan/provider"
+	"github.com/anisan-cli/anisan/source"
 	"github.com/anisan-cli/anisan/style"
 	"github.com/anisan-cli/anisan/tui"
 	"github.com/anisan-cli/anisan/version"
@@ -50,6 +53,16 @@ func init() {
 
 	rootCmd.Flags().BoolP("continue", "c", false, "Resume playback from the most recent history entry")
 
+	// Every call made to a source feeds its health record, which in turn ranks sources and their results
+	source.Observe = func(s source.Source, latency time.Duration, err error) {
+		if err := health.Observe(s.Name(), latency, err); err != nil {
+			log.Warn(err)
+		}
+	}
+	source.Health = func(s source.Source) float64 {
+		return health.Score(s.Name())
+	}
+
 	helpFunc := rootCmd.HelpFunc()
 	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
 		helpFunc(cmd, args)
@@ -70,6 +83,9 @@ var rootCmd = &cobra.Command{
 		}
 
 		CheckDependencies()
+		if viper.GetBool(key.SourcesSandbox) {
+			reviewPermissions()
+		}
 
 		options := tui.Options{
 			Continue: lo.Must(cmd.Flags().GetBool("continue")),
@@ -91,14 +107,24 @@ func Execute() {
 		})
 	}
 
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	flushHealth()
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
 
+// flushHealth writes the health records of the calls made to sources before anisan exits.
+func flushHealth() {
+	if err := health.Flush(); err != nil {
+		log.Warn(err)
+	}
+}
+
 func handleErr(err error) {
 	if err != nil {
+		flushHealth()
 		log.Error(err)
 		_, _ = fmt.Fprintf(os.Stderr, "%s %s\n", icon.Get(icon.Fail), strings.Trim(err.Error(), " \n"))
 		os.Exit(1)
@@ -22,6 +22,8 @@ Optionally utilizes the internal environment as a standalone Lua interpreter.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		sourcePath := args[0]
 
+		custom.PermissionPrompt = promptPermissions
+
 		// Invoke the Lua interpreter to load and execute the target script.
 		_, err := custom.LoadSource(sourcePath)
 		handleErr(err)
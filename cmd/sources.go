@@ -3,23 +3,31 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"os/user"
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/anisan-cli/anisan/color"
+	"github.com/anisan-cli/anisan/config"
 	"github.com/anisan-cli/anisan/constant"
 	"github.com/anisan-cli/anisan/util"
 
 	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/health"
 	"github.com/anisan-cli/anisan/icon"
 	"github.com/anisan-cli/anisan/provider"
+	"github.com/anisan-cli/anisan/provider/custom"
+	"github.com/anisan-cli/anisan/source"
 	"github.com/anisan-cli/anisan/style"
 	"github.com/anisan-cli/anisan/where"
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func init() {
@@ -38,6 +46,7 @@ func init() {
 	sourcesListCmd.Flags().BoolP("raw", "r", false, "Suppress header and metadata descriptions in the output")
 	sourcesListCmd.Flags().BoolP("custom", "c", false, "Display only user-installed custom Lua sources")
 	sourcesListCmd.Flags().BoolP("builtin", "b", false, "Display only pre-compiled built-in sources")
+	sourcesListCmd.Flags().Bool("health", false, "Display the success rate, latency and last error of each source, healthiest first")
 
 	sourcesListCmd.MarkFlagsMutuallyExclusive("custom", "builtin")
 	sourcesListCmd.SetOut(os.Stdout)
@@ -49,6 +58,7 @@ var sourcesListCmd = &cobra.Command{
 	Short: "Display a collection of all registered scraping providers",
 	Run: func(cmd *cobra.Command, args []string) {
 		printHeader := !lo.Must(cmd.Flags().GetBool("raw"))
+		showHealth := lo.Must(cmd.Flags().GetBool("health"))
 		headerStyle := style.New().Foreground(color.HiBlue).Bold(true).Render
 		h := func(s string) {
 			if printHeader {
@@ -56,18 +66,66 @@ var sourcesListCmd = &cobra.Command{
 			}
 		}
 
+		printProvider := func(p *provider.Provider) {
+			if !printHeader {
+				if showHealth {
+					cmd.Printf("%s\t%s\n", p.Name, health.Get(p.Name))
+				} else {
+					cmd.Println(p.Name)
+				}
+				return
+			}
+
+			var details []string
+			if p.Version != "" {
+				details = append(details, "v"+p.Version)
+			}
+			if p.Language != "" {
+				details = append(details, p.Language)
+			}
+			if p.Author != "" {
+				details = append(details, "by "+p.Author)
+			}
+			if p.UsesHeadless {
+				details = append(details, "headless")
+			}
+
+			line := p.Name
+			if len(details) > 0 {
+				line += " " + style.Faint(strings.Join(details, " • "))
+			}
+			if p.Err != nil {
+				line += " " + style.Fg(color.Red)("unavailable: "+p.Err.Error())
+			}
+
+			if showHealth {
+				record := health.Get(p.Name)
+				line += "\n  " + record.String()
+				if record.Failing() {
+					line += "\n  " + style.Fg(color.Yellow)("failing: "+record.LastError)
+				}
+			}
+
+			cmd.Println(line)
+		}
+
+		printProviders := func(providers []*provider.Provider) {
+			if showHealth {
+				health.Sort(providers, func(p *provider.Provider) string { return p.Name })
+			}
+			for _, p := range providers {
+				printProvider(p)
+			}
+		}
+
 		printBuiltin := func() {
 			h("Builtin:")
-			for _, p := range provider.Builtins() {
-				cmd.Println(p.Name)
-			}
+			printProviders(provider.Builtins())
 		}
 
 		printCustom := func() {
 			h("Custom:")
-			for _, p := range provider.Customs() {
-				cmd.Println(p.Name)
-			}
+			printProviders(provider.Customs())
 		}
 
 		switch {
@@ -85,6 +143,264 @@ var sourcesListCmd = &cobra.Command{
 	},
 }
 
+func init() {
+	sourcesCmd.AddCommand(sourcesInfoCmd)
+	sourcesInfoCmd.SetOut(os.Stdout)
+}
+
+// sourcesInfoCmd displays the manifest of a scraping provider.
+var sourcesInfoCmd = &cobra.Command{
+	Use:   "info <name>",
+	Short: "Display the manifest and capabilities of a scraping provider",
+	Args:  cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return lo.Map(append(provider.Builtins(), provider.Customs()...), func(p *provider.Provider, _ int) string {
+			return p.Name
+		}), cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		p, ok := provider.Get(args[0])
+		if !ok {
+			handleErr(fmt.Errorf("source not found: %s", args[0]))
+		}
+
+		key := style.Fg(color.Blue)
+		field := func(name, value string) {
+			if value != "" {
+				cmd.Printf("%s %s\n", key(fmt.Sprintf("%-14s", name+":")), value)
+			}
+		}
+
+		kind := "Built-in"
+		if p.IsCustom {
+			kind = "Lua Extension"
+		}
+
+		field("Name", style.Fg(color.Yellow)(p.Name))
+		field("ID", p.ID)
+		field("Type", kind)
+		field("Version", p.Version)
+		field("Author", p.Author)
+		field("License", p.License)
+		field("URL", p.URL)
+		field("Language", p.Language)
+		field("Min. anisan", p.MinVersion)
+		field("Capabilities", strings.Join(p.Capabilities, ", "))
+		if p.IsCustom {
+			field("Permissions", p.Permissions.String())
+			if granted, err := custom.Granted(p.Name); err == nil && !p.Permissions.IsEmpty() {
+				field("Granted", lo.Ternary(granted.Covers(p.Permissions), "yes", style.Fg(color.Red)("no")))
+			}
+		}
+		for _, option := range p.Options {
+			value := fmt.Sprintf("%s = %v", config.SourceOptionKey(p.Name, option.Name), viper.Get(config.SourceOptionKey(p.Name, option.Name)))
+			if option.Description != "" {
+				value += " " + style.Faint("("+option.Description+")")
+			}
+			field("Option", value)
+		}
+		for _, mirror := range p.Mirrors {
+			value := mirror.Name + " = " + mirror.URLs[0]
+			if known, ok := custom.KnownMirror(p.Name, mirror); ok {
+				value = mirror.Name + " = " + known
+			}
+			if len(mirror.URLs) > 1 {
+				value += " " + style.Faint("(of "+strings.Join(mirror.URLs, ", ")+")")
+			}
+			field("Mirror", value)
+		}
+		if p.UsesHeadless {
+			field("Headless", "required")
+		}
+		field("Path", p.Path)
+
+		if p.Err != nil {
+			field("Status", style.Fg(color.Red)(p.Err.Error()))
+			return
+		}
+
+		src, err := p.CreateSource()
+		if err != nil {
+			field("Status", style.Fg(color.Red)(err.Error()))
+			return
+		}
+
+		if closer, ok := src.(io.Closer); ok {
+			defer util.Ignore(closer.Close)
+		}
+
+		for _, f := range source.FiltersOf(src) {
+			value := f.Name
+			if f.Label != "" {
+				value += " " + style.Faint("("+f.Label+")")
+			}
+			if len(f.Options) > 0 {
+				value += ": " + strings.Join(f.Options, ", ")
+			}
+			field("Filter", value)
+		}
+	},
+}
+
+func init() {
+	sourcesCmd.AddCommand(sourcesGrantCmd)
+	sourcesGrantCmd.Flags().BoolP("yes", "y", false, "Grant the permissions without asking")
+}
+
+// sourcesGrantCmd reviews and grants the permissions a custom source requests.
+var sourcesGrantCmd = &cobra.Command{
+	Use:   "grant <name>",
+	Short: "Review and grant the hosts and modules a custom source may use",
+	Args:  cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return lo.Map(provider.Customs(), func(p *provider.Provider, _ int) string {
+			return p.Name
+		}), cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		p, ok := provider.Get(args[0])
+		if !ok || !p.IsCustom {
+			handleErr(fmt.Errorf("custom source not found: %s", args[0]))
+		}
+
+		if p.Permissions.IsEmpty() {
+			fmt.Printf("%s %s needs no permissions\n", icon.Get(icon.Success), style.Fg(color.Yellow)(p.Name))
+			return
+		}
+
+		if !lo.Must(cmd.Flags().GetBool("yes")) && !promptPermissions(p.Name, p.Permissions) {
+			return
+		}
+
+		handleErr(custom.Grant(p.Name, p.Permissions))
+		fmt.Printf("%s granted %s: %s\n", icon.Get(icon.Success), style.Fg(color.Yellow)(p.Name), p.Permissions)
+	},
+}
+
+func init() {
+	sourcesCmd.AddCommand(sourcesTestCmd)
+
+	sourcesTestCmd.Flags().StringP("query", "q", "", "The search query to start the test with")
+	sourcesTestCmd.Flags().BoolP("record", "r", false, "Run against the network and record the responses to the cassette")
+	sourcesTestCmd.Flags().StringP("cassette", "c", "", "The cassette file, defaults to <name>.json in the cassettes directory")
+
+	lo.Must0(sourcesTestCmd.MarkFlagRequired("query"))
+}
+
+// sourcesTestCmd runs a custom source end to end against a recorded cassette.
+var sourcesTestCmd = &cobra.Command{
+	Use:   "test <name>",
+	Short: "Test a custom source offline against recorded responses",
+	Long: `Search with the given query, then fetch the episodes of the first anime found and the videos of its first episode,
+checking everything the source returns. With --record the requests of the source go to the network and are saved to a cassette,
+otherwise they are answered from that cassette without network.`,
+	Example: "  anisan sources test allanime -q naruto --record\n  anisan sources test allanime -q naruto",
+	Args:    cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return lo.Map(provider.Customs(), func(p *provider.Provider, _ int) string {
+			return p.Name
+		}), cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		p, ok := provider.Get(args[0])
+		if !ok || !p.IsCustom {
+			handleErr(fmt.Errorf("custom source not found: %s", args[0]))
+		}
+
+		path := lo.Must(cmd.Flags().GetString("cassette"))
+		if path == "" {
+			path = filepath.Join(where.Cassettes(), p.Name+".json")
+		}
+
+		var cassette *custom.Cassette
+		if lo.Must(cmd.Flags().GetBool("record")) {
+			cassette = custom.NewCassette(path)
+		} else {
+			var err error
+			cassette, err = custom.LoadCassette(path)
+			handleErr(err)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		custom.PermissionPrompt = promptPermissions
+		report, err := custom.Test(ctx, p.Path, lo.Must(cmd.Flags().GetString("query")), cassette)
+		handleErr(err)
+
+		if cassette.Recording() {
+			handleErr(cassette.Save())
+			fmt.Printf("%s recorded %d responses to %s\n", icon.Get(icon.Success), len(cassette.Interactions), cassette.Path())
+		}
+
+		for _, step := range report.Steps {
+			status := icon.Get(icon.Success)
+			summary := fmt.Sprintf("%d results", step.Results)
+			if !step.Passed() {
+				status = icon.Get(icon.Fail)
+			}
+			if len(step.Problems) > 0 {
+				summary += style.Fg(color.Red)(fmt.Sprintf(", %d problems", len(step.Problems)))
+			}
+
+			fmt.Printf("%s %s(%s) %s %s\n", status, style.Fg(color.Yellow)(step.Function), step.Input, summary, style.Faint(step.Duration.Round(time.Millisecond).String()))
+			if step.Err != nil {
+				fmt.Printf("    %s\n", style.Fg(color.Red)(step.Err.Error()))
+			}
+			for _, problem := range step.Problems {
+				fmt.Printf("    %s\n", problem)
+			}
+		}
+
+		if !report.Passed() {
+			handleErr(fmt.Errorf("source %s failed the test", p.Name))
+		}
+
+		fmt.Printf("%s source %s passed\n", icon.Get(icon.Success), style.Fg(color.Yellow)(p.Name))
+	},
+}
+
+func init() {
+	sourcesCmd.AddCommand(sourcesValidateCmd)
+}
+
+// sourcesValidateCmd statically checks a Lua source file.
+var sourcesValidateCmd = &cobra.Command{
+	Use:   "validate <file>",
+	Short: "Check a Lua source file or package for errors without running it",
+	Long: `Compile the file and check its manifest, the functions anisan requires
+and the shape of the tables they return. Problems are reported with their line numbers.`,
+	Example: "  anisan sources validate ./example.lua",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+		diagnostics, err := custom.Validate(path)
+		handleErr(err)
+
+		var errors int
+		for _, d := range diagnostics {
+			severity := style.Fg(color.Yellow)("warning")
+			if !d.Warning {
+				severity = style.Fg(color.Red)("error")
+				errors++
+			}
+
+			location := custom.ScriptPath(path)
+			if d.Line > 0 {
+				location = fmt.Sprintf("%s:%d", location, d.Line)
+			}
+
+			fmt.Printf("%s: %s: %s\n", location, severity, d.Message)
+		}
+
+		if errors > 0 {
+			handleErr(fmt.Errorf("%s has %s", path, util.Quantify(errors, "error", "errors")))
+		}
+
+		fmt.Printf("%s %s is valid\n", icon.Get(icon.Success), path)
+	},
+}
+
 func init() {
 	sourcesCmd.AddCommand(sourcesRemoveCmd)
 
@@ -97,6 +413,10 @@ func init() {
 
 		return lo.FilterMap(sources, func(item os.FileInfo, _ int) (string, bool) {
 			name := item.Name()
+			if item.IsDir() {
+				return name, !strings.HasPrefix(name, ".")
+			}
+
 			if !strings.HasSuffix(name, provider.CustomProviderExtension) {
 				return "", false
 			}
@@ -112,8 +432,14 @@ var sourcesRemoveCmd = &cobra.Command{
 	Short: "Permanently uninstall specified custom Lua sources from the system",
 	Run: func(cmd *cobra.Command, args []string) {
 		for _, name := range lo.Must(cmd.Flags().GetStringArray("name")) {
-			path := filepath.Join(where.Sources(), name+provider.CustomProviderExtension)
-			handleErr(filesystem.API().Remove(path))
+			// Packages are removed with all their modules
+			path := filepath.Join(where.Sources(), name)
+			if !custom.IsPackage(path) {
+				path += provider.CustomProviderExtension
+				handleErr(filesystem.API().Remove(path))
+			} else {
+				handleErr(filesystem.API().RemoveAll(path))
+			}
 			fmt.Printf("%s successfully removed %s\n", icon.Get(icon.Success), style.Fg(color.Yellow)(name))
 		}
 	},
@@ -166,13 +492,17 @@ var sourcesGenCmd = &cobra.Command{
 			URL             string
 			SearchAnimesFn  string
 			AnimeEpisodesFn string
+			EpisodeVideosFn string
 			Author          string
+			AnisanVersion   string
 		}{
 			Name:            lo.Must(cmd.Flags().GetString("name")),
 			URL:             lo.Must(cmd.Flags().GetString("url")),
 			SearchAnimesFn:  constant.SearchAnimesFn,
 			AnimeEpisodesFn: constant.AnimeEpisodesFn,
+			EpisodeVideosFn: constant.EpisodeVideosFn,
 			Author:          author,
+			AnisanVersion:   constant.Version,
 		}
 
 		funcMap := template.FuncMap{
@@ -2,6 +2,7 @@ package config
 
 import (
 	"strings"
+	"sync"
 
 	"github.com/anisan-cli/anisan/constant"
 	"github.com/anisan-cli/anisan/filesystem"
@@ -40,6 +41,30 @@ func Setup() error {
 	return nil
 }
 
+// SourceOptionKey returns the configuration key of an option declared by a custom source.
+func SourceOptionKey(source, name string) string {
+	return "sources." + source + "." + name
+}
+
+// RegisterSourceOption turns an option declared by a custom source into a configuration key,
+// so that users can set it like any other. Keys that already exist are left as they are.
+func RegisterSourceOption(source, name string, value any, description string) {
+	k := SourceOptionKey(source, name)
+
+	sourceOptionsMutex.Lock()
+	defer sourceOptionsMutex.Unlock()
+
+	if _, exists := Default[k]; exists {
+		return
+	}
+
+	Default[k] = Field{Key: k, Value: value, Description: description}
+	viper.SetDefault(k, value)
+	_ = viper.BindEnv(k)
+}
+
+var sourceOptionsMutex sync.Mutex
+
 func SetupTrackerDefaults() {
 	viper.SetDefault("tracker.backend", "anilist") // Options: "anilist" | "mal" | "none"
 	viper.SetDefault("tracker.enable", false)
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
 	"testing"
 
+	"github.com/anisan-cli/anisan/key"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/spf13/viper"
 )
@@ -30,3 +35,26 @@ func TestSetup(t *testing.T) {
 		})
 	})
 }
+
+func TestDefault(t *testing.T) {
+	Convey("Every key of the key package is registered with a default", t, func() {
+		file, err := parser.ParseFile(token.NewFileSet(), "../key/keys.go", nil, 0)
+		So(err, ShouldBeNil)
+
+		var keys []string
+		ast.Inspect(file, func(node ast.Node) bool {
+			if lit, ok := node.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				k, err := strconv.Unquote(lit.Value)
+				So(err, ShouldBeNil)
+				keys = append(keys, k)
+			}
+			return true
+		})
+
+		for _, k := range keys {
+			So(Default, ShouldContainKey, k)
+		}
+		So(Default, ShouldHaveLength, len(keys))
+		So(Default, ShouldHaveLength, key.DefinedFieldsCount)
+	})
+}
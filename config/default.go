@@ -95,6 +95,22 @@ func init() {
 	}
 
 	register(key.DefaultSources, []string{"allanime"}, "Default sources to use.\nWill prompt if not set.\nType \"anisan sources list\" to show available sources")
+	register(key.SourcesPoolSize, 4, "Maximum number of Lua VMs per custom source.\nHigher values let more requests to the same source run in parallel")
+	register(key.SourcesSandbox, true, "Run custom sources in a sandbox limited to the hosts and modules their manifest declares.\nDisable only for trusted scripts without permissions")
+	register(key.SourcesLibrary, []string{}, "Directories of downloaded video files listed by the built-in \"local\" source.\nFiles are grouped into animes by their release-style names, e.g. \"[Group] Title - 01 (1080p).mkv\"")
+	register(key.SourcesJellyfinURL, "", "Address of the Jellyfin server listed by the built-in \"jellyfin\" source, e.g. http://localhost:8096")
+	register(key.SourcesJellyfinAPIKey, "", "API key the \"jellyfin\" source authenticates with.\nCreate one in the Jellyfin dashboard under API Keys")
+	register(key.SourcesJellyfinUserID, "", "ID of the Jellyfin user whose progress is reported back to the server")
+	register(key.SourcesJellyfinReportProgress, false, "Report how far episodes of the \"jellyfin\" source were watched back to the server.\nRequires "+key.SourcesJellyfinUserID)
+	register(key.SourcesTimeout, 120, "Seconds a single search, episodes or videos call into a custom source may take. 0 disables the limit")
+	register(key.SourcesMaxInstructions, 500_000_000, "Lua instructions a single call into a custom source may execute. 0 disables the limit")
+	register(key.SourcesMaxStack, 64, "MiB of Lua value stack a custom source may use, which stops runaway recursion.\nStrings and tables are not counted: the memory of a source is not bounded. 0 keeps the Lua default")
+	register(key.StreamQuality, []string{"1080p", "720p", "480p", "360p"}, "Preferred stream qualities, best first.\nUnlisted qualities are tried afterwards, highest resolution first")
+	register(key.StreamContainer, "", "Preferred stream container when qualities are equal (e.g., mp4, m3u8)")
+	register(key.StreamAllowHosts, []string{}, "Only play streams from these hosts (and their subdomains). Empty allows all")
+	register(key.StreamDenyHosts, []string{}, "Never play streams from these hosts (and their subdomains)")
+	register(key.StreamAsk, false, "Ask which stream to play when several are available")
+	register(key.StreamProbeHLS, true, "Fetch HLS master playlists without a quality to list their variants")
 	register(key.TrackerFetchMetadata, true, "Fetch metadata from the active tracker\nIt will also cache the results to not spam the API")
 	register(key.MetadataTagRelevanceThreshold, 60, "Minimum relevance of a tag to be included. From 0 to 100")
 	register(key.MiniSearchLimit, 20, "Limit of search results to show")
@@ -120,6 +136,8 @@ func init() {
 	register(key.Aniskip, true, "Enable automatic introduction skipping (aniskip)")
 	register(key.Player, "mpv", "Media player to use (e.g., mpv, iina)")
 	register(key.PlayerCompletionPercentage, 80, "Percentage required to mark an episode as watched (1-100)")
+	register(key.PlayerPreferAudio, "sub", "Preferred audio variant when a source offers several (e.g., sub, dub)")
+	register(key.PlayerSubtitleLanguage, "en", "Preferred subtitle languages, comma-separated (e.g., en,eng)")
 }
 
 var prettyTemplate = lo.Must(template.New("pretty").Funcs(template.FuncMap{
@@ -1,17 +1,33 @@
 -----------------------------------------------------------------------
+-- @name    allanime
+-- @url     https://allanime.day
+-- @author  anisan-cli
+-- @license MIT
+-- @version 1.5.0
+-- @lang    en
+-- @min-anisan-version 0.1.0
+-- @capabilities search, episodes, videos, filters
+-- @permission hosts *.allanime.day, allanime.to, *s3taku*, *gogoplay*, *anitaku*, *gotaku1*
+-- @mirror  api     https://api.allanime.day/api
+-- @mirror  base    https://allanime.day
+-- @mirror  referer https://allmanga.to
+-----------------------------------------------------------------------
 -- AllAnime Scraper
 --
 -- Note: `http_tls` is a global injected by the Go Lua VM via
 -- registerTLSClient(). It exposes:
 --   http_tls.get(url [, headers_tbl]) → body (string)
 --   http_tls.request({method, url, headers, body}) → {status, body}
--- Errors are raised as Lua errors, NOT returned as second values.
+--   http_tls.batch({request, ...}) → {{status, body} or {error}, ...}
+-- Errors are raised as Lua errors, NOT returned as second values,
+-- except by http_tls.batch, which reports them in the failed results.
+--
+-- The AllAnime domains are the @mirror entries above, read with
+-- anisan.mirror.get(). Users can override them with
+-- `anisan config set sources.allanime.mirrors.<name> <url>`.
 -----------------------------------------------------------------------
 
 -- Constants
-local AllanimeAPI   = "https://api.allanime.day/api"
-local AllanimeBase  = "https://allanime.day"
-local AllanimeRefr  = "https://allmanga.to"
 local UA            = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:109.0) Gecko/20100101 Firefox/121.0"
 
 -- json is pre-loaded by mangal-lua-libs
@@ -87,23 +103,34 @@ end
 -----------------------------------------------------------------------
 local function gqlRequest(gql, variables)
     local params = "variables=" .. urlencode(json.encode(variables)) .. "&query=" .. urlencode(gql)
-    local fullUrl = AllanimeAPI .. "?" .. params
 
     -- http_tls.request returns a single table {status, body}
     -- Errors are raised as Lua errors (caught by pcall if needed)
-    local reqOk, res = pcall(function()
-        return http_tls.request({
-            method  = "GET",
-            url     = fullUrl,
-            headers = {
-                ["Referer"]    = AllanimeRefr,
-                ["User-Agent"] = UA,
-            },
-        })
-    end)
+    local function get(api)
+        return pcall(function()
+            return http_tls.request({
+                method  = "GET",
+                url     = api .. "?" .. params,
+                headers = {
+                    ["Referer"]    = anisan.mirror.get("referer"),
+                    ["User-Agent"] = UA,
+                },
+            })
+        end)
+    end
+
+    local api = anisan.mirror.get("api")
+    local reqOk, res = get(api)
+    if not reqOk then
+        -- The API may have moved, try the next mirror once
+        local other = anisan.mirror.failed("api", api)
+        if other ~= api then
+            reqOk, res = get(other)
+        end
+    end
 
     if not reqOk or not res or res.status ~= 200 then
-        -- error("Failed to request: " .. fullUrl)
+        -- error("Failed to request: " .. api)
         return nil
     end
 
@@ -119,40 +146,66 @@ end
 -----------------------------------------------------------------------
 -- Search
 -----------------------------------------------------------------------
-function SearchAnimes(query)
-    -- io.stderr:write("DEBUG: SearchAnimes called with " .. query .. "\n")
+local SearchLimit        = 100
+local ApiPagesPerRequest = 3
+
+function SearchFilters()
+    return {
+        { name = "translation", label = "Sub/Dub", options = { "sub", "dub" } },
+        { name = "country", label = "Country of origin", options = { "ALL", "JP", "CN", "KR" } },
+    }
+end
+
+function SearchAnimes(query, request)
+    request = request or {}
+    local filters = request.filters or {}
+    local translation = filters.translation or "sub"
+    local page = request.page or 1
+
     local gql = 'query( $search: SearchInput $limit: Int $page: Int $translationType: VaildTranslationTypeEnumType $countryOrigin: VaildCountryOriginEnumType ) { shows( search: $search limit: $limit page: $page translationType: $translationType countryOrigin: $countryOrigin ) { edges { _id name availableEpisodes __typename thumbnail } }}'
 
     local results = {}
-    
-    -- Fetch pages 1 to 3 to find more results (older shows like Code Geass TV often pushed back)
-    for page = 1, 3 do
+    local hasMore = false
+
+    -- Each requested page spans several API pages to find more results
+    -- (older shows like Code Geass TV are often pushed back)
+    local first = (page - 1) * ApiPagesPerRequest + 1
+    for apiPage = first, first + ApiPagesPerRequest - 1 do
         local data = gqlRequest(gql, {
             search = {
                 allowAdult = false,
                 allowUnknown = false,
                 query = query,
             },
-            limit = 100, 
-            page = page,
-            translationType = "sub",
-            countryOrigin = "ALL",
+            limit = SearchLimit,
+            page = apiPage,
+            translationType = translation,
+            countryOrigin = filters.country or "ALL",
         })
 
-        if data and data.data and data.data.shows and data.data.shows.edges then
-            for _, show in ipairs(data.data.shows.edges) do
-                local epCount = 0
-                if show.availableEpisodes and show.availableEpisodes.sub then
-                    epCount = tonumber(show.availableEpisodes.sub) or 0
-                end
-                
-                table.insert(results, {
-                    name  = show.name .. " (" .. epCount .. " eps)",
-                    url   = show._id,
-                    cover = show.thumbnail,
-                    epCount = epCount, -- Helper for sorting
-                })
+        local edges = data and data.data and data.data.shows and data.data.shows.edges
+        if not edges then
+            hasMore = false
+            break
+        end
+
+        for _, show in ipairs(edges) do
+            local epCount = 0
+            if show.availableEpisodes and show.availableEpisodes[translation] then
+                epCount = tonumber(show.availableEpisodes[translation]) or 0
             end
+
+            table.insert(results, {
+                name  = show.name .. " (" .. epCount .. " eps)",
+                url   = show._id,
+                cover = show.thumbnail,
+                epCount = epCount, -- Helper for sorting
+            })
+        end
+
+        hasMore = #edges >= SearchLimit
+        if not hasMore then
+            break
         end
     end
 
@@ -161,12 +214,15 @@ function SearchAnimes(query)
         return a.epCount > b.epCount
     end)
 
-    return results
+    return { animes = results, has_more = hasMore }
 end
 
 -----------------------------------------------------------------------
 -- Episodes
 -----------------------------------------------------------------------
+local Translations   = { "sub", "dub" }
+local AudioLanguages = { sub = "ja", dub = "en" }
+
 function AnimeEpisodes(anime_data)
     local showId
     if type(anime_data) == "table" then
@@ -184,101 +240,34 @@ function AnimeEpisodes(anime_data)
         return {}
     end
 
-    local details = data.data.show.availableEpisodesDetail.sub
+    local available = data.data.show.availableEpisodesDetail or {}
     local episodes = {}
 
-    if details then
-        for _, epStr in ipairs(details) do
-            table.insert(episodes, {
-                name   = "Episode " .. epStr,
-                url    = showId .. ":" .. epStr,
-                number = tonumber(epStr) or 0,
-            })
-        end
-
-        -- FORCE ASCENDING SORT
-        table.sort(episodes, function(a, b)
-            return a.number < b.number
-        end)
-    end
-
-    return episodes
-end
-
------------------------------------------------------------------------
--- Stream Extraction (ChapterPages)
------------------------------------------------------------------------
-
--- Priority order for stream providers.
-local providerPriority = { "Luf-Mp4", "Default", "S-mp4", "Yt-mp4", "Sak", "Kir" }
-
-function EpisodeVideos(episode_url)
-    -- If input is table (from custom provider), extract url field
-    if type(episode_url) == "table" then
-        episode_url = episode_url.url
-    end
-
-    -- url format: showId:episodeString
-    local parts = {}
-    for part in string.gmatch(episode_url, "([^:]+)") do
-        table.insert(parts, part)
-    end
-    local showId = parts[1]
-    local epStr  = parts[2]
-
-    local gql = 'query ($showId: String!, $translationType: VaildTranslationTypeEnumType!, $episodeString: String!) { episode( showId: $showId translationType: $translationType episodeString: $episodeString ) { episodeString sourceUrls }}'
-
-    local data = gqlRequest(gql, {
-        showId = showId,
-        translationType = "sub",
-        episodeString = epStr,
-    })
-
-    if not data or not data.data or not data.data.episode then
-        return {}
-    end
-
-    local sourceUrls = data.data.episode.sourceUrls
-    if not sourceUrls then
-        return {}
-    end
-
-    -- Build a map of sourceName → decrypted path
-    local sourceMap = {}
-    for _, source in ipairs(sourceUrls) do
-        local sName = source.sourceName or ""
-        local sUrl  = source.sourceUrl or ""
-        if string.sub(sUrl, 1, 2) == "--" then
-            local hex = string.sub(sUrl, 3)
-            local path = decrypt(hex)
-            if path and path ~= "" then
-                sourceMap[sName] = path
-            end
-        end
-    end
-
-    -- Try providers in priority order
-    for _, provName in ipairs(providerPriority) do
-        local path = sourceMap[provName]
-        if path then
-            local result = fetchProvider(path)
-            if result then
-                return result
+    -- Sub and dub are listed as separate variants of each episode
+    for _, translation in ipairs(Translations) do
+        local details = available[translation]
+        if details then
+            for _, epStr in ipairs(details) do
+                table.insert(episodes, {
+                    name        = "Episode " .. epStr,
+                    url         = showId .. ":" .. epStr .. ":" .. translation,
+                    number      = tonumber(epStr) or 0,
+                    translation = translation,
+                    language    = AudioLanguages[translation],
+                })
             end
         end
     end
 
-    -- Fallback: try any decrypted source
-    for _, path in pairs(sourceMap) do
-        if path then
-            local result = fetchProvider(path)
-            if result then
-                return result
-            end
+    -- FORCE ASCENDING SORT
+    table.sort(episodes, function(a, b)
+        if a.number ~= b.number then
+            return a.number < b.number
         end
-    end
+        return a.translation < b.translation
+    end)
 
-    return {}
+    return episodes
 end
 
 -----------------------------------------------------------------------
@@ -299,31 +288,34 @@ local function resolve_embed(url)
 end
 
 -----------------------------------------------------------------------
--- fetchProvider: given a decrypted path, fetch the actual video link.
--- The decrypted path is an endpoint on AllanimeBase.
--- It typically returns JSON with a `links` array.
+-- providerRequest: given a decrypted path, build the request for its
+-- video link. The decrypted path is an endpoint on the base mirror.
 -----------------------------------------------------------------------
-function fetchProvider(path)
+local function providerRequest(path)
     local embedUrl
     if string.sub(path, 1, 4) == "http" then
         embedUrl = path
     else
-        embedUrl = AllanimeBase .. path
+        embedUrl = anisan.mirror.get("base") .. path
     end
 
-    -- Use pcall because some providers may 404 or return garbage
-    local ok, res = pcall(function()
-        return http_tls.request({
-            method  = "GET",
-            url     = embedUrl,
-            headers = {
-                ["Referer"]    = AllanimeRefr,
-                ["User-Agent"] = UA,
-            },
-        })
-    end)
+    return {
+        method  = "GET",
+        url     = embedUrl,
+        headers = {
+            ["Referer"]    = anisan.mirror.get("referer"),
+            ["User-Agent"] = UA,
+        },
+    }
+end
 
-    if not ok or not res or not res.body then
+-----------------------------------------------------------------------
+-- readProvider: given the response of a provider request, extract the
+-- actual video link. It typically returns JSON with a `links` array.
+-----------------------------------------------------------------------
+local function readProvider(embedUrl, res)
+    -- Some providers may 404 or return garbage
+    if not res or res.error or not res.body then
         return nil
     end
 
@@ -362,7 +354,7 @@ function fetchProvider(path)
             return {{
                 url = finalLink,
                 headers = {
-                    ["Referer"] = AllanimeRefr,
+                    ["Referer"] = anisan.mirror.get("referer"),
                     ["User-Agent"] = UA,
                 },
             }}
@@ -374,7 +366,7 @@ function fetchProvider(path)
         return {{
             url = embedUrl,
             headers = {
-                ["Referer"] = AllanimeRefr,
+                ["Referer"] = anisan.mirror.get("referer"),
                 ["User-Agent"] = UA,
             },
         }}
@@ -383,8 +375,88 @@ function fetchProvider(path)
     return nil
 end
 
+-----------------------------------------------------------------------
+-- Stream Extraction (ChapterPages)
+-----------------------------------------------------------------------
 
+-- Priority order for stream providers.
+local providerPriority = { "Luf-Mp4", "Default", "S-mp4", "Yt-mp4", "Sak", "Kir" }
 
+function EpisodeVideos(episode_url)
+    -- If input is table (from custom provider), extract url field
+    if type(episode_url) == "table" then
+        episode_url = episode_url.url
+    end
 
+    -- url format: showId:episodeString[:translation]
+    local parts = {}
+    for part in string.gmatch(episode_url, "([^:]+)") do
+        table.insert(parts, part)
+    end
+    local showId      = parts[1]
+    local epStr       = parts[2]
+    local translation = parts[3] or "sub"
 
+    local gql = 'query ($showId: String!, $translationType: VaildTranslationTypeEnumType!, $episodeString: String!) { episode( showId: $showId translationType: $translationType episodeString: $episodeString ) { episodeString sourceUrls }}'
 
+    local data = gqlRequest(gql, {
+        showId = showId,
+        translationType = translation,
+        episodeString = epStr,
+    })
+
+    if not data or not data.data or not data.data.episode then
+        return {}
+    end
+
+    local sourceUrls = data.data.episode.sourceUrls
+    if not sourceUrls then
+        return {}
+    end
+
+    -- Build a map of sourceName → decrypted path
+    local sourceMap = {}
+    for _, source in ipairs(sourceUrls) do
+        local sName = source.sourceName or ""
+        local sUrl  = source.sourceUrl or ""
+        if string.sub(sUrl, 1, 2) == "--" then
+            local hex = string.sub(sUrl, 3)
+            local path = decrypt(hex)
+            if path and path ~= "" then
+                sourceMap[sName] = path
+            end
+        end
+    end
+
+    -- Providers in priority order, then any other decrypted source
+    local paths = {}
+    for _, provName in ipairs(providerPriority) do
+        if sourceMap[provName] then
+            table.insert(paths, sourceMap[provName])
+            sourceMap[provName] = nil
+        end
+    end
+    for _, path in pairs(sourceMap) do
+        table.insert(paths, path)
+    end
+
+    -- Query every provider at once and keep the first that answers, in order
+    local requests = {}
+    for i, path in ipairs(paths) do
+        requests[i] = providerRequest(path)
+    end
+    local responses = http_tls.batch(requests)
+
+    for i, req in ipairs(requests) do
+        local result = readProvider(req.url, responses[i])
+        if result then
+            for _, v in ipairs(result) do
+                v.translation = translation
+                v.language    = AudioLanguages[translation]
+            end
+            return result
+        end
+    end
+
+    return {}
+end
@@ -6,19 +6,78 @@ const (
 	SearchAnimesFn  = "SearchAnimes"
 	AnimeEpisodesFn = "AnimeEpisodes"
 	EpisodeVideosFn = "EpisodeVideos"
+
+	// SearchFiltersFn is optional and declares the search filters a scraper accepts.
+	SearchFiltersFn = "SearchFilters"
 )
 
 // SourceTemplate is a Go text/template for scaffolding new Lua scraper files.
+// The generated script loads as is and returns a sample fixture until its functions are filled in.
 const SourceTemplate = `{{ $divider := repeat "-" (plus (max (len .URL) (len .Name) (len .Author) 3) 12) }}{{ $divider }}
--- @name    {{ .Name }} 
+-- @name    {{ .Name }}
 -- @url     {{ .URL }}
--- @author  {{ .Author }} 
+-- @author  {{ .Author }}
 -- @license MIT
+-- @version 0.1.0
+-- @lang    en
+-- @min-anisan-version {{ .AnisanVersion }}
+-- @capabilities search, episodes, videos
 {{ $divider }}
 
 
----@alias anime { name: string, url: string, author: string|nil, genres: string|nil, summary: string|nil }
----@alias episode { name: string, url: string, volume: string|nil, anime_summary: string|nil, anime_author: string|nil, anime_genres: string|nil }
+---@class anime
+---@field name string Title shown to the user
+---@field url string Passed back to {{ .AnimeEpisodesFn }}, must be unique
+---@field summary string|nil
+---@field cover string|nil Cover image URL
+---@field banner string|nil Banner image URL
+---@field genres string|string[]|nil Comma separated or a list
+---@field status string|nil
+---@field synonyms string|string[]|nil Comma separated or a list
+
+---@class episode
+---@field name string Title shown to the user, the last number in it is the episode number
+---@field url string Passed back to {{ .EpisodeVideosFn }}, must be unique
+---@field number number|nil Episode number, used if the name has none. Specials may use fractions, e.g. 25.5
+---@field kind "regular"|"special"|"ova"|"recap"|"movie"|nil Guessed from the name if missing
+---@field volume string|nil
+---@field translation "sub"|"dub"|"raw"|nil
+---@field language string|nil Audio language as a BCP 47 tag, e.g. "ja"
+
+---@class subtitle
+---@field url string
+---@field language string|nil
+---@field format "vtt"|"srt"|"ass"|nil Guessed from the url if missing
+---@field label string|nil
+
+---@class video
+---@field url string Direct http(s) link to the stream
+---@field quality string|nil E.g. "1080p"
+---@field extension string|nil E.g. "m3u8" or "mp4"
+---@field headers table<string, string>|nil Sent with every request for the stream, e.g. Referer
+---@field subtitles subtitle[]|nil
+---@field translation "sub"|"dub"|"raw"|nil
+---@field language string|nil
+
+---@class request
+---@field query string
+---@field page number
+---@field cursor string|nil
+---@field filters table<string, string>
+
+--- An anime passed back to the functions below, with everything known about it.
+---@class known_anime : anime
+---@field id string
+---@field title string|nil Preferred title from the tracker
+---@field synonyms string[]
+---@field year number|nil
+---@field anilist_id number|nil
+---@field mal_id number|nil
+
+---@class known_episode : episode
+---@field id string
+---@field index number Position in the list returned by {{ .AnimeEpisodesFn }}
+---@field anime known_anime
 
 
 ----- IMPORTS -----
@@ -27,6 +86,20 @@ const SourceTemplate = `{{ $divider := repeat "-" (plus (max (len .URL) (len .Na
 
 
 ----- VARIABLES -----
+
+--- Sample fixture returned until the functions below scrape {{ .URL }}.
+--- The http_tls and anisan modules are described under "Lua API for Custom Sources" in the README.
+local fixture = {
+	anime = { name = "Sample Anime", url = "{{ .URL }}/anime/sample" },
+	episode = { name = "Episode 1", url = "{{ .URL }}/anime/sample/1", number = 1, translation = "sub" },
+	video = {
+		url = "https://test-streams.mux.dev/x36xhzz/x36xhzz.m3u8",
+		quality = "720p",
+		extension = "m3u8",
+		headers = { Referer = "{{ .URL }}" },
+	},
+}
+
 --- END VARIABLES ---
 
 
@@ -34,18 +107,28 @@ const SourceTemplate = `{{ $divider := repeat "-" (plus (max (len .URL) (len .Na
 ----- MAIN -----
 
 --- Searches for anime with given query.
--- @param query string Query to search for
--- @return anime[] Table of animes
-function {{ .SearchAnimesFn }}(query)
-	return {}
+---@param query string Query to search for
+---@param request request The full search request
+---@param known known_anime|nil The anime looked for, when the query is bound to a tracker entry
+---@return anime[]
+function {{ .SearchAnimesFn }}(query, request, known)
+	return { fixture.anime }
 end
 
 
 --- Gets the list of all anime episodes.
--- @param animeURL string URL of the anime
--- @return episode[] Table of episodes
-function {{ .AnimeEpisodesFn }}(animeURL)
-	return {}
+---@param anime known_anime The anime as returned by {{ .SearchAnimesFn }}
+---@return episode[]
+function {{ .AnimeEpisodesFn }}(anime)
+	return { fixture.episode }
+end
+
+
+--- Gets the streams of an episode.
+---@param episode known_episode The episode as returned by {{ .AnimeEpisodesFn }}
+---@return video[]
+function {{ .EpisodeVideosFn }}(episode)
+	return { fixture.video }
 end
 
 
@@ -0,0 +1,214 @@
+// Package health keeps track of how reliably each source answers, so that working sources are preferred.
+package health
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/log"
+	"github.com/anisan-cli/anisan/where"
+	"github.com/metafates/gache"
+)
+
+// window is the number of calls after which older outcomes start to weigh less,
+// so that a source recovering from an outage climbs back up.
+const window = 50
+
+// slow is the latency at which a source loses half of its score.
+const slow = 10 * time.Second
+
+// Record summarizes the calls made to a source.
+type Record struct {
+	Successes float64 `json:"successes"`
+	Failures  float64 `json:"failures"`
+	// Latency is the moving average duration of successful calls.
+	Latency       time.Duration `json:"latency"`
+	LastError     string        `json:"last_error,omitempty"`
+	LastErrorAt   time.Time     `json:"last_error_at"`
+	LastSuccessAt time.Time     `json:"last_success_at"`
+}
+
+// Calls returns the number of calls the record is made of.
+func (r Record) Calls() float64 {
+	return r.Successes + r.Failures
+}
+
+// SuccessRate returns the fraction of calls that succeeded, from 0 to 1.
+func (r Record) SuccessRate() float64 {
+	if r.Calls() == 0 {
+		return 0
+	}
+	return r.Successes / r.Calls()
+}
+
+// Failing reports whether the most recent call failed.
+func (r Record) Failing() bool {
+	return !r.LastErrorAt.IsZero() && r.LastErrorAt.After(r.LastSuccessAt)
+}
+
+// Score rates the source from 0 to 1, higher is better.
+// Sources without any calls score 0.5, fast sources score higher than slow ones
+// and a source whose last call failed loses half of its score.
+func (r Record) Score() float64 {
+	score := (r.Successes + 1) / (r.Calls() + 2)
+	score /= 1 + float64(r.Latency)/float64(slow)
+
+	if r.Failing() {
+		score /= 2
+	}
+
+	return score
+}
+
+// String summarizes the record, e.g. "92% of 25 calls, 1.2s".
+func (r Record) String() string {
+	if r.Calls() == 0 {
+		return "no calls yet"
+	}
+
+	parts := []string{fmt.Sprintf("%.0f%% of %.0f calls", r.SuccessRate()*100, r.Calls())}
+	if r.Latency > 0 {
+		precision := time.Millisecond
+		if r.Latency < time.Millisecond {
+			precision = time.Microsecond
+		}
+		parts = append(parts, r.Latency.Round(precision).String())
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// saveDelay is how long the records are kept in memory after a call before they are
+// written, so that the many calls of a search are saved at once.
+const saveDelay = 5 * time.Second
+
+var (
+	mutex   sync.Mutex
+	records = gache.New[map[string]*Record](
+		&gache.Options{
+			Path:       where.SourceHealth(),
+			FileSystem: &filesystem.GacheFs{},
+		},
+	)
+	// current holds the records once read, with the calls not written yet.
+	current map[string]*Record
+	// pending writes the records once saveDelay has passed, if set.
+	pending *time.Timer
+)
+
+func load() (map[string]*Record, error) {
+	if current != nil {
+		return current, nil
+	}
+
+	cached, expired, err := records.Get()
+	if err != nil {
+		return nil, err
+	}
+	if expired || cached == nil {
+		cached = make(map[string]*Record)
+	}
+
+	current = cached
+	return current, nil
+}
+
+// Observe records the outcome of a call made to the named source.
+// Records are written shortly after, or by Flush.
+func Observe(name string, latency time.Duration, err error) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	all, loadErr := load()
+	if loadErr != nil {
+		return loadErr
+	}
+
+	record, ok := all[name]
+	if !ok {
+		record = &Record{}
+		all[name] = record
+	}
+
+	if record.Calls() >= window {
+		record.Successes /= 2
+		record.Failures /= 2
+	}
+
+	now := time.Now()
+	if err != nil {
+		record.Failures++
+		record.LastError = err.Error()
+		record.LastErrorAt = now
+	} else {
+		record.Successes++
+		record.LastSuccessAt = now
+		if record.Latency == 0 {
+			record.Latency = latency
+		} else {
+			record.Latency = (record.Latency*3 + latency) / 4
+		}
+	}
+
+	if pending == nil {
+		pending = time.AfterFunc(saveDelay, func() {
+			if err := Flush(); err != nil {
+				log.Warn(err)
+			}
+		})
+	}
+
+	return nil
+}
+
+// Flush writes the calls observed since the last write. It is called before anisan exits.
+func Flush() error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if pending == nil {
+		return nil
+	}
+	pending.Stop()
+	pending = nil
+
+	return records.Set(current)
+}
+
+// Get returns the record of the named source. Sources never called have an empty record.
+func Get(name string) Record {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	all, err := load()
+	if err != nil {
+		return Record{}
+	}
+
+	if record, ok := all[name]; ok {
+		return *record
+	}
+
+	return Record{}
+}
+
+// Score returns the score of the named source.
+func Score(name string) float64 {
+	return Get(name).Score()
+}
+
+// Sort orders items from the healthiest source down, keeping the order of equally healthy ones.
+func Sort[T any](items []T, name func(T) string) {
+	scores := make(map[string]float64, len(items))
+	for _, item := range items {
+		scores[name(item)] = Score(name(item))
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return scores[name(items[i])] > scores[name(items[j])]
+	})
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/where"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func init() {
+	filesystem.SetMemMapFs()
+}
+
+func TestHealth(t *testing.T) {
+	Convey("Given a source that was never called", t, func() {
+		record := Get("untouched")
+
+		Convey("Then it has a neutral score", func() {
+			So(record.Calls(), ShouldEqual, 0)
+			So(record.Failing(), ShouldBeFalse)
+			So(record.Score(), ShouldEqual, 0.5)
+		})
+	})
+
+	Convey("Given calls made to a source", t, func() {
+		So(Observe("flaky", time.Second, nil), ShouldBeNil)
+		So(Observe("flaky", 3*time.Second, nil), ShouldBeNil)
+		So(Observe("flaky", time.Second, errors.New("403 forbidden")), ShouldBeNil)
+
+		record := Get("flaky")
+
+		Convey("Then their outcomes are recorded", func() {
+			So(record.Successes, ShouldEqual, 2)
+			So(record.Failures, ShouldEqual, 1)
+			So(record.SuccessRate(), ShouldAlmostEqual, 2.0/3)
+			So(record.Latency, ShouldEqual, 1500*time.Millisecond)
+			So(record.LastError, ShouldEqual, "403 forbidden")
+		})
+
+		Convey("Then the source is failing until it answers again", func() {
+			So(record.Failing(), ShouldBeTrue)
+			So(record.Score(), ShouldBeLessThan, Score("untouched"))
+
+			So(Observe("flaky", time.Second, nil), ShouldBeNil)
+			So(Get("flaky").Failing(), ShouldBeFalse)
+		})
+	})
+
+	Convey("Given many calls made to a source", t, func() {
+		for i := 0; i < window; i++ {
+			So(Observe("outage", time.Second, errors.New("timeout")), ShouldBeNil)
+		}
+		So(Observe("outage", time.Second, nil), ShouldBeNil)
+
+		Convey("Then older outcomes weigh less", func() {
+			So(Get("outage").Calls(), ShouldEqual, window/2+1)
+		})
+	})
+
+	Convey("Given sources of different health", t, func() {
+		So(Observe("working", 500*time.Millisecond, nil), ShouldBeNil)
+		So(Observe("broken", time.Second, errors.New("no such host")), ShouldBeNil)
+
+		names := []string{"broken", "unknown", "working"}
+		Sort(names, func(name string) string { return name })
+
+		Convey("Then sorting puts the working ones first", func() {
+			So(names, ShouldResemble, []string{"working", "unknown", "broken"})
+		})
+	})
+
+	Convey("Given a call observed since the last write", t, func() {
+		So(Flush(), ShouldBeNil)
+		before, _ := filesystem.API().ReadFile(where.SourceHealth())
+		So(Observe("pending", time.Second, nil), ShouldBeNil)
+
+		Convey("Then it is only written once flushed", func() {
+			unchanged, _ := filesystem.API().ReadFile(where.SourceHealth())
+			So(string(unchanged), ShouldEqual, string(before))
+
+			So(Flush(), ShouldBeNil)
+			written, err := filesystem.API().ReadFile(where.SourceHealth())
+			So(err, ShouldBeNil)
+			So(string(written), ShouldContainSubstring, "pending")
+		})
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/anisan-cli/anisan/source"
+	"github.com/samber/lo"
 )
 
 // SavedEpisode represents a single playback entry preserved in the user's history.
@@ -16,12 +17,15 @@ type SavedEpisode struct {
 	URL                string   `json:"url"`
 	ID                 string   `json:"id"`
 	Index              int      `json:"index"`
+	Number             float64  `json:"number,omitempty"` // Episode number as the source counts it, e.g. 25.5.
+	Kind               string   `json:"kind,omitempty"`   // Kind of the episode, empty for regular ones.
 	AnimeID            string   `json:"anime_id"`
 	WatchedPercentage  float64  `json:"watched_percentage"`
 	Score              int      `json:"score"`
 	Status             string   `json:"status"`
 	Genres             []string `json:"genres"`
-	CoverURL           string   `json:"cover_url"` // Persistent high-fidelity cover image URL for offline viewing.
+	CoverURL           string   `json:"cover_url"`             // Persistent high-fidelity cover image URL for offline viewing.
+	Translation        string   `json:"translation,omitempty"` // Audio variant that was watched (e.g. "sub", "dub").
 
 	// Metadata contains technical details populated at runtime; not persisted to disk.
 	Metadata *source.Metadata `json:"-"`
@@ -33,7 +37,46 @@ func (s *SavedEpisode) encode() string {
 }
 
 func (s *SavedEpisode) String() string {
-	return fmt.Sprintf("%s : %d / %d", s.AnimeName, s.Index, s.AnimeEpisodesTotal)
+	return fmt.Sprintf("%s : %s / %d", s.AnimeName, s.EpisodeNumber(), s.AnimeEpisodesTotal)
+}
+
+// EpisodeNumber formats the number of the saved episode, followed by its kind for specials.
+func (s *SavedEpisode) EpisodeNumber() string {
+	// Entries saved before episode numbers were tracked hold the number in Index
+	number := source.FormatEpisodeNumber(float64(s.Index))
+	if s.Number > 0 {
+		number = source.FormatEpisodeNumber(s.Number)
+	}
+
+	if s.Kind != "" {
+		return number + " " + s.Kind
+	}
+	return number
+}
+
+// Matches reports whether the live episode is the one this entry was saved from.
+// Entries saved before translations were tracked fall back to the episode number.
+func (s *SavedEpisode) Matches(episode *source.Episode) bool {
+	if episode.URL == s.URL {
+		return true
+	}
+
+	if s.Translation != "" {
+		return false
+	}
+
+	return s.MatchesNumber(episode)
+}
+
+// MatchesNumber reports whether the live episode has the number and kind of this entry,
+// whatever its url and audio variant.
+func (s *SavedEpisode) MatchesNumber(episode *source.Episode) bool {
+	if s.Number > 0 {
+		return episode.Number == s.Number && string(episode.Kind) == s.Kind
+	}
+
+	// Entries saved before episode numbers were tracked hold the number in Index
+	return episode.Number == float64(s.Index)
 }
 
 // newSavedEpisode constructs a new persistent history entry from a live episode source,
@@ -47,8 +90,11 @@ func newSavedEpisode(episode *source.Episode) *SavedEpisode {
 		URL:                episode.URL,
 		ID:                 episode.ID,
 		AnimeID:            episode.Anime.ID,
-		AnimeEpisodesTotal: len(episode.Anime.Episodes),
+		AnimeEpisodesTotal: lo.CountBy(source.FilterTranslation(episode.Anime.Episodes, episode.Translation), (*source.Episode).IsRegular),
 		Index:              int(episode.Index),
+		Number:             episode.Number,
+		Kind:               string(episode.Kind),
+		Translation:        episode.Translation,
 	}
 
 	saved.Score = episode.Anime.Metadata.Score
@@ -38,10 +38,11 @@ func init() {
 func TestHistory(t *testing.T) {
 	Convey("Given a episode", t, func() {
 		episode := source.Episode{
-			Name:  "adwad",
-			URL:   "dwaofa",
-			Index: 42069,
-			ID:    "fawfa",
+			Name:        "adwad",
+			URL:         "dwaofa",
+			Index:       42069,
+			ID:          "fawfa",
+			Translation: source.TranslationDub,
 		}
 		anime := source.Anime{
 			Name:     "dawf",
@@ -62,9 +63,39 @@ func TestHistory(t *testing.T) {
 					episodes, err := Get()
 					So(err, ShouldBeNil)
 					So(len(episodes), ShouldBeGreaterThan, 0)
-					So(episodes[fmt.Sprintf("%s (%s)", episode.Anime.Name, episode.Source().ID())].Name, ShouldEqual, episode.Name)
+					saved := episodes[fmt.Sprintf("%s (%s)", episode.Anime.Name, episode.Source().ID())]
+					So(saved.Name, ShouldEqual, episode.Name)
+					So(saved.Translation, ShouldEqual, source.TranslationDub)
 				})
 			})
 		})
 	})
 }
+
+func TestSavedEpisodeMatches(t *testing.T) {
+	Convey("Given saved episodes", t, func() {
+		special := &source.Episode{URL: "ep-25.5", Number: 25.5, Kind: source.KindSpecial}
+		regular := &source.Episode{URL: "ep-25", Number: 25}
+
+		Convey("Specials do not match the regular episode of the same number", func() {
+			saved := &SavedEpisode{URL: "old-url", Number: 25.5, Kind: string(source.KindSpecial)}
+			So(saved.Matches(special), ShouldBeTrue)
+			So(saved.Matches(regular), ShouldBeFalse)
+			So(saved.EpisodeNumber(), ShouldEqual, "25.5 special")
+		})
+
+		Convey("Entries saved before numbers were tracked match by their index", func() {
+			saved := &SavedEpisode{URL: "old-url", Index: 25}
+			So(saved.Matches(regular), ShouldBeTrue)
+			So(saved.Matches(special), ShouldBeFalse)
+			So(saved.EpisodeNumber(), ShouldEqual, "25")
+		})
+
+		Convey("Entries of another audio variant match by number only", func() {
+			saved := &SavedEpisode{URL: "ep-25-dub", Number: 25, Translation: "dub"}
+			So(saved.Matches(regular), ShouldBeFalse)
+			So(saved.MatchesNumber(regular), ShouldBeTrue)
+			So(saved.MatchesNumber(special), ShouldBeFalse)
+		})
+	})
+}
@@ -2,6 +2,8 @@
 package inline
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -9,24 +11,51 @@ import (
 	"github.com/anisan-cli/anisan/key"
 	"github.com/anisan-cli/anisan/log"
 	"github.com/anisan-cli/anisan/source"
+	"github.com/anisan-cli/anisan/stream"
 	"github.com/anisan-cli/anisan/where"
 	"github.com/spf13/viper"
 )
 
-func Run(options *Options) error {
+// Run executes the inline pipeline. Cancelling ctx aborts any source call in flight.
+func Run(ctx context.Context, options *Options) error {
 	fmt.Fprintf(os.Stderr, "DEBUG: Looking for sources in: %s\n", where.Sources())
 	if options.Out == nil {
 		options.Out = os.Stdout
 	}
 
 	// Step 1: Execute concurrent searches across all configured providers.
-	var animes []*source.Anime
+	var (
+		animes []*source.Anime
+		failed []error
+		known  = source.KnownAnime(options.Query)
+	)
 	for _, src := range options.Sources {
-		m, err := src.Search(options.Query)
+		if err := source.ValidateFilters(src, options.Filters); err != nil {
+			return err
+		}
+
+		page, err := source.SearchPageOf(ctx, src, source.SearchRequest{
+			Query:   options.Query,
+			Page:    options.Page,
+			Filters: options.Filters,
+			Anime:   known,
+		})
 		if err != nil {
-			return fmt.Errorf("search failed for %s: %w", src.Name(), err)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			// A failing source is only fatal when no other source answered
+			err = fmt.Errorf("search failed for %s: %w", src.Name(), err)
+			log.Warn(err)
+			failed = append(failed, err)
+			continue
 		}
-		animes = append(animes, m...)
+		animes = append(animes, page.Animes...)
+	}
+
+	if len(failed) > 0 && len(failed) == len(options.Sources) {
+		return errors.Join(failed...)
 	}
 
 	// Step 2: Apply anime selection logic if a picker is defined.
@@ -49,7 +78,7 @@ func Run(options *Options) error {
 
 	// Step 3: Populate metadata and retrieve episodes for the selected subset of anime.
 	for _, anime := range selected {
-		if err := prepareAnime(anime, options); err != nil {
+		if err := prepareAnime(ctx, anime, options); err != nil {
 			return err
 		}
 	}
@@ -66,6 +95,7 @@ func Run(options *Options) error {
 		for _, ep := range anime.Episodes {
 			log.Info("Found " + ep.Name)
 			if options.Videos && len(ep.Videos) > 0 {
+				// Preferred stream first
 				for _, v := range ep.Videos {
 					fmt.Fprintln(options.Out, v.URL)
 				}
@@ -78,7 +108,7 @@ func Run(options *Options) error {
 	return nil
 }
 
-func prepareAnime(anime *source.Anime, options *Options) error {
+func prepareAnime(ctx context.Context, anime *source.Anime, options *Options) error {
 	// Resolve agnostic metadata fetch trigger (supporting both unified and legacy keys)
 	fetchMetadata := viper.GetBool("tracker.fetch_metadata") || viper.GetBool(key.TrackerFetchMetadata)
 
@@ -101,11 +131,18 @@ func prepareAnime(anime *source.Anime, options *Options) error {
 	}
 
 	// Episodes
-	episodes, err := anime.Source.EpisodesOf(anime)
+	episodes, err := source.EpisodesOf(ctx, anime)
 	if err != nil {
 		return err
 	}
 
+	// Selectors pick from the episodes in the order they aired, specials after their episode
+	source.SortEpisodes(episodes)
+
+	// Keep a single audio variant so episode filters select unambiguously
+	translation := source.PreferredTranslation(source.Translations(episodes), viper.GetString(key.PlayerPreferAudio))
+	episodes = source.FilterTranslation(episodes, translation)
+
 	// Filter Episodes
 	if options.EpisodesFilter.IsPresent() {
 		filter := options.EpisodesFilter.MustGet()
@@ -118,15 +155,22 @@ func prepareAnime(anime *source.Anime, options *Options) error {
 		anime.Episodes = episodes
 	}
 
-	// Videos
+	// Videos, ranked by the stream policy with excluded hosts dropped
 	if options.Videos {
+		policy := stream.PolicyFromConfig()
 		for _, ep := range anime.Episodes {
-			videos, err := anime.Source.VideosOf(ep)
+			videos, err := source.VideosOf(ctx, ep)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if source.IsBudgetError(err) {
+				return err
+			}
 			if err != nil {
 				log.Warnf("failed to fetch videos for %s: %v", ep.Name, err)
 				continue
 			}
-			ep.Videos = videos
+			ep.Videos = policy.Rank(stream.ProbeFromConfig(ctx, videos))
 		}
 	}
 
@@ -134,7 +178,7 @@ func prepareAnime(anime *source.Anime, options *Options) error {
 }
 
 func writeJson(out io.Writer, animes []*source.Anime, options *Options) error {
-	data, err := asJson(animes, options.Query, options.IncludeAnilistAnime, options.IncludeMalAnime)
+	data, err := asJson(animes, options.Query, options.Page, options.IncludeAnilistAnime, options.IncludeMalAnime)
 	if err != nil {
 		return err
 	}
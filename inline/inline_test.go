@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/anisan-cli/anisan/source"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -22,5 +23,87 @@ func TestWriteJsonResponse(t *testing.T) {
 			So(output.Query, ShouldEqual, "test")
 			So(output.Result, ShouldHaveLength, 0)
 		})
+
+		Convey("Should include the subtitle tracks of videos", func() {
+			anime := &source.Anime{Name: "Test", Source: testSource{}}
+			anime.Episodes = []*source.Episode{{
+				Name: "Episode 1",
+				Videos: []*source.Video{{
+					URL:       "https://example.com/video.m3u8",
+					Subtitles: []*source.Subtitle{{URL: "https://example.com/en.vtt", Language: "en", Format: "vtt"}},
+				}},
+			}}
+
+			var buf bytes.Buffer
+			err := writeJson(&buf, []*source.Anime{anime}, &Options{Json: true})
+			So(err, ShouldBeNil)
+
+			var output Output
+			So(json.Unmarshal(buf.Bytes(), &output), ShouldBeNil)
+			subtitles := output.Result[0].Anime.Episodes[0].Videos[0].Subtitles
+			So(subtitles, ShouldHaveLength, 1)
+			So(subtitles[0].Language, ShouldEqual, "en")
+			So(subtitles[0].Format, ShouldEqual, "vtt")
+		})
+	})
+}
+
+type testSource struct{}
+
+func (testSource) Name() string                                        { return "test" }
+func (testSource) ID() string                                          { return "test" }
+func (testSource) Search(string) ([]*source.Anime, error)              { return nil, nil }
+func (testSource) EpisodesOf(*source.Anime) ([]*source.Episode, error) { return nil, nil }
+func (testSource) VideosOf(*source.Episode) ([]*source.Video, error)   { return nil, nil }
+
+func TestParseFilters(t *testing.T) {
+	Convey("ParseFilters", t, func() {
+		Convey("Should parse name=value assignments", func() {
+			filters, err := ParseFilters([]string{"translation=dub", " year = 2020"})
+			So(err, ShouldBeNil)
+			So(filters, ShouldResemble, map[string]string{"translation": "dub", "year": "2020"})
+		})
+
+		Convey("Should reject assignments without a name", func() {
+			_, err := ParseFilters([]string{"=dub"})
+			So(err, ShouldNotBeNil)
+
+			_, err = ParseFilters([]string{"dub"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestParseEpisodesFilter(t *testing.T) {
+	Convey("ParseEpisodesFilter", t, func() {
+		episodes := []*source.Episode{
+			{Name: "Episode 1", Number: 1},
+			{Name: "Episode 2", Number: 2},
+			{Name: "Episode 2.5", Number: 2.5, Kind: source.KindSpecial},
+			{Name: "Episode 3", Number: 3},
+		}
+
+		apply := func(description string) []*source.Episode {
+			filter, err := ParseEpisodesFilter(description)
+			So(err, ShouldBeNil)
+			selected, err := filter(episodes)
+			So(err, ShouldBeNil)
+			return selected
+		}
+
+		Convey("Should select by episode number", func() {
+			So(apply("#2.5"), ShouldResemble, episodes[2:3])
+			So(apply("#2-3"), ShouldResemble, episodes[1:])
+		})
+
+		Convey("Should select by kind", func() {
+			So(apply("specials"), ShouldResemble, episodes[2:3])
+			So(apply("regular"), ShouldHaveLength, 3)
+		})
+
+		Convey("Should reject invalid numbers", func() {
+			_, err := ParseEpisodesFilter("#two")
+			So(err, ShouldNotBeNil)
+		})
 	})
 }
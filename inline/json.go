@@ -22,10 +22,11 @@ type Anime struct {
 
 type Output struct {
 	Query  string   `json:"query"`
+	Page   int      `json:"page,omitempty"`
 	Result []*Anime `json:"result"`
 }
 
-func asJson(animes []*source.Anime, query string, includeAnilist, includeMal bool) ([]byte, error) {
+func asJson(animes []*source.Anime, query string, page int, includeAnilist, includeMal bool) ([]byte, error) {
 	var result = make([]*Anime, len(animes))
 	for i, a := range animes {
 		var al *anilist.Anime
@@ -48,6 +49,7 @@ func asJson(animes []*source.Anime, query string, includeAnilist, includeMal boo
 
 	return json.Marshal(&Output{
 		Query:  query,
+		Page:   page,
 		Result: result,
 	})
 }
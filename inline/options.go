@@ -25,6 +25,8 @@ type Options struct {
 	IncludeMalAnime     bool
 	Json                bool
 	Query               string
+	Page                int               // 1-based page of search results to fetch
+	Filters             map[string]string // Search filters declared by the sources
 	AnimePicker         mo.Option[AnimePicker]
 	EpisodesFilter      mo.Option[EpisodesFilter]
 	Videos              bool
@@ -72,8 +74,26 @@ func ParseAnimePicker(kind, value string) (AnimePicker, error) {
 	}
 }
 
+// ParseFilters parses "name=value" search filter assignments.
+func ParseFilters(assignments []string) (map[string]string, error) {
+	filters := make(map[string]string, len(assignments))
+	for _, a := range assignments {
+		name, value, ok := strings.Cut(a, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid filter %q, expected name=value", a)
+		}
+
+		filters[name] = strings.TrimSpace(value)
+	}
+
+	return filters, nil
+}
+
 // ParseEpisodesFilter parses legacy string description of filter
 // Format: "first", "last", "all", "From 1 To 5", "Sub 'Search'"
+// Episodes can also be picked by their number with "#25.5" or "#1-12",
+// and by kind with "regular" or "specials".
 // This logic is kept compatible with legacy CLI args for now
 func ParseEpisodesFilter(description string) (EpisodesFilter, error) {
 	if description == "first" {
@@ -98,6 +118,35 @@ func ParseEpisodesFilter(description string) (EpisodesFilter, error) {
 		}, nil
 	}
 
+	if description == "regular" || description == "specials" {
+		regular := description == "regular"
+		return func(episodes []*source.Episode) ([]*source.Episode, error) {
+			return lo.Filter(episodes, func(e *source.Episode, _ int) bool {
+				return e.IsRegular() == regular
+			}), nil
+		}, nil
+	}
+
+	// Episode number: "#25.5", or a range of them: "#1-12"
+	if number, ok := strings.CutPrefix(description, "#"); ok {
+		fromStr, toStr, isRange := strings.Cut(number, "-")
+		if !isRange {
+			toStr = fromStr
+		}
+
+		from, err1 := strconv.ParseFloat(strings.TrimSpace(fromStr), 64)
+		to, err2 := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(toStr), "#"), 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid episode number: %s", description)
+		}
+
+		return func(episodes []*source.Episode) ([]*source.Episode, error) {
+			return lo.Filter(episodes, func(e *source.Episode, _ int) bool {
+				return e.Number >= from && e.Number <= to
+			}), nil
+		}, nil
+	}
+
 	// Range: "1-5"
 	if strings.Contains(description, "-") {
 		parts := strings.Split(description, "-")
@@ -2,7 +2,10 @@
 package scraper
 
 import (
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	lua "github.com/yuin/gopher-lua"
@@ -13,34 +16,78 @@ var bytecodeCache sync.Map
 
 // PreCompileAndLoad executes a Lua script within the provided LState, utilizing a bytecode cache to minimize compilation overhead.
 func PreCompileAndLoad(L *lua.LState, scriptPath string) error {
+	proto, err := Compile(scriptPath)
+	if err != nil {
+		return err
+	}
+
+	return Load(L, proto)
+}
+
+// Compile returns the bytecode prototype of a Lua script, parsing it only on the first request.
+// The prototype is immutable and can be shared by any number of LStates.
+func Compile(scriptPath string) (*lua.FunctionProto, error) {
+	scriptPath = filepath.Clean(scriptPath)
+
 	// Check for cached prototype
 	if cachedProto, exists := bytecodeCache.Load(scriptPath); exists {
-		// Cache hit: Load pre-compiled bytecode prototype directly into the state.
-		fn := L.NewFunctionFromProto(cachedProto.(*lua.FunctionProto))
-		L.Push(fn)
-		return L.PCall(0, lua.MultRet, nil)
+		return cachedProto.(*lua.FunctionProto), nil
 	}
 
 	// Cache miss: Parse the script and compile it into a reusable bytecode prototype.
 	file, err := os.Open(scriptPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer file.Close()
 
 	chunk, err := parse.Parse(file, scriptPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	proto, err := lua.Compile(chunk, scriptPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Persist the bytecode prototype in the global cache for future re-execution.
 	bytecodeCache.Store(scriptPath, proto)
+	return proto, nil
+}
+
+// CompileDir compiles every Lua script under dir, so that all modules of a source package
+// are cached and the first syntax error among them is reported before any of them runs.
+func CompileDir(dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || filepath.Ext(path) != ".lua" {
+			return nil
+		}
+
+		_, err = Compile(path)
+		return err
+	})
+}
+
+// Forget drops the cached prototypes of the script at path, or of every script under it
+// if it is a directory, so that updated scripts are compiled again.
+func Forget(path string) {
+	path = filepath.Clean(path)
+	bytecodeCache.Range(func(key, _ any) bool {
+		cached := key.(string)
+		if cached == path || strings.HasPrefix(cached, path+string(filepath.Separator)) {
+			bytecodeCache.Delete(key)
+		}
+		return true
+	})
+}
 
+// Load executes a pre-compiled prototype as the main chunk of the provided LState.
+func Load(L *lua.LState, proto *lua.FunctionProto) error {
 	fn := L.NewFunctionFromProto(proto)
 	L.Push(fn)
 	return L.PCall(0, lua.MultRet, nil)
@@ -0,0 +1,213 @@
+package tracker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/where"
+	"github.com/metafates/gache"
+)
+
+// Segment maps a range of source episode numbers to an entry of the tracker, e.g.
+// source episodes 25–48 to episodes 1–24 of the second cour: {From: 25, To: 48, ID: X, Offset: 24}.
+type Segment struct {
+	// From is the first source episode of the range, 0 for the start.
+	From int `json:"from,omitempty"`
+	// To is the last source episode of the range, 0 for no end.
+	To int `json:"to,omitempty"`
+	// ID of the tracker entry the range belongs to, 0 for the entry the anime is bound to.
+	ID int `json:"id,omitempty"`
+	// Offset is subtracted from source episode numbers to get the episode of the entry.
+	Offset int `json:"offset,omitempty"`
+}
+
+// Contains reports whether the source episode falls into the range.
+func (s Segment) Contains(episode int) bool {
+	return episode >= s.From && (s.To == 0 || episode <= s.To)
+}
+
+// Split reports whether the segment starts past the first episode, so that it belongs to
+// another entry than the one the anime is bound to, e.g. a second cour.
+func (s Segment) Split() bool {
+	return s.From > 1
+}
+
+func (s Segment) overlaps(other Segment) bool {
+	endA, endB := s.To, other.To
+	if endA == 0 {
+		endA = int(^uint(0) >> 1)
+	}
+	if endB == 0 {
+		endB = int(^uint(0) >> 1)
+	}
+	return s.From <= endB && other.From <= endA
+}
+
+func (s Segment) String() string {
+	var parts []string
+	if s.ID != 0 {
+		parts = append(parts, strconv.Itoa(s.ID))
+	}
+
+	switch {
+	case s.From == 0 && s.To == 0:
+	case s.To == 0:
+		parts = append(parts, fmt.Sprintf("%d-", s.From))
+	default:
+		parts = append(parts, fmt.Sprintf("%d-%d", s.From, s.To))
+	}
+
+	if s.Offset != 0 {
+		parts = append(parts, strconv.Itoa(s.Offset))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ParseSegment reads a segment written as "ID [FROM-[TO]] [OFFSET]", e.g. "12345 25-48".
+// The offset defaults to one less than FROM, so that the range starts at episode 1 of the entry.
+func ParseSegment(spec string) (Segment, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 3 {
+		return Segment{}, fmt.Errorf("invalid binding %q, expected \"ID [FROM-[TO]] [OFFSET]\"", spec)
+	}
+
+	var segment Segment
+	id, err := strconv.Atoi(fields[0])
+	if err != nil || id <= 0 {
+		return Segment{}, fmt.Errorf("invalid ID: %s must be a number", fields[0])
+	}
+	segment.ID = id
+
+	rest := fields[1:]
+	hasOffset := false
+	if len(rest) > 0 && strings.Contains(rest[0], "-") && !strings.HasPrefix(rest[0], "-") {
+		from, to, _ := strings.Cut(rest[0], "-")
+		if segment.From, err = strconv.Atoi(from); err != nil {
+			return Segment{}, fmt.Errorf("invalid episode range %q", rest[0])
+		}
+		if to != "" {
+			if segment.To, err = strconv.Atoi(to); err != nil || segment.To < segment.From {
+				return Segment{}, fmt.Errorf("invalid episode range %q", rest[0])
+			}
+		}
+		rest = rest[1:]
+	}
+
+	if len(rest) > 0 {
+		if segment.Offset, err = strconv.Atoi(rest[0]); err != nil {
+			return Segment{}, fmt.Errorf("invalid offset: %s must be a number", rest[0])
+		}
+		hasOffset = true
+		rest = rest[1:]
+	}
+
+	if len(rest) > 0 {
+		return Segment{}, fmt.Errorf("invalid binding %q, expected \"ID [FROM-[TO]] [OFFSET]\"", spec)
+	}
+
+	if !hasOffset && segment.From > 0 {
+		segment.Offset = segment.From - 1
+	}
+
+	return segment, nil
+}
+
+// Binding splits the episodes of an anime across tracker entries.
+type Binding struct {
+	Segments []Segment `json:"segments"`
+}
+
+// Resolve returns the tracker entry and episode number the source episode counts as.
+// Episodes outside of every segment count as themselves on the bound entry, id.
+// It fails for episodes that land before the first episode of their entry.
+func (b Binding) Resolve(id, episode int) (int, int, bool) {
+	for _, segment := range b.Segments {
+		if !segment.Contains(episode) {
+			continue
+		}
+
+		if segment.ID != 0 {
+			id = segment.ID
+		}
+		episode -= segment.Offset
+		break
+	}
+
+	return id, episode, id != 0 && episode > 0
+}
+
+// With returns the binding with the segment added, replacing the segments it overlaps.
+func (b Binding) With(segment Segment) Binding {
+	segments := []Segment{segment}
+	for _, s := range b.Segments {
+		if !s.overlaps(segment) {
+			segments = append(segments, s)
+		}
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i].From < segments[j].From })
+	return Binding{Segments: segments}
+}
+
+// offsets persists the bindings by tracker backend and normalized anime name.
+var offsets = gache.New[map[string]map[string]Binding](
+	&gache.Options{
+		Path:       where.TrackerOffsets(),
+		FileSystem: &filesystem.GacheFs{},
+	},
+)
+
+func bindingName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func loadBindings() (map[string]map[string]Binding, error) {
+	bindings, expired, err := offsets.Get()
+	if err != nil {
+		return nil, err
+	}
+	if expired || bindings == nil {
+		bindings = make(map[string]map[string]Binding)
+	}
+	return bindings, nil
+}
+
+// GetBinding returns the episode ranges of the anime bound with the backend.
+func GetBinding(backend, name string) Binding {
+	bindings, err := loadBindings()
+	if err != nil {
+		return Binding{}
+	}
+
+	return bindings[backend][bindingName(name)]
+}
+
+// SetSegment binds a range of episodes of the anime to an entry of the backend.
+// A segment without a range or offset clears the binding, as the anime then maps one to one.
+func SetSegment(backend, name string, segment Segment) error {
+	bindings, err := loadBindings()
+	if err != nil {
+		return err
+	}
+
+	if bindings[backend] == nil {
+		bindings[backend] = make(map[string]Binding)
+	}
+
+	if segment.From == 0 && segment.To == 0 && segment.Offset == 0 {
+		delete(bindings[backend], bindingName(name))
+	} else {
+		bindings[backend][bindingName(name)] = bindings[backend][bindingName(name)].With(segment)
+	}
+
+	return offsets.Set(bindings)
+}
+
+// Resolve translates a source episode of the anime bound to id through its binding.
+func Resolve(backend, name string, id, episode int) (int, int, bool) {
+	return GetBinding(backend, name).Resolve(id, episode)
+}
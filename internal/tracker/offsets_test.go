@@ -0,0 +1,91 @@
+package tracker
+
+import (
+	"testing"
+
+	"github.com/anisan-cli/anisan/filesystem"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseSegment(t *testing.T) {
+	Convey("Segments are read as ID [FROM-[TO]] [OFFSET]", t, func() {
+		segment, err := ParseSegment("12345")
+		So(err, ShouldBeNil)
+		So(segment, ShouldResemble, Segment{ID: 12345})
+
+		segment, err = ParseSegment("12345 25-48")
+		So(err, ShouldBeNil)
+		So(segment, ShouldResemble, Segment{ID: 12345, From: 25, To: 48, Offset: 24})
+
+		segment, err = ParseSegment(" 12345  49- ")
+		So(err, ShouldBeNil)
+		So(segment, ShouldResemble, Segment{ID: 12345, From: 49, Offset: 48})
+
+		segment, err = ParseSegment("12345 13-26 0")
+		So(err, ShouldBeNil)
+		So(segment, ShouldResemble, Segment{ID: 12345, From: 13, To: 26})
+
+		segment, err = ParseSegment("12345 -12")
+		So(err, ShouldBeNil)
+		So(segment, ShouldResemble, Segment{ID: 12345, Offset: -12})
+
+		for _, invalid := range []string{"", "abc", "12345 48-25", "12345 a-b", "12345 1-2 3 4", "0"} {
+			_, err = ParseSegment(invalid)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestBinding(t *testing.T) {
+	Convey("Given a show split into two cours", t, func() {
+		binding := Binding{}.With(Segment{From: 25, To: 48, ID: 2, Offset: 24})
+
+		Convey("Episodes outside the ranges count on the bound entry", func() {
+			id, episode, ok := binding.Resolve(1, 24)
+			So(ok, ShouldBeTrue)
+			So(id, ShouldEqual, 1)
+			So(episode, ShouldEqual, 24)
+		})
+
+		Convey("Episodes in a range count on its entry", func() {
+			id, episode, ok := binding.Resolve(1, 30)
+			So(ok, ShouldBeTrue)
+			So(id, ShouldEqual, 2)
+			So(episode, ShouldEqual, 6)
+		})
+
+		Convey("Overlapping ranges are replaced", func() {
+			binding = binding.With(Segment{From: 40, ID: 3, Offset: 39}).With(Segment{From: 1, To: 12})
+			So(binding.Segments, ShouldResemble, []Segment{{From: 1, To: 12}, {From: 40, ID: 3, Offset: 39}})
+
+			id, episode, _ := binding.Resolve(1, 30)
+			So(id, ShouldEqual, 1)
+			So(episode, ShouldEqual, 30)
+		})
+
+		Convey("Episodes before the first of their entry are not counted", func() {
+			_, _, ok := Binding{}.With(Segment{Offset: 12}).Resolve(1, 5)
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given stored bindings", t, func() {
+		filesystem.SetMemMapFs()
+
+		So(SetSegment("anilist", "Show", Segment{From: 25, ID: 2, Offset: 24}), ShouldBeNil)
+		id, episode, ok := Resolve("anilist", "show ", 1, 25)
+		So(ok, ShouldBeTrue)
+		So(id, ShouldEqual, 2)
+		So(episode, ShouldEqual, 1)
+
+		Convey("They are kept per backend", func() {
+			id, _, _ := Resolve("mal", "Show", 1, 25)
+			So(id, ShouldEqual, 1)
+		})
+
+		Convey("Binding the whole anime clears them", func() {
+			So(SetSegment("anilist", "Show", Segment{}), ShouldBeNil)
+			So(GetBinding("anilist", "Show").Segments, ShouldBeEmpty)
+		})
+	})
+}
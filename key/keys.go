@@ -2,11 +2,33 @@
 package key
 
 // DefinedFieldsCount represents the total cardinality of the application configuration schema.
-const DefinedFieldsCount = 23
+const DefinedFieldsCount = 44
 
 // Provider Source Identifiers - these keys manage the registration and selection of scraping providers.
 const (
-	DefaultSources = "sources.default"
+	DefaultSources  = "sources.default"
+	SourcesPoolSize = "sources.pool_size"
+	SourcesSandbox  = "sources.sandbox"
+	SourcesLibrary  = "sources.library"
+
+	SourcesJellyfinURL            = "sources.jellyfin.url"
+	SourcesJellyfinAPIKey         = "sources.jellyfin.api_key"
+	SourcesJellyfinUserID         = "sources.jellyfin.user_id"
+	SourcesJellyfinReportProgress = "sources.jellyfin.report_progress"
+
+	SourcesTimeout         = "sources.timeout"
+	SourcesMaxInstructions = "sources.max_instructions"
+	SourcesMaxStack        = "sources.max_stack"
+)
+
+// Stream Selection - these keys decide which of the videos of an episode is played.
+const (
+	StreamQuality    = "stream.quality"
+	StreamContainer  = "stream.container"
+	StreamAllowHosts = "stream.allow_hosts"
+	StreamDenyHosts  = "stream.deny_hosts"
+	StreamAsk        = "stream.ask"
+	StreamProbeHLS   = "stream.probe_hls"
 )
 
 // Metadata Configuration - these keys govern the retrieval and processing of media metadata.
@@ -65,6 +87,8 @@ const (
 // Media Playback - these keys maintain the state and configuration for external video players.
 const (
 	PlayerCompletionPercentage = "player.completion_percentage"
+	PlayerPreferAudio          = "player.prefer_audio"
+	PlayerSubtitleLanguage     = "player.subtitle_language"
 )
 
 // Logging Infrastructure - these keys manage the application's internal diagnostics and auditing system.
@@ -1,8 +1,12 @@
 package mini
 
 import (
+	"context"
 	"os"
+	"os/signal"
 
+	"github.com/anisan-cli/anisan/health"
+	"github.com/anisan-cli/anisan/log"
 	"github.com/anisan-cli/anisan/source"
 	"github.com/anisan-cli/anisan/util"
 )
@@ -39,6 +43,12 @@ func newMini() *mini {
 	}
 }
 
+// interruptible returns a context that is cancelled by Ctrl+C, so that a slow
+// source call can be abandoned without killing the whole session.
+func interruptible() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
 func (m *mini) previousState() {
 	if m.statesHistory.Len() > 0 {
 		m.state = m.statesHistory.Pop()
@@ -89,6 +99,9 @@ func (m *mini) handleState() error {
 	case episodeReadState:
 		return m.handleEpisodeReadState()
 	case quitState:
+		if err := health.Flush(); err != nil {
+			log.Warn(err)
+		}
 		os.Exit(0)
 	}
 
@@ -2,6 +2,7 @@
 package mini
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -9,8 +10,10 @@ import (
 
 	"github.com/anisan-cli/anisan/history"
 	"github.com/anisan-cli/anisan/key"
+	"github.com/anisan-cli/anisan/player"
 	"github.com/anisan-cli/anisan/provider"
 	"github.com/anisan-cli/anisan/source"
+	"github.com/anisan-cli/anisan/stream"
 	"github.com/anisan-cli/anisan/util"
 	"github.com/samber/lo"
 	"github.com/spf13/viper"
@@ -90,7 +93,15 @@ func (m *mini) handleAnimeSearchState() error {
 		query := in.value
 
 		erase := progress("Searching Query..")
-		m.cachedAnimes[query], err = m.selectedSource.Search(query)
+		ctx, stop := interruptible()
+		m.cachedAnimes[query], err = source.Search(ctx, m.selectedSource, query)
+		cancelled := ctx.Err() != nil
+		stop()
+		if cancelled {
+			erase()
+			fail("Search cancelled")
+			return searchLoop()
+		}
 		max := lo.Min([]int{len(m.cachedAnimes[query]), viper.GetInt(key.MiniSearchLimit)})
 		m.cachedAnimes[query] = m.cachedAnimes[query][:max]
 		erase()
@@ -130,13 +141,23 @@ func (m *mini) handleEpisodeSelectState() error {
 	var err error
 
 	erase := progress("Searching Episodes..")
-	m.cachedEpisodes[m.selectedAnime.URL], err = m.selectedSource.EpisodesOf(m.selectedAnime)
+	ctx, stop := interruptible()
+	m.cachedEpisodes[m.selectedAnime.URL], err = source.EpisodesOf(ctx, m.selectedAnime)
+	cancelled := ctx.Err() != nil
+	stop()
 	erase()
+	if cancelled {
+		fail("Cancelled")
+		m.selectedAnime = nil
+		m.previousState()
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	episodes := m.cachedEpisodes[m.selectedAnime.URL]
+	source.SortEpisodes(m.cachedEpisodes[m.selectedAnime.URL])
+	episodes := preferredEpisodes(m.cachedEpisodes[m.selectedAnime.URL], "")
 
 	if len(episodes) == 0 {
 		fail("No episodes found")
@@ -221,6 +242,10 @@ func (m *mini) handleEpisodeReadState() error {
 		util.ClearScreen()
 		fmt.Printf("Reading %s...\n", episode.Name)
 
+		if err := m.play(episode); err != nil {
+			fail(err.Error())
+		}
+
 		title(fmt.Sprintf("Currently reading %s", episode.Name))
 
 		var options []*bind
@@ -333,16 +358,113 @@ func (m *mini) handleHistorySelectState() error {
 		ID:     c.AnimeID, // Fixed to map AnimeID
 		Source: s,
 	}
-	chaps, err := m.selectedSource.EpisodesOf(anime)
+	ctx, stop := interruptible()
+	chaps, err := source.EpisodesOf(ctx, anime)
+	cancelled := ctx.Err() != nil
+	stop()
 	erase()
-
+	if cancelled {
+		// Back to the history list
+		fail("Cancelled")
+		m.selectedSource = nil
+		return nil
+	}
 	if err != nil {
 		return err
 	}
+	source.SortEpisodes(chaps)
 
 	m.cachedEpisodes[anime.URL] = chaps
-	m.selectedEpisodes = chaps[c.Index-1:]
+
+	// Resume with the audio variant that was watched
+	chaps = preferredEpisodes(chaps, c.Translation)
+	_, start, found := lo.FindIndexOf(chaps, c.Matches)
+	if !found {
+		// The url changed or the episode is watched in another audio variant now
+		_, start, found = lo.FindIndexOf(chaps, c.MatchesNumber)
+	}
+	if !found {
+		start = 0
+	}
+	m.selectedEpisodes = chaps[start:]
 
 	m.newState(episodeReadState)
 	return nil
 }
+
+// preferredEpisodes keeps a single audio variant of the episodes: the given one if the
+// source offers it, otherwise the one configured with player.prefer_audio.
+func preferredEpisodes(episodes []*source.Episode, translation string) []*source.Episode {
+	available := source.Translations(episodes)
+	if !lo.Contains(available, translation) {
+		translation = source.PreferredTranslation(available, viper.GetString(key.PlayerPreferAudio))
+	}
+
+	return source.FilterTranslation(episodes, translation)
+}
+
+// streamItem lists a video in the stream picker.
+type streamItem struct {
+	*source.Video
+}
+
+func (s streamItem) String() string {
+	return stream.Label(s.Video)
+}
+
+// play fetches the videos of the episode and plays the one chosen by the stream policy.
+func (m *mini) play(episode *source.Episode) error {
+	erase := progress("Fetching Streams..")
+	ctx, stop := interruptible()
+	videos, err := source.VideosOf(ctx, episode)
+	if err == nil {
+		videos = stream.ProbeFromConfig(ctx, videos)
+	}
+	cancelled := ctx.Err() != nil
+	stop()
+	erase()
+	if cancelled {
+		fail("Cancelled")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	policy := stream.PolicyFromConfig()
+	video, err := policy.Select(videos)
+	if err != nil {
+		return err
+	}
+
+	ranked := policy.Rank(videos)
+	if policy.Ask && len(ranked) > 1 {
+		title("Choose a stream >>")
+		b, choice, err := menu(lo.Map(ranked, func(v *source.Video, _ int) streamItem { return streamItem{v} }))
+		if err != nil {
+			return err
+		}
+		if quit.eq(b) {
+			return nil
+		}
+		video = choice.Video
+	}
+
+	if viper.GetBool(key.HistorySaveOnRead) {
+		_ = history.Save(episode, 0.0)
+	}
+
+	// The chosen stream goes first, the others remain as fallbacks
+	candidates := append([]*source.Video{video}, lo.Without(ranked, video)...)
+
+	p := player.New()
+	title := fmt.Sprintf("%s - %s", episode.Anime.Name, episode.Name)
+	return stream.PlayFirst(context.Background(), candidates, func(v *source.Video) error {
+		p.SetSubtitles(stream.Subtitles(v))
+		p.SetMaxBitrate(v.Bandwidth)
+		p.SetRefresher(stream.Refresher(episode, v))
+		return p.PlaySync(v.URL, title, v.Headers)
+	}, func(ctx context.Context) ([]*source.Video, error) {
+		return stream.Fetch(ctx, episode)
+	})
+}
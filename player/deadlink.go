@@ -0,0 +1,123 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/anisan-cli/anisan/log"
+)
+
+// errExitedEarly is returned by waitForSocket when mpv quits before its IPC socket is up,
+// which is what happens when the media cannot be opened.
+var errExitedEarly = errors.New("mpv exited before socket was ready")
+
+// DeadLinkError reports media the player could not open at all, e.g. a broken or expired link.
+// Callers may retry with another stream.
+type DeadLinkError struct {
+	URL    string
+	Reason string
+}
+
+func (e *DeadLinkError) Error() string {
+	return fmt.Sprintf("could not play %s: %s", e.URL, e.Reason)
+}
+
+// IsDeadLink reports whether err means the media could not be opened.
+func IsDeadLink(err error) bool {
+	var dead *DeadLinkError
+	return errors.As(err, &dead)
+}
+
+// eventStream is a connection to the mpv IPC socket that mpv sends its events to.
+type eventStream struct {
+	conn    net.Conn
+	decoder *json.Decoder
+}
+
+// dialEvents connects to socketPath once mpv listens on it.
+// It returns nil if done is closed first, and the connection is closed together with done.
+func dialEvents(socketPath string, done <-chan struct{}) *eventStream {
+	var conn net.Conn
+	for conn == nil {
+		select {
+		case <-done:
+			return nil
+		case <-time.After(socketWaitDelay / 3):
+		}
+
+		conn, _ = net.Dial("unix", socketPath)
+	}
+
+	go func() {
+		<-done
+		conn.Close()
+	}()
+
+	return &eventStream{conn: conn, decoder: json.NewDecoder(conn)}
+}
+
+// next returns the next event, or false once the connection is gone.
+func (s *eventStream) next() (mpvEvent, bool) {
+	for {
+		var event mpvEvent
+		err := s.decoder.Decode(&event)
+		if err == nil {
+			return event, true
+		}
+		if _, ok := err.(*json.UnmarshalTypeError); !ok {
+			return mpvEvent{}, false
+		}
+	}
+}
+
+func (s *eventStream) Close() error {
+	return s.conn.Close()
+}
+
+// awaitLoad follows the mpv events on socketPath until the media has loaded.
+// If mpv gives up on the media first, it is told to quit and the reason is returned.
+// An empty reason means the media loaded, or that done was closed before anything happened.
+func awaitLoad(socketPath string, done <-chan struct{}) string {
+	events := dialEvents(socketPath, done)
+	if events == nil {
+		return ""
+	}
+	defer events.Close()
+
+	reason, _ := untilLoaded(socketPath, events)
+	return reason
+}
+
+// untilLoaded reads events until the media has loaded, which it reports, or mpv gave up on it.
+// The reason mpv gave up is returned, after telling it to quit.
+func untilLoaded(socketPath string, events *eventStream) (reason string, loaded bool) {
+	for {
+		event, ok := events.next()
+		if !ok {
+			return "", false
+		}
+
+		switch event.Event {
+		case "file-loaded", "playback-restart":
+			return "", true
+		case "end-file":
+			if event.Reason != "error" {
+				return "", false
+			}
+
+			reason := event.FileError
+			if reason == "" {
+				reason = "mpv could not open the media"
+			}
+
+			// mpv idles after a failed load, so close it ourselves.
+			if _, err := doSendCommand(socketPath, []interface{}{"quit"}); err != nil {
+				log.Warnf("could not quit mpv after failed load: %v", err)
+			}
+			return reason, false
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAwaitLoad(t *testing.T) {
+	Convey("awaitLoad", t, func() {
+		done := make(chan struct{})
+		defer close(done)
+
+		Convey("Should report the reason mpv failed to open the media", func() {
+			socket, commands := fakeMPV(t,
+				`{"event":"start-file","playlist_entry_id":1}`,
+				`{"event":"end-file","reason":"error","file_error":"loading failed"}`,
+			)
+			So(awaitLoad(socket, done), ShouldEqual, "loading failed")
+			So(nextCommand(commands, "quit"), ShouldNotBeNil)
+		})
+
+		Convey("Should report nothing once the media has loaded", func() {
+			socket, _ := fakeMPV(t,
+				`{"event":"start-file"}`,
+				`{"event":"file-loaded"}`,
+				`{"event":"end-file","reason":"error"}`,
+			)
+			So(awaitLoad(socket, done), ShouldBeEmpty)
+		})
+
+		Convey("Should give up when the player is gone", func() {
+			stopped := make(chan struct{})
+			close(stopped)
+			missing := filepath.Join(os.TempDir(), fmt.Sprintf("anisan-missing-%d.sock", time.Now().UnixNano()))
+			So(awaitLoad(missing, stopped), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestIsDeadLink(t *testing.T) {
+	Convey("IsDeadLink should see through wrapped errors", t, func() {
+		err := fmt.Errorf("playing: %w", &DeadLinkError{URL: "https://example.com", Reason: "loading failed"})
+		So(IsDeadLink(err), ShouldBeTrue)
+		So(IsDeadLink(errors.New("loading failed")), ShouldBeFalse)
+	})
+}
@@ -15,8 +15,10 @@ import (
 
 // IINA implements the Player interface for macOS native IINA playback.
 type IINA struct {
-	cmd    *exec.Cmd
-	exited chan struct{}
+	cmd       *exec.Cmd
+	exited    chan struct{}
+	subtitles []Subtitle
+	bitrate   int
 }
 
 func NewIINA() *IINA {
@@ -30,6 +32,21 @@ func (m *IINA) SetTrackerContext(t tracker.MediaTracker, id, ep, total int, guar
 	// IINA native playback via 'open' does not support background IPC synchronization.
 }
 
+// SetSubtitles sets the external subtitle tracks loaded with the next media.
+func (m *IINA) SetSubtitles(subtitles []Subtitle) {
+	m.subtitles = subtitles
+}
+
+// SetMaxBitrate caps the bit rate of the HLS variant picked from a master playlist, 0 picks the highest.
+func (m *IINA) SetMaxBitrate(bitrate int) {
+	m.bitrate = bitrate
+}
+
+// SetRefresher implements the Player interface.
+func (m *IINA) SetRefresher(Refresher) {
+	// IINA native playback via 'open' cannot be controlled over IPC.
+}
+
 func (m *IINA) Play(rawURL string, title string, headers map[string]string) error {
 	args, err := m.buildArgs(rawURL, title, headers)
 	if err != nil {
@@ -91,6 +108,20 @@ func (m *IINA) buildArgs(rawURL string, title string, headers map[string]string)
 		args = append(args, fmt.Sprintf("--http-header-fields=%s", hBuilder.String()))
 	}
 
+	// IINA forwards --mpv-prefixed options to its embedded mpv.
+	if m.bitrate > 0 {
+		args = append(args, fmt.Sprintf("--mpv-hls-bitrate=%d", m.bitrate))
+	}
+	languages := subtitleLanguages(viper.GetString(key.PlayerSubtitleLanguage))
+	for _, sub := range orderSubtitles(m.subtitles, languages) {
+		if safeSub, err := sanitizeMediaTarget(sub.URL); err == nil {
+			args = append(args, fmt.Sprintf("--mpv-sub-file=%s", safeSub))
+		}
+	}
+	if len(languages) > 0 {
+		args = append(args, fmt.Sprintf("--mpv-slang=%s", strings.Join(languages, ",")))
+	}
+
 	args = append(args, rawURL)
 	return args, nil
 }
@@ -3,6 +3,7 @@ package player
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"net"
 	"net/url"
@@ -15,7 +16,9 @@ import (
 	"time"
 
 	"github.com/anisan-cli/anisan/internal/tracker"
+	"github.com/anisan-cli/anisan/key"
 	"github.com/anisan-cli/anisan/log"
+	"github.com/spf13/viper"
 )
 
 const (
@@ -30,6 +33,9 @@ type MPV struct {
 	exited     chan struct{} // closed when mpv process exits
 	tickerStop chan struct{} // signals ticker to stop
 	mu         sync.Mutex    // Protects socket writes
+	subtitles  []Subtitle    // External subtitle tracks for the next media
+	bitrate    int           // Highest HLS variant bit rate for the next media, 0 for no cap
+	refresher  Refresher     // Resolves a fresh link when the stream fails mid-playback
 
 	// Tracker context for background synchronization
 	tracker    tracker.MediaTracker
@@ -55,6 +61,21 @@ func (m *MPV) SetTrackerContext(t tracker.MediaTracker, id, ep, total int, guard
 	m.syncGuard = guard
 }
 
+// SetSubtitles sets the external subtitle tracks loaded with the next media.
+func (m *MPV) SetSubtitles(subtitles []Subtitle) {
+	m.subtitles = subtitles
+}
+
+// SetMaxBitrate caps the bit rate of the HLS variant picked from a master playlist, 0 picks the highest.
+func (m *MPV) SetMaxBitrate(bitrate int) {
+	m.bitrate = bitrate
+}
+
+// SetRefresher sets how a fresh link is resolved when the next media fails mid-playback.
+func (m *MPV) SetRefresher(refresh Refresher) {
+	m.refresher = refresh
+}
+
 // Play starts playback of the given URL. If mpv is already running,
 // it loads the new file into the existing instance via IPC.
 func (m *MPV) Play(rawURL string, title string, headers map[string]string) error {
@@ -63,12 +84,8 @@ func (m *MPV) Play(rawURL string, title string, headers map[string]string) error
 		return err
 	}
 
-	if m.socketPath == "" {
-		randomBytes := make([]byte, 4)
-		if _, err := rand.Read(randomBytes); err != nil {
-			return fmt.Errorf("generate socket name: %w", err)
-		}
-		m.socketPath = filepath.Join(os.TempDir(), fmt.Sprintf("anisan-%x.sock", randomBytes))
+	if err := m.ensureSocketPath(); err != nil {
+		return err
 	}
 
 	args[2] = fmt.Sprintf("--input-ipc-server=%s", m.socketPath)
@@ -106,22 +123,64 @@ func (m *MPV) Play(rawURL string, title string, headers map[string]string) error
 				_ = m.cmd.Process.Kill()
 			}
 		}
+		if errors.Is(err, errExitedEarly) {
+			return &DeadLinkError{URL: rawURL, Reason: err.Error()}
+		}
 		return fmt.Errorf("mpv socket not ready: %w", err)
 	}
 
-	// Initialize background sync watcher if tracker context is provided.
-	if m.tracker != nil {
-		watcher := NewMPVWatcher(m.socketPath, m.tracker, m.mediaID, m.episodeNum, m.totalEps, m.syncGuard)
-		go func() {
-			if err := watcher.Poll(context.Background()); err != nil {
-				log.Warnf("mpv watcher exited with error: %v", err)
-			}
-		}()
+	m.startTrackerWatcher()
+	go m.watchSession(m.exited)
+
+	return nil
+}
+
+// startTrackerWatcher initializes the background sync watcher if tracker context is provided.
+func (m *MPV) startTrackerWatcher() {
+	if m.tracker == nil {
+		return
 	}
 
+	watcher := NewMPVWatcher(m.socketPath, m.tracker, m.mediaID, m.episodeNum, m.totalEps, m.syncGuard)
+	go func() {
+		if err := watcher.Poll(context.Background()); err != nil {
+			log.Warnf("mpv watcher exited with error: %v", err)
+		}
+	}()
+}
+
+// ensureSocketPath picks a random IPC socket path for the session if there is none yet.
+func (m *MPV) ensureSocketPath() error {
+	if m.socketPath != "" {
+		return nil
+	}
+
+	randomBytes := make([]byte, 4)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return fmt.Errorf("generate socket name: %w", err)
+	}
+	m.socketPath = filepath.Join(os.TempDir(), fmt.Sprintf("anisan-%x.sock", randomBytes))
 	return nil
 }
 
+// waitForSyncSocket polls until the IPC socket of a synchronous session accepts connections.
+// It reports false if the session ended first.
+func (m *MPV) waitForSyncSocket(done <-chan struct{}) bool {
+	for i := 0; i < socketWaitRetries; i++ {
+		select {
+		case <-done:
+			return false
+		case <-time.After(socketWaitDelay):
+		}
+
+		if conn, err := net.Dial("unix", m.socketPath); err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
+
 // Wait returns a channel that is closed when the mpv process exits.
 func (m *MPV) Wait() <-chan struct{} {
 	return m.exited
@@ -135,7 +194,7 @@ func (m *MPV) waitForSocket() error {
 		// Check if process already exited
 		select {
 		case <-m.exited:
-			return fmt.Errorf("mpv exited before socket was ready")
+			return errExitedEarly
 		default:
 		}
 
@@ -418,23 +477,74 @@ func (m *MPV) buildArgs(rawURL string, title string, headers map[string]string,
 		args = append(args, fmt.Sprintf("--http-header-fields=%s", headerString))
 	}
 
+	if m.bitrate > 0 {
+		args = append(args, fmt.Sprintf("--hls-bitrate=%d", m.bitrate))
+	}
+
+	// External subtitles, preferred languages first so mpv selects them
+	languages := subtitleLanguages(viper.GetString(key.PlayerSubtitleLanguage))
+	for _, sub := range orderSubtitles(m.subtitles, languages) {
+		safeSub, err := sanitizeMediaTarget(sub.URL)
+		if err != nil {
+			log.Warnf("skipping subtitle %q: %v", sub.URL, err)
+			continue
+		}
+		args = append(args, fmt.Sprintf("--sub-file=%s", safeSub))
+	}
+	if len(languages) > 0 {
+		args = append(args, fmt.Sprintf("--slang=%s", strings.Join(languages, ",")))
+	}
+
 	args = append(args, safeURL)
 	return args, nil
 }
 
 // PlaySync starts playback synchronously and blocks until the player process exits.
 // This yields the TTY to the mpv process (essential for tea.Suspend handoffs).
+// Media that mpv cannot open is reported as a *DeadLinkError.
 func (m *MPV) PlaySync(rawURL string, title string, headers map[string]string) error {
 	args, err := m.buildArgs(rawURL, title, headers, true)
 	if err != nil {
 		return err
 	}
 
+	if err := m.ensureSocketPath(); err != nil {
+		return err
+	}
+	args[2] = fmt.Sprintf("--input-ipc-server=%s", m.socketPath)
+
 	m.cmd = exec.Command("mpv", args...)
 	m.cmd.SysProcAttr = sysProcAttr()
 	m.cmd.Stdout = os.Stdout
 	m.cmd.Stderr = os.Stderr
 	m.cmd.Stdin = os.Stdin
 
-	return m.cmd.Run()
+	if err := m.cmd.Start(); err != nil {
+		return err
+	}
+
+	// Watch the session over IPC, since mpv idles instead of exiting when the media fails.
+	done := make(chan struct{})
+	failure := make(chan string, 1)
+	go func() {
+		failure <- m.watchSession(done)
+	}()
+	go func() {
+		if m.waitForSyncSocket(done) {
+			m.startTrackerWatcher()
+		}
+	}()
+
+	err = m.cmd.Wait()
+	close(done)
+	reason := <-failure
+	_ = os.Remove(m.socketPath)
+
+	// Only a failure mpv reported for the media blames the link,
+	// not a bad flag or the user quitting early.
+	if reason != "" {
+		return &DeadLinkError{URL: rawURL, Reason: reason}
+	}
+
+	return err
 }
@@ -4,7 +4,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/anisan-cli/anisan/key"
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
 )
 
 func TestMPV(t *testing.T) {
@@ -35,5 +37,54 @@ func TestMPV(t *testing.T) {
 				}
 			})
 		})
+
+		Convey("Subtitles", func() {
+			viper.Set(key.PlayerSubtitleLanguage, "es, en")
+			defer viper.Set(key.PlayerSubtitleLanguage, "en")
+
+			mpv.SetSubtitles([]Subtitle{
+				{URL: "https://example.com/de.vtt", Language: "de"},
+				{URL: "https://example.com/en.vtt", Language: "en-US"},
+				{URL: "-evil", Language: "en"},
+				{URL: "https://example.com/es.vtt", Language: "es"},
+			})
+
+			args, err := mpv.buildArgs("https://example.com/video.m3u8", "Test", nil, true)
+			So(err, ShouldBeNil)
+
+			Convey("Should pass the preferred languages first as --sub-file", func() {
+				subFiles := make([]string, 0)
+				for _, a := range args {
+					if strings.HasPrefix(a, "--sub-file=") {
+						subFiles = append(subFiles, strings.TrimPrefix(a, "--sub-file="))
+					}
+				}
+				So(subFiles, ShouldResemble, []string{
+					"https://example.com/es.vtt",
+					"https://example.com/en.vtt",
+					"https://example.com/de.vtt",
+				})
+			})
+
+			Convey("Should select the preferred languages", func() {
+				So(args, ShouldContain, "--slang=es,en")
+			})
+
+			Convey("Should keep the media URL last", func() {
+				So(args[len(args)-1], ShouldEqual, "https://example.com/video.m3u8")
+			})
+		})
+
+		Convey("Bit rate cap", func() {
+			mpv.SetMaxBitrate(2800000)
+			args, err := mpv.buildArgs("https://example.com/master.m3u8", "Test", nil, true)
+			So(err, ShouldBeNil)
+			So(args, ShouldContain, "--hls-bitrate=2800000")
+
+			mpv.SetMaxBitrate(0)
+			args, err = mpv.buildArgs("https://example.com/master.m3u8", "Test", nil, true)
+			So(err, ShouldBeNil)
+			So(strings.Join(args, " "), ShouldNotContainSubstring, "--hls-bitrate")
+		})
 	})
 }
@@ -21,10 +21,11 @@ var ErrSyncQueued = errors.New("sync_queued")
 // mpvEvent is the minimal typed structure for MPV IPC events.
 // Using a typed struct avoids per-event heap allocations from map[string]interface{}.
 type mpvEvent struct {
-	Event  string  `json:"event"`
-	Name   string  `json:"name"`
-	Data   float64 `json:"data"`
-	Reason string  `json:"reason"`
+	Event     string  `json:"event"`
+	Name      string  `json:"name"`
+	Data      float64 `json:"data"`
+	Reason    string  `json:"reason"`
+	FileError string  `json:"file_error"`
 }
 
 // MPVWatcher monitors the MPV player's state via IPC and triggers tracker sync on playback events.
@@ -93,6 +94,11 @@ func (w *MPVWatcher) Poll(ctx context.Context) error {
 		}
 
 		// Case 2: Native 'end-file' event for deterministic completion (EOF heist).
+		// A failed stream may be reloaded in place, so keep watching.
+		if event.Event == "end-file" && event.Reason == "error" {
+			continue
+		}
+
 		if event.Event == "end-file" {
 			if !updateFired && event.Reason == "eof" {
 				_ = w.triggerUpdate(ctx)
@@ -1,11 +1,24 @@
 package player
 
 import (
+	"runtime"
 	"sync/atomic"
 
 	"github.com/anisan-cli/anisan/internal/tracker"
+	"github.com/anisan-cli/anisan/key"
+	"github.com/spf13/viper"
 )
 
+// New returns the player selected with player.default.
+// IINA is only available on macOS, everything else plays with mpv.
+func New() Player {
+	if viper.GetString(key.Player) == "iina" && runtime.GOOS == "darwin" {
+		return NewIINA()
+	}
+
+	return NewMPV()
+}
+
 // Player encapsulates the required capabilities for a media playback backend.
 type Player interface {
 	// Play starts playback in background mode.
@@ -14,6 +27,15 @@ type Player interface {
 	// PlaySync starts playback synchronously (TTY handoff).
 	PlaySync(url string, title string, headers map[string]string) error
 
+	// SetSubtitles sets the external subtitle tracks loaded with the next media.
+	SetSubtitles(subtitles []Subtitle)
+
+	// SetMaxBitrate caps the bit rate of the HLS variant picked from a master playlist, 0 picks the highest.
+	SetMaxBitrate(bitrate int)
+
+	// SetRefresher sets how a fresh link is resolved when the next media fails mid-playback.
+	SetRefresher(refresh Refresher)
+
 	// SetTrackerContext binds tracking metadata and an optional sync guard to the player session.
 	SetTrackerContext(t tracker.MediaTracker, mediaID, ep, totalEps int, syncGuard *atomic.Bool)
 
@@ -0,0 +1,132 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anisan-cli/anisan/log"
+)
+
+const (
+	// maxRecoveries caps how often one session reloads an expired stream.
+	maxRecoveries = 3
+	// refreshTimeout bounds how long resolving a fresh link may take.
+	refreshTimeout = 30 * time.Second
+)
+
+// Refresher resolves a fresh link for the media being played after the current one expired.
+type Refresher func(ctx context.Context) (url string, headers map[string]string, err error)
+
+// watchSession follows the mpv events on the session socket until done is closed.
+//
+// Until the media loads it behaves like awaitLoad, which is all it does without a refresher.
+// Once the media has loaded, a media that fails mid-playback, e.g. because the link expired,
+// is replaced with a fresh link resolved with the refresher and playback seeks back to
+// the last known position. When that is not possible mpv is closed, as it would idle on an empty window.
+func (m *MPV) watchSession(done <-chan struct{}) string {
+	socketPath, refresh := m.socketPath, m.refresher
+	if refresh == nil {
+		return awaitLoad(socketPath, done)
+	}
+
+	events := dialEvents(socketPath, done)
+	if events == nil {
+		return ""
+	}
+	defer events.Close()
+
+	if _, err := events.conn.Write([]byte(`{"command": ["observe_property", 2, "time-pos"]}` + "\n")); err != nil {
+		return ""
+	}
+
+	if reason, loaded := untilLoaded(socketPath, events); !loaded {
+		return reason
+	}
+
+	var (
+		position   float64
+		resumeAt   float64
+		recoveries int
+	)
+
+	for {
+		event, ok := events.next()
+		if !ok {
+			return ""
+		}
+
+		switch event.Event {
+		case "property-change":
+			if event.Name == "time-pos" && event.Data > 0 {
+				position = event.Data
+			}
+		case "file-loaded":
+			if resumeAt > 0 {
+				if _, err := doSendCommand(socketPath, []interface{}{"seek", resumeAt, "absolute"}); err != nil {
+					log.Warnf("could not resume at %.0fs: %v", resumeAt, err)
+				}
+				resumeAt = 0
+			}
+		case "end-file":
+			if event.Reason != "error" {
+				continue
+			}
+
+			reason := event.FileError
+			if reason == "" {
+				reason = "mpv could not open the media"
+			}
+
+			if recoveries >= maxRecoveries {
+				log.Warnf("stream failed mid-playback: %s", reason)
+				giveUp(socketPath, "Stream failed again, giving up")
+				return ""
+			}
+
+			recoveries++
+			log.Warnf("stream failed mid-playback at %.0fs (%s), fetching a fresh link", position, reason)
+			if err := reload(socketPath, refresh); err != nil {
+				log.Warnf("could not recover playback: %v", err)
+				giveUp(socketPath, "Stream expired and could not be refreshed")
+				return ""
+			}
+			resumeAt = position
+		}
+	}
+}
+
+// giveUp tells the user why playback stops and closes mpv.
+func giveUp(socketPath, message string) {
+	_, _ = doSendCommand(socketPath, []interface{}{"show-text", message, 5000})
+	if _, err := doSendCommand(socketPath, []interface{}{"quit"}); err != nil {
+		log.Warnf("could not quit mpv after failed playback: %v", err)
+	}
+}
+
+// reload resolves a fresh link and replaces the current media with it.
+func reload(socketPath string, refresh Refresher) error {
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+
+	rawURL, headers, err := refresh(ctx)
+	if err != nil {
+		return err
+	}
+
+	safeURL, err := sanitizeMediaTarget(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid media target: %w", err)
+	}
+
+	fields := make([]string, 0, len(headers))
+	for k, v := range headers {
+		fields = append(fields, fmt.Sprintf("%s: %s", k, v))
+	}
+	if _, err := doSendCommand(socketPath, []interface{}{"set_property", "http-header-fields", fields}); err != nil {
+		return err
+	}
+
+	_, err = doSendCommand(socketPath, []interface{}{"loadfile", safeURL, "replace"})
+	return err
+}
@@ -0,0 +1,156 @@
+package player
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeMPV serves the given events to the first client and records the commands
+// sent by any other client.
+func fakeMPV(t *testing.T, events ...string) (string, <-chan []interface{}) {
+	socket := filepath.Join(t.TempDir(), "mpv.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	commands := make(chan []interface{}, 16)
+	go func() {
+		first := true
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			if first {
+				first = false
+				for _, e := range events {
+					fmt.Fprintln(conn, e)
+				}
+				continue
+			}
+
+			var cmd ipcCommand
+			if line, err := bufio.NewReader(conn).ReadBytes('\n'); err == nil && json.Unmarshal(line, &cmd) == nil {
+				commands <- cmd.Command
+			}
+			fmt.Fprintln(conn, `{"error":"success"}`)
+			conn.Close()
+		}
+	}()
+
+	return socket, commands
+}
+
+// nextCommand returns the next recorded command named name.
+func nextCommand(commands <-chan []interface{}, name string) []interface{} {
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case cmd := <-commands:
+			if len(cmd) > 0 && cmd[0] == name {
+				return cmd
+			}
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+func TestWatchSession(t *testing.T) {
+	Convey("watchSession", t, func() {
+		done := make(chan struct{})
+		defer close(done)
+
+		Convey("Should quit and report the reason mpv failed to open the media", func() {
+			socket, commands := fakeMPV(t,
+				`{"event":"start-file","playlist_entry_id":1}`,
+				`{"event":"end-file","reason":"error","file_error":"loading failed"}`,
+			)
+			m := &MPV{socketPath: socket}
+			So(m.watchSession(done), ShouldEqual, "loading failed")
+			So(nextCommand(commands, "quit"), ShouldNotBeNil)
+		})
+
+		Convey("Should reload an expired stream and seek back to where it stopped", func() {
+			socket, commands := fakeMPV(t,
+				`{"event":"start-file"}`,
+				`{"event":"file-loaded"}`,
+				`{"event":"property-change","id":2,"name":"time-pos","data":611.5}`,
+				`{"event":"property-change","id":2,"name":"time-pos","data":null}`,
+				`{"event":"end-file","reason":"error","file_error":"HTTP error 403 Forbidden"}`,
+				`{"event":"start-file"}`,
+				`{"event":"file-loaded"}`,
+			)
+
+			refreshed := 0
+			m := &MPV{socketPath: socket}
+			m.SetRefresher(func(context.Context) (string, map[string]string, error) {
+				refreshed++
+				return "https://example.com/fresh.m3u8", map[string]string{"Referer": "https://example.com"}, nil
+			})
+
+			go m.watchSession(done)
+
+			So(nextCommand(commands, "set_property"), ShouldResemble,
+				[]interface{}{"set_property", "http-header-fields", []interface{}{"Referer: https://example.com"}})
+			So(nextCommand(commands, "loadfile"), ShouldResemble,
+				[]interface{}{"loadfile", "https://example.com/fresh.m3u8", "replace"})
+			So(nextCommand(commands, "seek"), ShouldResemble, []interface{}{"seek", 611.5, "absolute"})
+			So(refreshed, ShouldEqual, 1)
+		})
+
+		Convey("Should tell and quit when no fresh link is found", func() {
+			socket, commands := fakeMPV(t,
+				`{"event":"file-loaded"}`,
+				`{"event":"end-file","reason":"error"}`,
+			)
+			m := &MPV{socketPath: socket}
+			m.SetRefresher(func(context.Context) (string, map[string]string, error) {
+				return "", nil, errors.New("source is down")
+			})
+
+			go m.watchSession(done)
+			So(nextCommand(commands, "show-text"), ShouldNotBeNil)
+			So(nextCommand(commands, "quit"), ShouldNotBeNil)
+		})
+
+		Convey("Should quit once the stream failed too often", func() {
+			events := []string{`{"event":"file-loaded"}`}
+			for i := 0; i <= maxRecoveries; i++ {
+				events = append(events, `{"event":"end-file","reason":"error"}`, `{"event":"file-loaded"}`)
+			}
+			socket, commands := fakeMPV(t, events...)
+
+			refreshed := 0
+			m := &MPV{socketPath: socket}
+			m.SetRefresher(func(context.Context) (string, map[string]string, error) {
+				refreshed++
+				return "https://example.com/fresh.m3u8", nil, nil
+			})
+
+			So(m.watchSession(done), ShouldBeEmpty)
+			So(refreshed, ShouldEqual, maxRecoveries)
+			So(nextCommand(commands, "quit"), ShouldNotBeNil)
+		})
+
+		Convey("Should give up when the player is gone", func() {
+			stopped := make(chan struct{})
+			close(stopped)
+			m := &MPV{socketPath: filepath.Join(os.TempDir(), fmt.Sprintf("anisan-missing-%d.sock", time.Now().UnixNano()))}
+			So(m.watchSession(stopped), ShouldBeEmpty)
+		})
+	})
+}
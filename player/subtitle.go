@@ -0,0 +1,52 @@
+package player
+
+import (
+	"sort"
+	"strings"
+)
+
+// Subtitle is an external subtitle track loaded alongside the media.
+type Subtitle struct {
+	URL      string
+	Language string
+}
+
+// subtitleLanguages splits a comma-separated language preference such as "en,eng".
+func subtitleLanguages(preference string) []string {
+	var languages []string
+	for _, l := range strings.Split(preference, ",") {
+		if l = strings.ToLower(strings.TrimSpace(l)); l != "" {
+			languages = append(languages, l)
+		}
+	}
+
+	return languages
+}
+
+// languageRank returns the position of the first preferred language matching tag,
+// or len(preferred) if there is none. "en" matches "en", "en-US" and "en_GB".
+func languageRank(tag string, preferred []string) int {
+	tag = strings.ToLower(tag)
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+
+	for i, p := range preferred {
+		if tag == p || primary == p {
+			return i
+		}
+	}
+
+	return len(preferred)
+}
+
+// orderSubtitles moves tracks in the preferred languages to the front, keeping the order
+// of the source otherwise, so that the player picks them first.
+func orderSubtitles(subtitles []Subtitle, preferred []string) []Subtitle {
+	ordered := make([]Subtitle, len(subtitles))
+	copy(ordered, subtitles)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return languageRank(ordered[i].Language, preferred) < languageRank(ordered[j].Language, preferred)
+	})
+
+	return ordered
+}
@@ -0,0 +1,134 @@
+package custom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/anisan-cli/anisan/key"
+	"github.com/spf13/viper"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Limits a call into a script can run into.
+const (
+	LimitTime         = "time"
+	LimitInstructions = "instructions"
+	LimitStack        = "stack"
+)
+
+// mainChunk names the top-level code of a script in budget errors.
+const mainChunk = "main chunk"
+
+// registrySlotSize approximates the bytes taken by one slot of the Lua value stack.
+const registrySlotSize = 16
+
+// Budget bounds every call into a script. Zero values disable a limit.
+type Budget struct {
+	// Timeout is the wall-clock time a call may take, including its HTTP requests.
+	Timeout time.Duration
+	// Instructions is the number of Lua VM instructions a call may execute.
+	Instructions int64
+	// StackMiB caps the Lua value stack of the VM, which is where runaway
+	// recursion and unbounded unpacking end up. Strings and tables live on the
+	// Go heap, which cannot be bounded per VM, and are not counted.
+	StackMiB int
+}
+
+// BudgetFromConfig returns the budget set with the sources.timeout,
+// sources.max_instructions and sources.max_stack keys.
+func BudgetFromConfig() Budget {
+	return Budget{
+		Timeout:      time.Duration(viper.GetInt(key.SourcesTimeout)) * time.Second,
+		Instructions: viper.GetInt64(key.SourcesMaxInstructions),
+		StackMiB:     viper.GetInt(key.SourcesMaxStack),
+	}
+}
+
+// options returns the VM options enforcing the stack limit.
+func (b Budget) options() lua.Options {
+	if b.StackMiB <= 0 {
+		return lua.Options{}
+	}
+
+	return lua.Options{
+		RegistrySize:    lua.RegistrySize,
+		RegistryMaxSize: max(lua.RegistrySize, b.StackMiB<<20/registrySlotSize),
+	}
+}
+
+// BudgetError is returned when a call into a script runs out of its budget.
+// It is a source.BudgetError.
+type BudgetError struct {
+	Source   string
+	Function string
+	Limit    string // One of LimitTime, LimitInstructions or LimitStack
+	Budget   Budget
+}
+
+// Exceeded implements source.BudgetError.
+func (e *BudgetError) Exceeded() (source, function string) {
+	return e.Source, e.Function
+}
+
+func (e *BudgetError) Error() string {
+	switch e.Limit {
+	case LimitTime:
+		return fmt.Sprintf("source %s: %s timed out after %s (%s)", e.Source, e.Function, e.Budget.Timeout, key.SourcesTimeout)
+	case LimitInstructions:
+		return fmt.Sprintf("source %s: %s exceeded %d instructions (%s)", e.Source, e.Function, e.Budget.Instructions, key.SourcesMaxInstructions)
+	default:
+		return fmt.Sprintf("source %s: %s exceeded %d MiB of stack (%s)", e.Source, e.Function, e.Budget.StackMiB, key.SourcesMaxStack)
+	}
+}
+
+// meter is the context the VM runs a call under. gopher-lua polls Done before every
+// instruction, which is what the instruction limit counts. Go code called by the
+// script, such as HTTP requests, must use the context it wraps, see luaContext.
+type meter struct {
+	context.Context
+	cancel   context.CancelFunc
+	limit    int64
+	executed atomic.Int64
+}
+
+// meter starts measuring a call made with ctx. The caller must call stop once the call returns.
+func (b Budget) meter(ctx context.Context) *meter {
+	var cancel context.CancelFunc
+	if b.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, b.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	return &meter{Context: ctx, cancel: cancel, limit: b.Instructions}
+}
+
+func (m *meter) Done() <-chan struct{} {
+	if m.limit > 0 && m.executed.Add(1) == m.limit+1 {
+		m.cancel()
+	}
+	return m.Context.Done()
+}
+
+func (m *meter) stop() {
+	m.cancel()
+}
+
+// exceeded returns the limit that made the call fail with err, if any.
+// Calls cancelled by their caller exceed nothing.
+func (m *meter) exceeded(err error) string {
+	switch {
+	case m.limit > 0 && m.executed.Load() > m.limit:
+		return LimitInstructions
+	case errors.Is(m.Context.Err(), context.DeadlineExceeded):
+		return LimitTime
+	case strings.Contains(err.Error(), "registry overflow"):
+		return LimitStack
+	}
+
+	return ""
+}
@@ -0,0 +1,148 @@
+package custom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anisan-cli/anisan/source"
+	"github.com/samber/lo"
+	. "github.com/smartystreets/goconvey/convey"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+const budgetScript = `
+function Spin() while true do end end
+function Unpack()
+	local t = {}
+	for i = 1, 1000000 do t[i] = i end
+	return unpack(t)
+end
+function Echo(v) return v end
+function Request()
+	local body = poll()
+	for _ = 1, 10 do body = body .. "" end
+	return body
+end
+`
+
+func TestBudget(t *testing.T) {
+	Convey("Given a source with a budget", t, func() {
+		budget := Budget{Timeout: time.Minute, Instructions: 100_000, StackMiB: 1}
+		pool := newStatePool(1, func() (*lua.LState, error) {
+			L := lua.NewState(budget.options())
+			// poll waits on the call like an HTTP request does
+			L.SetGlobal("poll", L.NewFunction(func(L *lua.LState) int {
+				for i := 0; i < 1_000_000; i++ {
+					select {
+					case <-luaContext(L).Done():
+					default:
+					}
+				}
+				L.Push(lua.LString("ok"))
+				return 1
+			}))
+			return L, L.DoString(budgetScript)
+		})
+		defer pool.Close()
+
+		src, _ := newLuaSource("test", pool)
+		src.budget = budget
+
+		call := func(ctx context.Context, fn string) error {
+			_, err := src.call(ctx, fn, lua.LTString, func(*lua.LState) []lua.LValue {
+				return []lua.LValue{lua.LString("ok")}
+			})
+			return err
+		}
+
+		Convey("An endless loop runs out of instructions", func() {
+			err := call(context.Background(), "Spin")
+
+			var budgetErr *BudgetError
+			So(errors.As(err, &budgetErr), ShouldBeTrue)
+			So(budgetErr.Limit, ShouldEqual, LimitInstructions)
+			So(err.Error(), ShouldContainSubstring, "source test: Spin exceeded 100000 instructions")
+			So(source.IsBudgetError(fmt.Errorf("playing: %w", err)), ShouldBeTrue)
+
+			Convey("And the VM is replaced for the next call", func() {
+				So(call(context.Background(), "Echo"), ShouldBeNil)
+			})
+		})
+
+		Convey("Waiting on requests does not use up instructions", func() {
+			So(call(context.Background(), "Request"), ShouldBeNil)
+		})
+
+		Convey("A slow call runs out of time", func() {
+			src.budget = Budget{Timeout: 20 * time.Millisecond}
+			err := call(context.Background(), "Spin")
+
+			var budgetErr *BudgetError
+			So(errors.As(err, &budgetErr), ShouldBeTrue)
+			So(budgetErr.Limit, ShouldEqual, LimitTime)
+			So(err.Error(), ShouldContainSubstring, "Spin timed out after 20ms")
+		})
+
+		Convey("Filling the stack exceeds the stack limit", func() {
+			src.budget.Instructions = 0
+			err := call(context.Background(), "Unpack")
+
+			var budgetErr *BudgetError
+			So(errors.As(err, &budgetErr), ShouldBeTrue)
+			So(budgetErr.Limit, ShouldEqual, LimitStack)
+		})
+
+		Convey("Cancelling the caller is not a budget error", func() {
+			src.budget = Budget{}
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			err := call(ctx, "Spin")
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		})
+	})
+}
+
+func TestBudgetOutsideCalls(t *testing.T) {
+	Convey("Given a budget", t, func() {
+		budget := Budget{Instructions: 100_000}
+
+		Convey("A script looping in its top-level chunk fails to load", func() {
+			proto, err := lua.Compile(lo.Must(parse.Parse(strings.NewReader(`while true do end`), "spin.lua")), "spin.lua")
+			So(err, ShouldBeNil)
+
+			_, err = newState("spin", proto, &Permissions{}, budget, nil, nil, &Manifest{})
+
+			var budgetErr *BudgetError
+			So(errors.As(err, &budgetErr), ShouldBeTrue)
+			So(err.Error(), ShouldContainSubstring, "source spin: main chunk exceeded 100000 instructions")
+		})
+
+		Convey("A SearchFilters looping forever yields no filters", func() {
+			pool := newStatePool(1, func() (*lua.LState, error) {
+				L := lua.NewState(budget.options())
+				return L, L.DoString(`function SearchFilters() while true do end end`)
+			})
+			defer pool.Close()
+
+			src, _ := newLuaSource("test", pool)
+			src.budget = budget
+
+			So(src.Filters(), ShouldBeEmpty)
+
+			Convey("And the VM is given back to the pool", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+
+				L, err := pool.acquire(ctx)
+				So(err, ShouldBeNil)
+				pool.release(L)
+			})
+		})
+	})
+}
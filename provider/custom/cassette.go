@@ -0,0 +1,155 @@
+package custom
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/anisan-cli/anisan/filesystem"
+)
+
+// Interaction is one recorded http_tls exchange.
+type Interaction struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+
+	Status   int    `json:"status"`
+	Response string `json:"response"`
+	// Error is set for requests that failed without a response.
+	Error string `json:"error,omitempty"`
+}
+
+// Cassette records the http_tls exchanges of a source, or replays them without network.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+
+	path      string
+	recording bool
+
+	mu     sync.Mutex
+	played map[*Interaction]bool
+
+	// store replaces the stores of sources run with the cassette, so that what they
+	// remember, such as the working mirror, is the same when recording and replaying
+	// and the stores of the user are left alone.
+	store *memoryStore
+}
+
+// NewCassette returns an empty cassette that records into the file at path once saved.
+func NewCassette(path string) *Cassette {
+	return &Cassette{path: path, recording: true, store: newMemoryStore()}
+}
+
+// LoadCassette reads the cassette at path for replay.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := filesystem.API().ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no cassette at %s, record one first", path)
+		}
+		return nil, err
+	}
+
+	c := &Cassette{path: path, played: make(map[*Interaction]bool), store: newMemoryStore()}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("read cassette %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Recording reports whether the cassette records live exchanges rather than replaying them.
+func (c *Cassette) Recording() bool {
+	return c.recording
+}
+
+// Path returns the file of the cassette.
+func (c *Cassette) Path() string {
+	return c.path
+}
+
+// Save writes the recorded exchanges to the cassette file.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := filesystem.API().MkdirAll(filepath.Dir(c.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return filesystem.API().WriteFile(c.path, data, 0o644)
+}
+
+// do performs the request with live when recording, and looks up its recorded response otherwise.
+func (c *Cassette) do(ctx context.Context, method, url, body string, live func() (string, int, error)) (string, int, error) {
+	if c.recording {
+		response, status, err := live()
+		if ctx.Err() != nil {
+			return response, status, err
+		}
+
+		interaction := &Interaction{Method: method, URL: url, Body: body, Status: status, Response: response}
+		if err != nil {
+			interaction.Error = err.Error()
+		}
+
+		c.mu.Lock()
+		c.Interactions = append(c.Interactions, interaction)
+		c.mu.Unlock()
+
+		return response, status, err
+	}
+
+	interaction := c.find(method, url, body)
+	if interaction == nil {
+		return "", 0, fmt.Errorf("cassette %s has no response for %s %s", c.path, method, url)
+	}
+
+	if interaction.Error != "" {
+		return "", 0, errors.New(interaction.Error)
+	}
+
+	return interaction.Response, interaction.Status, nil
+}
+
+// storeOf returns the store of the named source while it runs with the cassette.
+// It starts empty every run, when recording as when replaying.
+func (c *Cassette) storeOf(name string) Store {
+	return Store{namespace: name, memory: c.store}
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: make(map[string]map[string]storeEntry)}
+}
+
+// find returns the first matching interaction not played yet.
+// Once all of them were played, the last one is replayed again.
+func (c *Cassette) find(method, url, body string) *Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var last *Interaction
+	for _, i := range c.Interactions {
+		if i.Method != method || i.URL != url || i.Body != body {
+			continue
+		}
+
+		if !c.played[i] {
+			c.played[i] = true
+			return i
+		}
+		last = i
+	}
+
+	return last
+}
@@ -2,6 +2,8 @@
 package custom
 
 import (
+	"context"
+
 	"github.com/anisan-cli/anisan/constant"
 	"github.com/anisan-cli/anisan/internal/cache"
 	"github.com/anisan-cli/anisan/source"
@@ -9,6 +11,10 @@ import (
 )
 
 func (s *luaSource) EpisodesOf(anime *source.Anime) ([]*source.Episode, error) {
+	return s.EpisodesOfContext(context.Background(), anime)
+}
+
+func (s *luaSource) EpisodesOfContext(ctx context.Context, anime *source.Anime) ([]*source.Episode, error) {
 	cacheKey := cache.GenerateKey(anime.URL, s.Name()+"_episodes")
 	var cachedEpisodes []*source.Episode
 	if cache.Read(cacheKey, &cachedEpisodes) {
@@ -16,10 +22,13 @@ func (s *luaSource) EpisodesOf(anime *source.Anime) ([]*source.Episode, error) {
 			ep.Anime = anime
 		}
 		anime.Episodes = cachedEpisodes
+		source.FromCache(ctx)
 		return cachedEpisodes, nil
 	}
 
-	val, err := s.call(constant.AnimeEpisodesFn, lua.LTTable, animeToTable(s.state, anime))
+	val, err := s.call(ctx, constant.AnimeEpisodesFn, lua.LTTable, func(L *lua.LState) []lua.LValue {
+		return []lua.LValue{animeToTable(L, anime)}
+	})
 	if err != nil {
 		return nil, err
 	}
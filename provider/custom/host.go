@@ -0,0 +1,330 @@
+package custom
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anisan-cli/anisan/config"
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/log"
+	"github.com/anisan-cli/anisan/where"
+	"github.com/metafates/gache"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// registerHost injects the "anisan" global module, the API anisan offers to the script of the named source:
+//
+//	anisan.log.debug(...), anisan.log.info(...), anisan.log.warn(...), anisan.log.error(...)
+//	anisan.store.get(key)                       → value or nil
+//	anisan.store.set(key, value [, ttl_seconds]) stores a string, number or boolean, nil deletes it
+//	anisan.store.delete(key)
+//	anisan.config.get(option [, fallback])      → value of sources.<name>.<option>, if declared by the manifest
+//	anisan.mirror.get(mirror)                   → url of the declared mirror that works
+//	anisan.mirror.failed(mirror [, url])        → url to use instead of the failing one
+//
+// The urls of mirrors are probed with client.
+func registerHost(L *lua.LState, name string, manifest *Manifest, client tlsClient) {
+	mod := L.NewTable()
+
+	logger := L.NewTable()
+	for level, write := range map[string]func(...any){
+		"debug": log.Debug,
+		"info":  log.Info,
+		"warn":  log.Warn,
+		"error": log.Error,
+	} {
+		L.SetField(logger, level, L.NewFunction(func(L *lua.LState) int {
+			write(name + ": " + luaArgs(L))
+			return 0
+		}))
+	}
+	L.SetField(mod, "log", logger)
+
+	store := client.storeOf(name)
+	storage := L.NewTable()
+	L.SetField(storage, "get", L.NewFunction(func(L *lua.LState) int {
+		value, ok := store.Get(L.CheckString(1))
+		if !ok {
+			L.Push(lua.LNil)
+			return 1
+		}
+		L.Push(toLua(L, value))
+		return 1
+	}))
+	L.SetField(storage, "set", L.NewFunction(func(L *lua.LState) int {
+		k := L.CheckString(1)
+		ttl := time.Duration(float64(L.OptNumber(3, 0)) * float64(time.Second))
+
+		var err error
+		switch value := L.Get(2).(type) {
+		case *lua.LNilType:
+			err = store.Delete(k)
+		case lua.LString:
+			err = store.Set(k, string(value), ttl)
+		case lua.LNumber:
+			err = store.Set(k, float64(value), ttl)
+		case lua.LBool:
+			err = store.Set(k, bool(value), ttl)
+		default:
+			L.ArgError(2, "expected a string, number or boolean, encode tables with json first")
+		}
+
+		if err != nil {
+			L.RaiseError("anisan.store.set: %s", err.Error())
+		}
+		return 0
+	}))
+	L.SetField(storage, "delete", L.NewFunction(func(L *lua.LState) int {
+		if err := store.Delete(L.CheckString(1)); err != nil {
+			L.RaiseError("anisan.store.delete: %s", err.Error())
+		}
+		return 0
+	}))
+	L.SetField(mod, "store", storage)
+
+	// Only the options the manifest declares are served, other keys may hold secrets of a builtin of the same name
+	declared := manifest.optionNames()
+	settings := L.NewTable()
+	L.SetField(settings, "get", L.NewFunction(func(L *lua.LState) int {
+		option := L.CheckString(1)
+		k := config.SourceOptionKey(name, option)
+		if !lo.Contains(declared, option) || !viper.IsSet(k) {
+			L.Push(L.Get(2))
+			return 1
+		}
+		L.Push(toLua(L, viper.Get(k)))
+		return 1
+	}))
+	L.SetField(mod, "config", settings)
+
+	resolver := mirrorResolver{source: name, client: client}
+	checkMirror := func(L *lua.LState) Mirror {
+		n := L.CheckString(1)
+		mirror, ok := lo.Find(manifest.Mirrors, func(m Mirror) bool { return m.Name == n })
+		if !ok {
+			L.ArgError(1, fmt.Sprintf("unknown mirror %q, declare it with \"-- @mirror %s <url>\"", n, n))
+		}
+		return mirror
+	}
+	mirror := L.NewTable()
+	L.SetField(mirror, "get", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString(resolver.resolve(luaContext(L), checkMirror(L))))
+		return 1
+	}))
+	L.SetField(mirror, "failed", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString(resolver.fail(luaContext(L), checkMirror(L), L.OptString(2, ""))))
+		return 1
+	}))
+	L.SetField(mod, "mirror", mirror)
+
+	L.SetGlobal("anisan", mod)
+}
+
+// RegisterOptions makes the options declared by the manifest of the named source configuration keys,
+// along with a key overriding each of its mirrors.
+func RegisterOptions(name string, manifest *Manifest) {
+	for _, option := range manifest.Options {
+		description := option.Description
+		if description == "" {
+			description = fmt.Sprintf("Option %q of the %s source", option.Name, name)
+		}
+		config.RegisterSourceOption(name, option.Name, option.Default, description)
+	}
+
+	for _, mirror := range manifest.Mirrors {
+		config.RegisterSourceOption(name, mirrorOption(mirror.Name), "",
+			fmt.Sprintf("URL of the %s mirror of the %s source, used instead of probing %s", mirror.Name, name, strings.Join(mirror.URLs, ", ")))
+	}
+}
+
+// optionNames returns the names of the options the manifest registers, those of its mirrors included.
+func (m *Manifest) optionNames() []string {
+	names := lo.Map(m.Options, func(option Option, _ int) string { return option.Name })
+	for _, mirror := range m.Mirrors {
+		names = append(names, mirrorOption(mirror.Name))
+	}
+	return names
+}
+
+// luaArgs joins the arguments of the call like print does.
+func luaArgs(L *lua.LState) string {
+	parts := make([]string, L.GetTop())
+	for i := range parts {
+		parts[i] = L.ToStringMeta(L.Get(i + 1)).String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// toLua converts a configuration or stored value to Lua.
+func toLua(L *lua.LState, value any) lua.LValue {
+	switch value := value.(type) {
+	case nil:
+		return lua.LNil
+	case string:
+		return lua.LString(value)
+	case bool:
+		return lua.LBool(value)
+	case int:
+		return lua.LNumber(value)
+	case int64:
+		return lua.LNumber(value)
+	case float64:
+		return lua.LNumber(value)
+	case []string:
+		table := L.NewTable()
+		for _, v := range value {
+			table.Append(lua.LString(v))
+		}
+		return table
+	case []any:
+		table := L.NewTable()
+		for _, v := range value {
+			table.Append(toLua(L, v))
+		}
+		return table
+	default:
+		return lua.LString(fmt.Sprint(value))
+	}
+}
+
+// storeEntry is a value kept by a source, until it expires if it has an expiry.
+type storeEntry struct {
+	Value   any       `json:"value"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+func (e storeEntry) expired() bool {
+	return !e.Expires.IsZero() && time.Now().After(e.Expires)
+}
+
+var (
+	storeMutex sync.Mutex
+	stores     = gache.New[map[string]map[string]storeEntry](
+		&gache.Options{
+			Path:       where.SourceStore(),
+			FileSystem: &filesystem.GacheFs{},
+		},
+	)
+)
+
+func loadStores() (map[string]map[string]storeEntry, error) {
+	cached, expired, err := stores.Get()
+	if err != nil {
+		return nil, err
+	}
+	if expired || cached == nil {
+		return make(map[string]map[string]storeEntry), nil
+	}
+	return cached, nil
+}
+
+// Store is the key/value store of a source, kept on disk between runs.
+// Every source has its own namespace.
+type Store struct {
+	namespace string
+	memory    *memoryStore // Replaces the disk when set
+}
+
+// memoryStore holds the entries of a store that is not kept between runs.
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]map[string]storeEntry
+}
+
+// StoreOf returns the store of the named source.
+func StoreOf(name string) Store {
+	return Store{namespace: name}
+}
+
+// Get returns the value stored under key, unless it is missing or expired.
+func (s Store) Get(key string) (any, bool) {
+	unlock := s.lock()
+	defer unlock()
+
+	all, err := s.load()
+	if err != nil {
+		log.Warn(err)
+		return nil, false
+	}
+
+	entry, ok := all[s.namespace][key]
+	if !ok || entry.expired() {
+		return nil, false
+	}
+
+	return entry.Value, true
+}
+
+// Set stores value under key. A positive ttl makes it expire after that long.
+func (s Store) Set(key string, value any, ttl time.Duration) error {
+	return s.update(func(entries map[string]storeEntry) {
+		entry := storeEntry{Value: value}
+		if ttl > 0 {
+			entry.Expires = time.Now().Add(ttl)
+		}
+		entries[key] = entry
+	})
+}
+
+// Delete removes the value stored under key.
+func (s Store) Delete(key string) error {
+	return s.update(func(entries map[string]storeEntry) {
+		delete(entries, key)
+	})
+}
+
+// update changes the entries of the store and drops the expired ones.
+func (s Store) update(change func(entries map[string]storeEntry)) error {
+	unlock := s.lock()
+	defer unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	entries, ok := all[s.namespace]
+	if !ok {
+		entries = make(map[string]storeEntry)
+		all[s.namespace] = entries
+	}
+
+	change(entries)
+	for k, entry := range entries {
+		if entry.expired() {
+			delete(entries, k)
+		}
+	}
+	if len(entries) == 0 {
+		delete(all, s.namespace)
+	}
+
+	if s.memory != nil {
+		return nil
+	}
+	return stores.Set(all)
+}
+
+// lock locks the backing of the store and returns the function unlocking it.
+func (s Store) lock() func() {
+	if s.memory != nil {
+		s.memory.mu.Lock()
+		return s.memory.mu.Unlock
+	}
+
+	storeMutex.Lock()
+	return storeMutex.Unlock
+}
+
+// load returns the entries of every source in the backing of the store.
+func (s Store) load() (map[string]map[string]storeEntry, error) {
+	if s.memory != nil {
+		return s.memory.entries, nil
+	}
+
+	return loadStores()
+}
@@ -0,0 +1,207 @@
+package custom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anisan-cli/anisan/config"
+	"github.com/anisan-cli/anisan/filesystem"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestHost(t *testing.T) {
+	Convey("Given a source with declared options", t, func() {
+		filesystem.SetMemMapFs()
+
+		manifest, err := ParseManifest(strings.NewReader("-- @option server vidstream Streaming server to prefer\n-- @option quality 1080\n"))
+		So(err, ShouldBeNil)
+		RegisterOptions("hosted", manifest)
+
+		L := newSandboxState(Permissions{}, lua.Options{}, nil)
+		defer L.Close()
+		registerHost(L, "hosted", manifest, tlsClient{})
+
+		Convey("Then its options are configuration keys", func() {
+			So(config.Default, ShouldContainKey, "sources.hosted.server")
+			So(config.Default["sources.hosted.quality"].Value, ShouldEqual, 1080)
+		})
+
+		Convey("When the script reads its options", func() {
+			So(L.DoString(`assert(anisan.config.get("server") == "vidstream")
+				assert(anisan.config.get("quality") == 1080)
+				assert(anisan.config.get("missing") == nil)
+				assert(anisan.config.get("missing", "fallback") == "fallback")`), ShouldBeNil)
+
+			Convey("Then options it did not declare are not served", func() {
+				viper.Set("sources.hosted.api_key", "secret")
+				defer viper.Set("sources.hosted.api_key", nil)
+
+				So(L.DoString(`assert(anisan.config.get("api_key") == nil)
+					assert(anisan.config.get("api_key", "fallback") == "fallback")`), ShouldBeNil)
+			})
+
+			Convey("Then values set by the user win", func() {
+				viper.Set("sources.hosted.server", "megacloud")
+				defer viper.Set("sources.hosted.server", nil)
+
+				So(L.DoString(`assert(anisan.config.get("server") == "megacloud")`), ShouldBeNil)
+			})
+		})
+
+		Convey("When the script stores values", func() {
+			So(L.DoString(`anisan.store.set("domain", "example.org")
+				anisan.store.set("attempts", 2)
+				anisan.store.set("forever", "yes", -1)
+				anisan.store.set("stale", "yes", 0.000001)`), ShouldBeNil)
+
+			Convey("Then they are read back, until they expire", func() {
+				So(L.DoString(`assert(anisan.store.get("domain") == "example.org")
+					assert(anisan.store.get("attempts") == 2)
+					assert(anisan.store.get("forever") == "yes")
+					assert(anisan.store.get("stale") == nil)
+					assert(anisan.store.get("missing") == nil)`), ShouldBeNil)
+			})
+
+			Convey("Then other sources do not see them", func() {
+				value, ok := StoreOf("other").Get("domain")
+				So(ok, ShouldBeFalse)
+				So(value, ShouldBeNil)
+			})
+
+			Convey("Then setting nil deletes them", func() {
+				So(L.DoString(`anisan.store.set("domain", nil)
+					assert(anisan.store.get("domain") == nil)`), ShouldBeNil)
+			})
+
+			Convey("Then tables are refused", func() {
+				err := L.DoString(`anisan.store.set("table", {})`)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "encode tables with json")
+			})
+		})
+
+		Convey("When the script logs", func() {
+			So(L.DoString(`anisan.log.info("resolved", 2, "servers")
+				anisan.log.warn("slow")`), ShouldBeNil)
+		})
+	})
+}
+
+func TestMirrors(t *testing.T) {
+	Convey("Given a source with a mirror that moved", t, func() {
+		filesystem.SetMemMapFs()
+
+		serve := func(status int) *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}))
+		}
+		down, first, second := serve(http.StatusBadGateway), serve(http.StatusOK), serve(http.StatusForbidden)
+		defer down.Close()
+		defer first.Close()
+		defer second.Close()
+
+		manifest, err := ParseManifest(strings.NewReader("-- @mirror site " + down.URL + ", " + first.URL + ", " + second.URL + "\n"))
+		So(err, ShouldBeNil)
+		RegisterOptions("mirrored", manifest)
+
+		permissions := manifest.RequestedPermissions()
+		L := newSandboxState(permissions, lua.Options{}, nil)
+		defer L.Close()
+		registerHost(L, "mirrored", manifest, tlsClient{allow: permissions.AllowsURL})
+
+		active := func() string {
+			So(L.DoString(`active = anisan.mirror.get("site")`), ShouldBeNil)
+			return L.GetGlobal("active").String()
+		}
+
+		Convey("When the script asks for its url", func() {
+			url := active()
+
+			Convey("Then the first url that answers is used and remembered", func() {
+				So(url, ShouldEqual, first.URL)
+
+				known, ok := KnownMirror("mirrored", manifest.Mirrors[0])
+				So(ok, ShouldBeTrue)
+				So(known, ShouldEqual, url)
+			})
+
+			Convey("Then reporting it as failing switches to the other one", func() {
+				So(L.DoString(`replacement = anisan.mirror.failed("site")`), ShouldBeNil)
+				replacement := L.GetGlobal("replacement").String()
+
+				So(replacement, ShouldEqual, second.URL)
+				So(active(), ShouldEqual, second.URL)
+			})
+		})
+
+		Convey("When the user sets the url", func() {
+			viper.Set("sources.mirrored.mirrors.site", "https://example.org/")
+			defer viper.Set("sources.mirrored.mirrors.site", nil)
+
+			Convey("Then it is used without probing", func() {
+				So(active(), ShouldEqual, "https://example.org")
+			})
+		})
+
+		Convey("When the script asks for an undeclared mirror", func() {
+			err := L.DoString(`anisan.mirror.get("cdn")`)
+
+			Convey("Then it fails", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "@mirror cdn")
+			})
+		})
+	})
+}
+
+func TestMirrorsWithCassette(t *testing.T) {
+	Convey("Given a source with a mirror, recorded to a cassette", t, func() {
+		filesystem.SetMemMapFs()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		manifest, err := ParseManifest(strings.NewReader("-- @mirror site https://stale.example.org, " + server.URL + "\n"))
+		So(err, ShouldBeNil)
+		RegisterOptions("taped", manifest)
+
+		// The user remembers another url than the one the recording finds
+		So(StoreOf("taped").Set(mirrorStoreKey("site"), "https://stale.example.org", mirrorTTL), ShouldBeNil)
+
+		run := func(cassette *Cassette) string {
+			L := lua.NewState()
+			defer L.Close()
+			registerHost(L, "taped", manifest, tlsClient{cassette: cassette})
+
+			So(L.DoString(`active = anisan.mirror.failed("site", "https://stale.example.org")`), ShouldBeNil)
+			return L.GetGlobal("active").String()
+		}
+
+		cassette := NewCassette(filepath.Join(t.TempDir(), "taped.json"))
+		recorded := run(cassette)
+		So(recorded, ShouldEqual, server.URL)
+		So(cassette.Save(), ShouldBeNil)
+
+		Convey("Then the store of the user is left alone", func() {
+			known, ok := KnownMirror("taped", manifest.Mirrors[0])
+			So(ok, ShouldBeTrue)
+			So(known, ShouldEqual, "https://stale.example.org")
+		})
+
+		Convey("Then replaying picks the recorded mirror", func() {
+			server.Close()
+
+			replay, err := LoadCassette(cassette.Path())
+			So(err, ShouldBeNil)
+			So(run(replay), ShouldEqual, recorded)
+		})
+	})
+}
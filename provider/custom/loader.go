@@ -2,13 +2,18 @@
 package custom
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/anisan-cli/anisan/constant"
 	"github.com/anisan-cli/anisan/internal/scraper"
+	"github.com/anisan-cli/anisan/key"
 	"github.com/anisan-cli/anisan/source"
-	"github.com/anisan-cli/anisan/util"
 	libs "github.com/metafates/mangal-lua-libs"
+	luahttp "github.com/metafates/mangal-lua-libs/http"
+	httpclient "github.com/metafates/mangal-lua-libs/http/client"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -17,19 +22,78 @@ func IDfromName(name string) string {
 	return name + " custom"
 }
 
+// LoadOptions change how a source is loaded.
+type LoadOptions struct {
+	// Cassette records or replays the http_tls requests of the source.
+	Cassette *Cassette
+}
+
 // LoadSource initializes a new source.Source instance by executing and validating a Lua scraper script.
+// The path is either a script or a package directory with an init.lua.
 func LoadSource(path string) (source.Source, error) {
-	state := lua.NewState()
-	libs.Preload(state)
-	registerTLSClient(state) // Injected from wrapper_tls.go
+	return LoadSourceWithOptions(path, LoadOptions{})
+}
+
+// LoadSourceWithOptions is LoadSource with options.
+func LoadSourceWithOptions(path string, options LoadOptions) (source.Source, error) {
+	src, err := loadLuaSource(path, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return src, nil
+}
+
+func loadLuaSource(path string, options LoadOptions) (*luaSource, error) {
+	name, script := SourceName(path), ScriptPath(path)
+
+	manifest, err := ReadManifest(script)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := manifest.CheckCompatibility(name); err != nil {
+		return nil, err
+	}
+
+	RegisterOptions(name, manifest)
+
+	var permissions *Permissions
+	if viper.GetBool(key.SourcesSandbox) {
+		if err := CheckPermissions(name, manifest); err != nil {
+			return nil, err
+		}
+
+		requested := manifest.RequestedPermissions()
+		// Mirrors set by the user need no grant.
+		_ = requested.add("hosts", hostsOf(lo.FilterMap(manifest.Mirrors, func(mirror Mirror, _ int) (string, bool) {
+			return ConfiguredMirror(name, mirror.Name)
+		})))
+		permissions = &requested
+	}
+
+	// Compile once; every pooled VM is then built from the cached prototypes.
+	if IsPackage(path) {
+		if err := scraper.CompileDir(path); err != nil {
+			return nil, err
+		}
+	}
 
-	// Load and compile the Lua script (using cache if available).
-	err := scraper.PreCompileAndLoad(state, path)
+	proto, err := scraper.Compile(script)
 	if err != nil {
 		return nil, err
 	}
 
-	name := util.FileStem(path)
+	budget := BudgetFromConfig()
+	roots := moduleRoots(script)
+	build := func() (*lua.LState, error) {
+		return newState(name, proto, permissions, budget, options.Cassette, roots, manifest)
+	}
+
+	state, err := build()
+	if err != nil {
+		return nil, err
+	}
 
 	// Validation
 	required := []string{
@@ -40,9 +104,59 @@ func LoadSource(path string) (source.Source, error) {
 
 	for _, fn := range required {
 		if state.GetGlobal(fn).Type() != lua.LTFunction {
+			state.Close()
 			return nil, fmt.Errorf("function %s is required but not defined in %s", fn, name)
 		}
 	}
 
-	return newLuaSource(name, state)
+	pool := newStatePool(viper.GetInt(key.SourcesPoolSize), build)
+	pool.put(state)
+
+	src, err := newLuaSource(name, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	src.budget = budget
+	return src, nil
+}
+
+// newState builds a ready-to-call VM for the named source by running the compiled script in a fresh LState.
+// The VM is sandboxed to the permissions unless they are nil. Modules are required from the roots.
+// The manifest declares the options and mirrors the script may use.
+func newState(name string, proto *lua.FunctionProto, permissions *Permissions, budget Budget, cassette *Cassette, roots []string, manifest *Manifest) (*lua.LState, error) {
+	var state *lua.LState
+	client := tlsClient{cassette: cassette}
+	if permissions != nil {
+		state = newSandboxState(*permissions, budget.options(), cassette)
+		client.allow = permissions.AllowsURL
+	} else {
+		state = lua.NewState(budget.options())
+		libs.Preload(state)
+		registerTLSClient(state, client) // Injected from wrapper_tls.go
+		if cassette != nil {
+			allowAll := func(string) error { return nil }
+			state.PreloadModule("http", guardedHTTP(luahttp.Loader, allowAll, true, cassette))
+			state.PreloadModule("http_client", guardedHTTP(httpclient.Loader, allowAll, true, cassette))
+		}
+	}
+	registerHost(state, name, manifest, client)
+	registerModules(state, roots, permissions != nil)
+
+	// The top-level chunk runs within the budget of a call, as scripts may do anything there too
+	m := budget.meter(context.Background())
+	state.SetContext(m)
+	err := scraper.Load(state, proto)
+	state.RemoveContext()
+	m.stop()
+
+	if err != nil {
+		state.Close()
+		if limit := m.exceeded(err); limit != "" {
+			return nil, &BudgetError{Source: name, Function: mainChunk, Limit: limit, Budget: budget}
+		}
+		return nil, err
+	}
+
+	return state, nil
 }
@@ -0,0 +1,309 @@
+package custom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/anisan-cli/anisan/constant"
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/version"
+	"github.com/samber/lo"
+)
+
+// Capability names a feature a script declares in its manifest.
+const (
+	CapabilitySearch   = "search"
+	CapabilityEpisodes = "episodes"
+	CapabilityVideos   = "videos"
+	CapabilityFilters  = "filters"
+	CapabilityHeadless = "headless"
+)
+
+// Manifest is the metadata declared in the leading comment block of a Lua script:
+//
+//	-- @name    example
+//	-- @url     https://example.com
+//	-- @author  someone
+//	-- @license MIT
+//	-- @version 1.2.0
+//	-- @lang    en
+//	-- @min-anisan-version 0.1.0
+//	-- @capabilities search, episodes, videos
+//	-- @permission hosts   api.example.com, *.cdn.example.com
+//	-- @permission modules headless
+//	-- @option  server default Streaming server to prefer
+//	-- @mirror  api https://api.example.com, https://api.example.net
+type Manifest struct {
+	Name         string   `json:"name,omitempty"`
+	URL          string   `json:"url,omitempty"`
+	Author       string   `json:"author,omitempty"`
+	License      string   `json:"license,omitempty"`
+	Version      string   `json:"version,omitempty"`
+	Language     string   `json:"language,omitempty"`
+	MinVersion   string   `json:"min_anisan_version,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// Requires lists the modules loaded with require() anywhere in the script.
+	Requires []string `json:"requires,omitempty"`
+
+	// Permissions lists what the script needs beyond the sandbox defaults.
+	Permissions Permissions `json:"permissions"`
+
+	// Options lists the settings users can change with "anisan config set sources.<name>.<option>".
+	Options []Option `json:"options,omitempty"`
+
+	// Mirrors lists the domains the site is reachable at, the script reads the working one with anisan.mirror.get.
+	Mirrors []Mirror `json:"mirrors,omitempty"`
+}
+
+// Option is a setting declared by a script as "-- @option <name> <default> <description>".
+// The default is a boolean, an integer or a string, "" being the empty string.
+type Option struct {
+	Name        string `json:"name"`
+	Default     any    `json:"default"`
+	Description string `json:"description,omitempty"`
+}
+
+var optionName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// parseOption reads the value of an @option line.
+func parseOption(value string) (Option, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return Option{}, fmt.Errorf("option %q needs a name and a default value", value)
+	}
+
+	name := strings.ToLower(fields[0])
+	if !optionName.MatchString(name) {
+		return Option{}, fmt.Errorf("invalid option name %q, expected lowercase letters, digits and underscores", fields[0])
+	}
+
+	option := Option{Name: name, Description: strings.Join(fields[2:], " ")}
+	switch value := fields[1]; {
+	case value == "true" || value == "false":
+		option.Default = value == "true"
+	case value == `""`:
+		option.Default = ""
+	default:
+		if i, err := strconv.Atoi(value); err == nil {
+			option.Default = i
+		} else {
+			option.Default = value
+		}
+	}
+
+	return option, nil
+}
+
+// Mirror is a base URL of the site declared by a script as "-- @mirror <name> <url>, <url>...",
+// with every URL it may be reached at in order of preference.
+type Mirror struct {
+	Name string   `json:"name"`
+	URLs []string `json:"urls"`
+}
+
+// parseMirror reads the value of a @mirror line.
+func parseMirror(value string) (Mirror, error) {
+	fields := splitList(value)
+	if len(fields) < 2 {
+		return Mirror{}, fmt.Errorf("mirror %q needs a name and at least one url", value)
+	}
+
+	name := strings.ToLower(fields[0])
+	if !optionName.MatchString(name) {
+		return Mirror{}, fmt.Errorf("invalid mirror name %q, expected lowercase letters, digits and underscores", fields[0])
+	}
+
+	mirror := Mirror{Name: name}
+	for _, rawURL := range fields[1:] {
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Mirror{}, fmt.Errorf("invalid url %q for mirror %s", rawURL, name)
+		}
+		mirror.URLs = append(mirror.URLs, strings.TrimSuffix(rawURL, "/"))
+	}
+
+	return mirror, nil
+}
+
+var (
+	manifestField = regexp.MustCompile(`^--+\s*@([\w-]+)\s*(.*?)\s*$`)
+	requireCall   = regexp.MustCompile(`\brequire\s*\(?\s*["']([\w.-]+)["']`)
+)
+
+// ManifestError is returned for a manifest line that cannot be parsed.
+type ManifestError struct {
+	Line int
+	Err  error
+}
+
+func (e *ManifestError) Error() string {
+	return fmt.Sprintf("manifest line %d: %v", e.Line, e.Err)
+}
+
+func (e *ManifestError) Unwrap() error {
+	return e.Err
+}
+
+// ParseManifest reads the manifest from the leading comment block of a script.
+// Scripts without a header yield an empty manifest.
+func ParseManifest(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+	scanner := bufio.NewScanner(r)
+	inHeader := true
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+
+		for _, match := range requireCall.FindAllStringSubmatch(line, -1) {
+			if !lo.Contains(m.Requires, match[1]) {
+				m.Requires = append(m.Requires, match[1])
+			}
+		}
+
+		if !inHeader {
+			continue
+		}
+
+		if line != "" && !strings.HasPrefix(line, "--") {
+			inHeader = false
+			continue
+		}
+
+		match := manifestField.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		if err := m.set(strings.ToLower(match[1]), match[2]); err != nil {
+			return nil, &ManifestError{Line: lineNo, Err: err}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ReadManifest parses the manifest of the script at path.
+func ReadManifest(path string) (*Manifest, error) {
+	file, err := filesystem.API().Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseManifest(file)
+}
+
+func (m *Manifest) set(field, value string) error {
+	switch field {
+	case "name":
+		m.Name = value
+	case "url":
+		m.URL = value
+	case "author":
+		m.Author = value
+	case "license":
+		m.License = value
+	case "version":
+		if _, err := version.Compare(value, value); err != nil {
+			return fmt.Errorf("invalid version %q, expected major.minor.patch", value)
+		}
+		m.Version = value
+	case "lang", "language":
+		m.Language = value
+	case "min-anisan-version", "min-anisan", "min_anisan_version":
+		if _, err := version.Compare(value, value); err != nil {
+			return fmt.Errorf("invalid minimum anisan version %q, expected major.minor.patch", value)
+		}
+		m.MinVersion = value
+	case "capabilities", "capability":
+		for _, c := range splitList(value) {
+			c = strings.ToLower(c)
+			if !lo.Contains(m.Capabilities, c) {
+				m.Capabilities = append(m.Capabilities, c)
+			}
+		}
+	case "option":
+		option, err := parseOption(value)
+		if err != nil {
+			return err
+		}
+		if lo.ContainsBy(m.Options, func(o Option) bool { return o.Name == option.Name }) {
+			return fmt.Errorf("option %q is declared twice", option.Name)
+		}
+		m.Options = append(m.Options, option)
+	case "mirror", "mirrors":
+		mirror, err := parseMirror(value)
+		if err != nil {
+			return err
+		}
+		// Several lines with the same name add to its urls.
+		if _, i, ok := lo.FindIndexOf(m.Mirrors, func(m Mirror) bool { return m.Name == mirror.Name }); ok {
+			m.Mirrors[i].URLs = lo.Uniq(append(m.Mirrors[i].URLs, mirror.URLs...))
+		} else {
+			m.Mirrors = append(m.Mirrors, mirror)
+		}
+	case "permission", "permissions":
+		kind, values, _ := strings.Cut(value, " ")
+		if err := m.Permissions.add(strings.ToLower(kind), splitList(values)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitList splits a comma or space separated manifest value.
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// Has reports whether the script declares the capability.
+func (m *Manifest) Has(capability string) bool {
+	return lo.Contains(m.Capabilities, capability)
+}
+
+// UsesHeadless reports whether the script needs a headless browser,
+// either declared as a capability or implied by requiring the headless module.
+func (m *Manifest) UsesHeadless() bool {
+	return m.Has(CapabilityHeadless) || lo.Contains(m.Requires, "headless")
+}
+
+// IncompatibleError is returned for scripts that require a newer anisan.
+type IncompatibleError struct {
+	Script     string
+	MinVersion string
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("source %s requires anisan %s or newer, but this is %s; please update anisan", e.Script, e.MinVersion, constant.Version)
+}
+
+// CheckCompatibility ensures the running anisan satisfies the script's minimum version.
+func (m *Manifest) CheckCompatibility(script string) error {
+	if m.MinVersion == "" {
+		return nil
+	}
+
+	cmp, err := version.Compare(constant.Version, m.MinVersion)
+	if err != nil {
+		return err
+	}
+
+	if cmp < 0 {
+		return &IncompatibleError{Script: script, MinVersion: m.MinVersion}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package custom
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseManifest(t *testing.T) {
+	Convey("ParseManifest", t, func() {
+		Convey("Should read every header field", func() {
+			script := `-----------------------------
+-- @name    example
+-- @url     https://example.com
+-- @author  someone
+-- @license MIT
+-- @version 1.2.3
+-- @lang    ja
+-- @min-anisan-version 0.1.0
+-- @capabilities search, episodes videos
+-----------------------------
+
+local json = require("json")
+local html = require 'html'
+
+-- @name not-a-header-field
+function SearchAnimes(query) return {} end
+`
+			m, err := ParseManifest(strings.NewReader(script))
+			So(err, ShouldBeNil)
+			So(m.Name, ShouldEqual, "example")
+			So(m.URL, ShouldEqual, "https://example.com")
+			So(m.Author, ShouldEqual, "someone")
+			So(m.License, ShouldEqual, "MIT")
+			So(m.Version, ShouldEqual, "1.2.3")
+			So(m.Language, ShouldEqual, "ja")
+			So(m.MinVersion, ShouldEqual, "0.1.0")
+			So(m.Capabilities, ShouldResemble, []string{"search", "episodes", "videos"})
+			So(m.Requires, ShouldResemble, []string{"json", "html"})
+			So(m.UsesHeadless(), ShouldBeFalse)
+		})
+
+		Convey("Should detect headless usage from capabilities or require()", func() {
+			m, err := ParseManifest(strings.NewReader("-- @capabilities headless\n"))
+			So(err, ShouldBeNil)
+			So(m.UsesHeadless(), ShouldBeTrue)
+
+			m, err = ParseManifest(strings.NewReader(`local headless = require("headless")`))
+			So(err, ShouldBeNil)
+			So(m.UsesHeadless(), ShouldBeTrue)
+		})
+
+		Convey("Should read options", func() {
+			m, err := ParseManifest(strings.NewReader("-- @option server default Streaming server to prefer\n-- @option retries 3\n-- @option dub false\n-- @option token \"\"\n"))
+			So(err, ShouldBeNil)
+			So(m.Options, ShouldResemble, []Option{
+				{Name: "server", Default: "default", Description: "Streaming server to prefer"},
+				{Name: "retries", Default: 3},
+				{Name: "dub", Default: false},
+				{Name: "token", Default: ""},
+			})
+
+			_, err = ParseManifest(strings.NewReader("-- @option server\n"))
+			So(err, ShouldNotBeNil)
+
+			_, err = ParseManifest(strings.NewReader("-- @option Server.Name x\n"))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Should read mirrors", func() {
+			m, err := ParseManifest(strings.NewReader("-- @mirror api https://api.example.com/api, https://api.example.net/api\n-- @mirror site https://example.com/\n-- @mirror api https://api.example.org/api\n"))
+			So(err, ShouldBeNil)
+			So(m.Mirrors, ShouldResemble, []Mirror{
+				{Name: "api", URLs: []string{"https://api.example.com/api", "https://api.example.net/api", "https://api.example.org/api"}},
+				{Name: "site", URLs: []string{"https://example.com"}},
+			})
+			So(m.RequestedPermissions().Hosts, ShouldResemble, []string{"api.example.com", "api.example.net", "api.example.org", "example.com"})
+
+			_, err = ParseManifest(strings.NewReader("-- @mirror api\n"))
+			So(err, ShouldNotBeNil)
+
+			_, err = ParseManifest(strings.NewReader("-- @mirror api example.com\n"))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Should read permissions", func() {
+			m, err := ParseManifest(strings.NewReader("-- @url https://example.com/anime\n-- @permission hosts api.example.com, *.cdn.net\n-- @permissions modules headless\n"))
+			So(err, ShouldBeNil)
+			So(m.Permissions.Hosts, ShouldResemble, []string{"api.example.com", "*.cdn.net"})
+			So(m.Permissions.Modules, ShouldResemble, []string{"headless"})
+			So(m.RequestedPermissions().Hosts, ShouldResemble, []string{"api.example.com", "*.cdn.net", "example.com"})
+
+			_, err = ParseManifest(strings.NewReader("-- @permission files /etc\n"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "line 1")
+		})
+
+		Convey("Should reject malformed versions with the line number", func() {
+			_, err := ParseManifest(strings.NewReader("-- @name x\n-- @version latest\n"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "line 2")
+		})
+
+		Convey("Should refuse scripts requiring a newer anisan", func() {
+			m, err := ParseManifest(strings.NewReader("-- @min-anisan-version 999.0.0\n"))
+			So(err, ShouldBeNil)
+
+			err = m.CheckCompatibility("example")
+			var incompatible *IncompatibleError
+			So(errors.As(err, &incompatible), ShouldBeTrue)
+			So(err.Error(), ShouldContainSubstring, "999.0.0")
+		})
+
+		Convey("Should accept scripts without a minimum version", func() {
+			m, err := ParseManifest(strings.NewReader("function SearchAnimes() end\n"))
+			So(err, ShouldBeNil)
+			So(m.CheckCompatibility("example"), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,175 @@
+package custom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anisan-cli/anisan/config"
+	"github.com/anisan-cli/anisan/log"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+)
+
+const (
+	// mirrorProbeTimeout bounds how long the urls of a mirror may take to answer a probe.
+	mirrorProbeTimeout = 10 * time.Second
+	// mirrorTTL is how long the working url of a mirror is remembered before it is probed again.
+	mirrorTTL = 24 * time.Hour
+)
+
+// mirrorOption returns the name of the source option overriding the mirror.
+func mirrorOption(mirror string) string {
+	return "mirrors." + mirror
+}
+
+// mirrorStoreKey returns the key under which the working url of the mirror is remembered in the store of the source.
+func mirrorStoreKey(mirror string) string {
+	return "mirror." + mirror
+}
+
+// ConfiguredMirror returns the url the user set for the mirror of the source with
+// "anisan config set sources.<source>.mirrors.<mirror>", if any.
+func ConfiguredMirror(source, mirror string) (string, bool) {
+	value := strings.TrimSuffix(viper.GetString(config.SourceOptionKey(source, mirrorOption(mirror))), "/")
+	return value, value != ""
+}
+
+// KnownMirror returns the url the source uses for the mirror without probing it:
+// the one set by the user, otherwise the one that worked last time.
+func KnownMirror(source string, mirror Mirror) (string, bool) {
+	return knownMirror(StoreOf(source), source, mirror)
+}
+
+// knownMirror is KnownMirror with the store the working url is remembered in.
+func knownMirror(store Store, source string, mirror Mirror) (string, bool) {
+	if configured, ok := ConfiguredMirror(source, mirror.Name); ok {
+		return configured, true
+	}
+
+	value, ok := store.Get(mirrorStoreKey(mirror.Name))
+	if !ok {
+		return "", false
+	}
+
+	// An update of the script may have dropped the url.
+	remembered, ok := value.(string)
+	if !ok || !lo.Contains(mirror.URLs, remembered) {
+		return "", false
+	}
+
+	return remembered, true
+}
+
+// mirrorLocks serializes the probes of each mirror, so that pooled VMs do not probe it at the same time.
+var mirrorLocks sync.Map
+
+func mirrorLock(source, mirror string) *sync.Mutex {
+	lock, _ := mirrorLocks.LoadOrStore(source+"/"+mirror, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// mirrorResolver finds the working urls of the mirrors of a source.
+type mirrorResolver struct {
+	source string
+	client tlsClient
+}
+
+// resolve returns the url to use for the mirror, probing its urls when none is known.
+func (r mirrorResolver) resolve(ctx context.Context, mirror Mirror) string {
+	lock := mirrorLock(r.source, mirror.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if known, ok := knownMirror(r.client.storeOf(r.source), r.source, mirror); ok {
+		return known
+	}
+
+	return r.switchMirror(ctx, mirror, "")
+}
+
+// fail reports that failing, a url of the mirror, stopped working and returns the url to use instead.
+func (r mirrorResolver) fail(ctx context.Context, mirror Mirror, failing string) string {
+	lock := mirrorLock(r.source, mirror.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if configured, ok := ConfiguredMirror(r.source, mirror.Name); ok {
+		log.Warnf("%s: mirror %s is set to %s, which is failing", r.source, mirror.Name, configured)
+		return configured
+	}
+
+	known, ok := knownMirror(r.client.storeOf(r.source), r.source, mirror)
+	if failing == "" {
+		failing = known
+	}
+
+	// Another VM already switched away from it.
+	if ok && known != failing {
+		return known
+	}
+
+	return r.switchMirror(ctx, mirror, failing)
+}
+
+// switchMirror probes the urls of the mirror but the excluded one and remembers the first that works.
+// When none does, the first url is returned so that the script reports a meaningful error.
+func (r mirrorResolver) switchMirror(ctx context.Context, mirror Mirror, exclude string) string {
+	candidates := lo.Without(mirror.URLs, exclude)
+	if len(candidates) == 0 {
+		candidates = mirror.URLs
+	}
+
+	working, ok := r.probe(ctx, candidates)
+	if ctx.Err() != nil {
+		return candidates[0]
+	}
+
+	store := r.client.storeOf(r.source)
+	if !ok {
+		log.Warnf("%s: none of the urls of mirror %s answers", r.source, mirror.Name)
+		if err := store.Delete(mirrorStoreKey(mirror.Name)); err != nil {
+			log.Warn(err)
+		}
+		return candidates[0]
+	}
+
+	log.Infof("%s: using %s for mirror %s", r.source, working, mirror.Name)
+	if err := store.Set(mirrorStoreKey(mirror.Name), working, mirrorTTL); err != nil {
+		log.Warn(err)
+	}
+
+	return working
+}
+
+// probe requests the urls at once and returns the first of them, in order, to answer without a server error.
+func (r mirrorResolver) probe(ctx context.Context, urls []string) (string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, mirrorProbeTimeout)
+	defer cancel()
+
+	answers := make([]chan bool, len(urls))
+	for i, u := range urls {
+		answers[i] = make(chan bool, 1)
+		go func(u string, answer chan<- bool) {
+			_, status, err := r.client.do(ctx, http.MethodGet, u, nil, "")
+			if err == nil && status >= http.StatusInternalServerError {
+				err = fmt.Errorf("status %d", status)
+			}
+			if err != nil {
+				log.Debugf("%s: probing %s: %v", r.source, u, err)
+			}
+			answer <- err == nil
+		}(u, answers[i])
+	}
+
+	for i, answer := range answers {
+		if <-answer {
+			return urls[i], true
+		}
+	}
+
+	return "", false
+}
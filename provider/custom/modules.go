@@ -0,0 +1,193 @@
+package custom
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/internal/scraper"
+	"github.com/anisan-cli/anisan/util"
+	"github.com/anisan-cli/anisan/where"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/ast"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// PackageScript is the script a source packaged as a directory starts from.
+const PackageScript = "init.lua"
+
+// IsPackage reports whether path is a source packaged as a directory,
+// e.g. sources/<name>/init.lua along with the modules it requires.
+func IsPackage(path string) bool {
+	info, err := filesystem.API().Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// ScriptPath returns the script to run for the source at path:
+// the file itself, or the init.lua of a package.
+func ScriptPath(path string) string {
+	if IsPackage(path) {
+		return filepath.Join(path, PackageScript)
+	}
+	return path
+}
+
+// SourceName returns the name of the source at path, which for a package is the name of its directory.
+func SourceName(path string) string {
+	if IsPackage(path) {
+		return filepath.Base(filepath.Clean(path))
+	}
+	return util.FileStem(path)
+}
+
+// IsModule reports whether the script at path is a module shared by sources, such as common.lua,
+// rather than a source. Modules return a value from their main chunk, sources define globals.
+func IsModule(path string) bool {
+	data, err := filesystem.API().ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	chunk, err := parse.Parse(bytes.NewReader(data), path)
+	if err != nil || len(chunk) == 0 {
+		return false
+	}
+
+	ret, ok := chunk[len(chunk)-1].(*ast.ReturnStmt)
+	return ok && len(ret.Exprs) > 0
+}
+
+// Requires returns the names of the modules the script requires with a string literal,
+// e.g. local util = require("util"), in the order they first appear.
+func Requires(data []byte) []string {
+	chunk, err := parse.Parse(bytes.NewReader(data), "")
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	walkStmts(chunk, 0, func(stmt ast.Stmt, _ int) {
+		var exprs []ast.Expr
+		switch stmt := stmt.(type) {
+		case *ast.LocalAssignStmt:
+			exprs = stmt.Exprs
+		case *ast.AssignStmt:
+			exprs = stmt.Rhs
+		case *ast.FuncCallStmt:
+			exprs = []ast.Expr{stmt.Expr}
+		}
+
+		for _, expr := range exprs {
+			call, ok := expr.(*ast.FuncCallExpr)
+			if !ok || len(call.Args) == 0 {
+				continue
+			}
+
+			fn, isIdent := call.Func.(*ast.IdentExpr)
+			name, isString := call.Args[0].(*ast.StringExpr)
+			if isIdent && isString && fn.Value == "require" && !seen[name.Value] {
+				seen[name.Value] = true
+				names = append(names, name.Value)
+			}
+		}
+	})
+
+	return names
+}
+
+// moduleRoots are the directories the modules of the source at script are looked up in:
+// its package, if it is one, then the shared modules of the sources directory.
+func moduleRoots(script string) []string {
+	roots := []string{where.Sources()}
+	if filepath.Base(script) == PackageScript {
+		roots = append([]string{filepath.Dir(script)}, roots...)
+	}
+	return roots
+}
+
+// registerModules points package.path at the roots and replaces the Lua file loader of require
+// with one that runs modules from the bytecode cache. When confined, modules outside
+// of the roots are not loaded whatever the script sets package.path to.
+func registerModules(L *lua.LState, roots []string, confined bool) {
+	var patterns []string
+	for _, root := range roots {
+		patterns = append(patterns, filepath.Join(root, "?.lua"), filepath.Join(root, "?", PackageScript))
+	}
+
+	pkg, ok := L.GetGlobal(lua.LoadLibName).(*lua.LTable)
+	if !ok {
+		return
+	}
+
+	path := strings.Join(patterns, ";")
+	if current := lua.LVAsString(pkg.RawGetString("path")); !confined && current != "" {
+		path += ";" + current
+	}
+	pkg.RawSetString("path", lua.LString(path))
+
+	loaders, ok := L.GetField(L.Get(lua.RegistryIndex), "_LOADERS").(*lua.LTable)
+	if !ok {
+		return
+	}
+
+	loaders.RawSetInt(2, L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+
+		file, tried := findModule(L, name, roots, confined)
+		if file == "" {
+			L.Push(lua.LString(tried))
+			return 1
+		}
+
+		proto, err := scraper.Compile(file)
+		if err != nil {
+			L.RaiseError("error loading module %q from %s:\n\t%v", name, file, err)
+		}
+
+		L.Push(L.NewFunctionFromProto(proto))
+		return 1
+	}))
+}
+
+// findModule looks the module up along package.path, returning the file it is in,
+// or the files tried otherwise.
+func findModule(L *lua.LState, name string, roots []string, confined bool) (string, string) {
+	pkg, _ := L.GetGlobal(lua.LoadLibName).(*lua.LTable)
+	path := ""
+	if pkg != nil {
+		path = lua.LVAsString(pkg.RawGetString("path"))
+	}
+
+	var tried strings.Builder
+	relative := strings.ReplaceAll(name, ".", string(filepath.Separator))
+	for _, pattern := range strings.Split(path, ";") {
+		if pattern == "" {
+			continue
+		}
+
+		file := filepath.Clean(strings.ReplaceAll(pattern, "?", relative))
+		if confined && !withinAny(file, roots) {
+			continue
+		}
+
+		tried.WriteString("\n\tno file '" + file + "'")
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file, ""
+		}
+	}
+
+	return "", tried.String()
+}
+
+func withinAny(file string, roots []string) bool {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, file)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package custom
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/internal/scraper"
+	"github.com/anisan-cli/anisan/where"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPackages(t *testing.T) {
+	Convey("Given a source packaged with its modules", t, func() {
+		filesystem.SetOsFs()
+		t.Setenv(where.EnvConfigPath, t.TempDir())
+
+		write := func(path, content string) string {
+			path = filepath.Join(where.Sources(), path)
+			So(os.MkdirAll(filepath.Dir(path), os.ModePerm), ShouldBeNil)
+			So(os.WriteFile(path, []byte(content), 0o644), ShouldBeNil)
+			return path
+		}
+
+		write("common.lua", `local common = {}
+function common.greet() return "hi" end
+return common`)
+		write("example/util.lua", `local util = {}
+function util.title(s) return s:upper() end
+return util`)
+		write("example/lib/nested.lua", `return { mark = "!" }`)
+		init := write("example/init.lua", `-- @name example
+local util = require("util")
+local common = require("common")
+local nested = require("lib.nested")
+
+function SearchAnimes(query)
+	return { { name = util.title(query) .. " " .. common.greet() .. nested.mark, url = "/a" } }
+end
+function AnimeEpisodes() return {} end
+function EpisodeVideos() return {} end`)
+		pkg := filepath.Dir(init)
+
+		Convey("It is named after its directory and runs from init.lua", func() {
+			So(IsPackage(pkg), ShouldBeTrue)
+			So(SourceName(pkg), ShouldEqual, "example")
+			So(ScriptPath(pkg), ShouldEqual, init)
+		})
+
+		Convey("Its modules and the shared ones are required", func() {
+			src, err := LoadSource(pkg)
+			So(err, ShouldBeNil)
+			So(src.Name(), ShouldEqual, "example")
+
+			animes, err := src.Search("frieren")
+			So(err, ShouldBeNil)
+			So(animes[0].Name, ShouldEqual, "FRIEREN hi!")
+		})
+
+		Convey("Modules are told apart from sources", func() {
+			So(IsModule(filepath.Join(where.Sources(), "common.lua")), ShouldBeTrue)
+			So(IsModule(init), ShouldBeFalse)
+
+			data, err := os.ReadFile(init)
+			So(err, ShouldBeNil)
+			So(Requires(data), ShouldResemble, []string{"util", "common", "lib.nested"})
+		})
+
+		Convey("Sandboxed sources cannot require files outside of the roots", func() {
+			outside := filepath.Join(t.TempDir(), "outside.lua")
+			So(os.WriteFile(outside, []byte(`return {}`), 0o644), ShouldBeNil)
+
+			script := write("escape/init.lua", `package.path = "`+filepath.Join(filepath.Dir(outside), "?.lua")+`"
+require("outside")`)
+			proto, err := scraper.Compile(script)
+			So(err, ShouldBeNil)
+
+			_, err = newState("packaged", proto, &Permissions{}, Budget{}, nil, moduleRoots(script), &Manifest{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, `module outside not found`)
+		})
+	})
+}
@@ -0,0 +1,253 @@
+package custom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/anisan-cli/anisan/constant"
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/where"
+	"github.com/samber/lo"
+)
+
+// Permissions are what a script may use beyond the sandbox defaults.
+type Permissions struct {
+	// Hosts the script may send requests to. "*.example.com" matches example.com
+	// and its subdomains, other patterns use shell globbing, "*" matches any host.
+	Hosts []string `json:"hosts,omitempty"`
+	// Modules the script may load on top of the sandboxed ones, e.g. headless or ioutil.
+	Modules []string `json:"modules,omitempty"`
+}
+
+func (p *Permissions) add(kind string, values []string) error {
+	var target *[]string
+	switch kind {
+	case "host", "hosts", "network":
+		target = &p.Hosts
+	case "module", "modules":
+		target = &p.Modules
+	default:
+		return fmt.Errorf("unknown permission %q, expected hosts or modules", kind)
+	}
+
+	for _, v := range values {
+		v = strings.ToLower(v)
+		if !lo.Contains(*target, v) {
+			*target = append(*target, v)
+		}
+	}
+
+	return nil
+}
+
+// IsEmpty reports whether nothing is requested.
+func (p Permissions) IsEmpty() bool {
+	return len(p.Hosts) == 0 && len(p.Modules) == 0
+}
+
+// Covers reports whether p grants everything other requests.
+func (p Permissions) Covers(other Permissions) bool {
+	return lo.Every(p.Hosts, other.Hosts) && lo.Every(p.Modules, other.Modules)
+}
+
+// AllowsHost reports whether requests to host are permitted.
+func (p Permissions) AllowsHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return lo.SomeBy(p.Hosts, func(pattern string) bool { return hostAllowed(host, pattern) })
+}
+
+// AllowsModule reports whether the module may be loaded.
+func (p Permissions) AllowsModule(module string) bool {
+	return lo.Contains(p.Modules, module)
+}
+
+// AllowsURL reports whether requests to the URL are permitted.
+func (p Permissions) AllowsURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if !p.AllowsHost(u.Hostname()) {
+		return fmt.Errorf("host %s is not permitted; declare it with \"-- @permission hosts %s\"", u.Hostname(), u.Hostname())
+	}
+
+	return nil
+}
+
+func (p Permissions) String() string {
+	var parts []string
+	if len(p.Hosts) > 0 {
+		parts = append(parts, "hosts: "+strings.Join(p.Hosts, ", "))
+	}
+	if len(p.Modules) > 0 {
+		parts = append(parts, "modules: "+strings.Join(p.Modules, ", "))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// hostsOf returns the hosts of the URLs that parse.
+func hostsOf(urls []string) []string {
+	var hosts []string
+	for _, rawURL := range urls {
+		if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	return hosts
+}
+
+func hostAllowed(host, pattern string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "*.") && !strings.ContainsAny(pattern[2:], "*?["):
+		domain := pattern[2:]
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+
+	ok, _ := path.Match(pattern, host)
+	return ok
+}
+
+// RequestedPermissions returns the permissions declared by the manifest,
+// including access to the host of its @url and of its mirrors.
+func (m *Manifest) RequestedPermissions() Permissions {
+	requested := Permissions{
+		Hosts:   append([]string{}, m.Permissions.Hosts...),
+		Modules: append([]string{}, m.Permissions.Modules...),
+	}
+
+	hosts := hostsOf([]string{m.URL})
+	for _, mirror := range m.Mirrors {
+		hosts = append(hosts, hostsOf(mirror.URLs)...)
+	}
+
+	// Hosts already matched by a declared pattern are left out.
+	_ = requested.add("hosts", lo.Reject(hosts, func(host string, _ int) bool { return requested.AllowsHost(host) }))
+
+	return requested
+}
+
+// PermissionPrompt asks the user whether the script may use the requested permissions.
+// It is nil while anisan cannot prompt, e.g. when the TUI owns the terminal,
+// in which case sources without a grant fail to load with a *PermissionError.
+var PermissionPrompt func(script string, requested Permissions) bool
+
+// PermissionError is returned for sources whose permissions have not been granted yet.
+type PermissionError struct {
+	Script    string
+	Requested Permissions
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("source %s needs permissions that were not granted (%s); run \"%s sources grant %s\" to review them",
+		e.Script, e.Requested, constant.Anisan, e.Script)
+}
+
+// grants is the registry of permissions the user granted, by script name.
+var grants = struct {
+	sync.Mutex
+	loaded map[string]Permissions
+}{}
+
+func loadGrants() (map[string]Permissions, error) {
+	if grants.loaded != nil {
+		return grants.loaded, nil
+	}
+
+	loaded := make(map[string]Permissions)
+	data, err := filesystem.API().ReadFile(where.Grants())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return nil, fmt.Errorf("read %s: %w", where.Grants(), err)
+		}
+	}
+
+	grants.loaded = loaded
+	return loaded, nil
+}
+
+// Granted returns the permissions granted to the script.
+func Granted(script string) (Permissions, error) {
+	grants.Lock()
+	defer grants.Unlock()
+
+	loaded, err := loadGrants()
+	if err != nil {
+		return Permissions{}, err
+	}
+
+	return loaded[script], nil
+}
+
+// Grant records that the script may use the permissions.
+func Grant(script string, permissions Permissions) error {
+	grants.Lock()
+	defer grants.Unlock()
+
+	loaded, err := loadGrants()
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(permissions.Hosts)
+	sort.Strings(permissions.Modules)
+	loaded[script] = permissions
+
+	data, err := json.MarshalIndent(loaded, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return filesystem.API().WriteFile(where.Grants(), data, 0o644)
+}
+
+// CheckPermissions ensures the permissions requested by the script were granted,
+// asking with PermissionPrompt the first time.
+func CheckPermissions(script string, manifest *Manifest) error {
+	requested := manifest.RequestedPermissions()
+	if requested.IsEmpty() {
+		return nil
+	}
+
+	granted, err := Granted(script)
+	if err != nil {
+		return err
+	}
+
+	if granted.Covers(requested) {
+		return nil
+	}
+
+	if PermissionPrompt == nil || !PermissionPrompt(script, requested) {
+		return &PermissionError{Script: script, Requested: requested}
+	}
+
+	return Grant(script, requested)
+}
+
+// ReviewPermissions checks the permissions of the source at path, asking with PermissionPrompt
+// for those that were not granted yet. The path is either a script or a package directory.
+func ReviewPermissions(path string) error {
+	manifest, err := ReadManifest(ScriptPath(path))
+	if err != nil {
+		return err
+	}
+
+	return CheckPermissions(SourceName(path), manifest)
+}
@@ -0,0 +1,118 @@
+package custom
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// errPoolClosed is returned when a call is made on a source that has been closed.
+var errPoolClosed = errors.New("source is closed")
+
+// statePool hands out Lua VMs built from the same compiled script.
+// An LState is not safe for concurrent use, so every in-flight call owns one VM;
+// up to size VMs are built lazily and recycled between calls.
+type statePool struct {
+	build func() (*lua.LState, error)
+	idle  chan *lua.LState
+	slots chan struct{} // A token is held for every live VM, idle or busy
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func newStatePool(size int, build func() (*lua.LState, error)) *statePool {
+	if size < 1 {
+		size = 1
+	}
+
+	return &statePool{
+		build: build,
+		idle:  make(chan *lua.LState, size),
+		slots: make(chan struct{}, size),
+	}
+}
+
+// put seeds the pool with an already built VM, e.g. the one used to validate the script.
+func (p *statePool) put(L *lua.LState) {
+	p.slots <- struct{}{}
+	p.release(L)
+}
+
+// acquire returns an idle VM, builds a new one while under the size limit,
+// or waits for one to be released until ctx is done.
+func (p *statePool) acquire(ctx context.Context) (*lua.LState, error) {
+	if p.isClosed() {
+		return nil, errPoolClosed
+	}
+
+	// Prefer recycling a warm VM over building another one.
+	select {
+	case L := <-p.idle:
+		return L, nil
+	default:
+	}
+
+	select {
+	case L := <-p.idle:
+		return L, nil
+	case p.slots <- struct{}{}:
+		L, err := p.build()
+		if err != nil {
+			<-p.slots
+			return nil, err
+		}
+		return L, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// release returns a healthy VM to the pool.
+func (p *statePool) release(L *lua.LState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		L.Close()
+		<-p.slots
+		return
+	}
+
+	p.idle <- L
+}
+
+// discard drops a VM whose stack can no longer be trusted, e.g. after a call was aborted mid-instruction.
+func (p *statePool) discard(L *lua.LState) {
+	L.Close()
+	<-p.slots
+}
+
+func (p *statePool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// Close frees every idle VM. VMs still executing are freed as soon as they are released.
+func (p *statePool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.closed = true
+
+	for {
+		select {
+		case L := <-p.idle:
+			L.Close()
+			<-p.slots
+		default:
+			return
+		}
+	}
+}
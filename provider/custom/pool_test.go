@@ -0,0 +1,87 @@
+package custom
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestStatePool(t *testing.T) {
+	Convey("Given a pool of two VMs", t, func() {
+		var built atomic.Int32
+		pool := newStatePool(2, func() (*lua.LState, error) {
+			built.Add(1)
+			L := lua.NewState()
+			err := L.DoString(`function Echo(v) return v end`)
+			return L, err
+		})
+		defer pool.Close()
+
+		Convey("When two VMs are in use", func() {
+			a, err := pool.acquire(context.Background())
+			So(err, ShouldBeNil)
+			b, err := pool.acquire(context.Background())
+			So(err, ShouldBeNil)
+			So(a, ShouldNotEqual, b)
+
+			Convey("Then a third caller waits until its context expires", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				defer cancel()
+
+				_, err := pool.acquire(ctx)
+				So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			})
+
+			Convey("Then a released VM is handed out again instead of building a new one", func() {
+				pool.release(a)
+
+				c, err := pool.acquire(context.Background())
+				So(err, ShouldBeNil)
+				So(c, ShouldEqual, a)
+				So(built.Load(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When calls run concurrently on a source", func() {
+			src, _ := newLuaSource("test", pool)
+
+			var wg sync.WaitGroup
+			results := make([]string, 8)
+			for i := range results {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					val, err := src.call(context.Background(), "Echo", lua.LTString, func(*lua.LState) []lua.LValue {
+						return []lua.LValue{lua.LString(string(rune('a' + i)))}
+					})
+					if err == nil {
+						results[i] = val.String()
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			Convey("Then every call gets its own result and no more VMs than the limit are built", func() {
+				for i, r := range results {
+					So(r, ShouldEqual, string(rune('a'+i)))
+				}
+				So(built.Load(), ShouldBeLessThanOrEqualTo, 2)
+			})
+		})
+
+		Convey("When the pool is closed", func() {
+			pool.Close()
+
+			Convey("Then new calls are refused", func() {
+				_, err := pool.acquire(context.Background())
+				So(err, ShouldEqual, errPoolClosed)
+			})
+		})
+	})
+}
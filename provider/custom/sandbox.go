@@ -0,0 +1,287 @@
+package custom
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/metafates/mangal-lua-libs/base64"
+	"github.com/metafates/mangal-lua-libs/crypto"
+	"github.com/metafates/mangal-lua-libs/filepath"
+	"github.com/metafates/mangal-lua-libs/goos"
+	"github.com/metafates/mangal-lua-libs/headless"
+	"github.com/metafates/mangal-lua-libs/html"
+	luahttp "github.com/metafates/mangal-lua-libs/http"
+	httpclient "github.com/metafates/mangal-lua-libs/http/client"
+	httputil "github.com/metafates/mangal-lua-libs/http/util"
+	"github.com/metafates/mangal-lua-libs/humanize"
+	"github.com/metafates/mangal-lua-libs/inspect"
+	"github.com/metafates/mangal-lua-libs/ioutil"
+	"github.com/metafates/mangal-lua-libs/json"
+	"github.com/metafates/mangal-lua-libs/log"
+	"github.com/metafates/mangal-lua-libs/regexp"
+	"github.com/metafates/mangal-lua-libs/runtime"
+	"github.com/metafates/mangal-lua-libs/shellescape"
+	"github.com/metafates/mangal-lua-libs/stats"
+	"github.com/metafates/mangal-lua-libs/storage"
+	"github.com/metafates/mangal-lua-libs/strings"
+	"github.com/metafates/mangal-lua-libs/template"
+	"github.com/metafates/mangal-lua-libs/time"
+	"github.com/metafates/mangal-lua-libs/xmlpath"
+	"github.com/metafates/mangal-lua-libs/yaml"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// sandboxModules are the modules every sandboxed script may require.
+// They cannot touch the filesystem or the host, and the network ones honour the allowed hosts.
+// template is not among them: it reads files, so it comes with the io permission.
+var sandboxModules = map[string]func(*lua.LState){
+	"base64":      base64.Preload,
+	"crypto":      crypto.Preload,
+	"html":        html.Preload,
+	"humanize":    humanize.Preload,
+	"inspect":     inspect.Preload,
+	"json":        json.Preload,
+	"regexp":      regexp.Preload,
+	"shellescape": shellescape.Preload,
+	"stats":       stats.Preload,
+	"strings":     strings.Preload,
+	"time":        time.Preload,
+	"xmlpath":     xmlpath.Preload,
+	"yaml":        yaml.Preload,
+	"http_util":   httputil.Preload,
+}
+
+// restrictedModules must be granted with "-- @permission modules <name>".
+// "os" and "io" stand for the full Lua standard libraries of the same name.
+var restrictedModules = map[string]func(*lua.LState){
+	"filepath": filepath.Preload,
+	"goos":     goos.Preload,
+	"headless": headless.Preload,
+	"ioutil":   ioutil.Preload,
+	"log":      log.Preload,
+	"runtime":  runtime.Preload,
+	"storage":  storage.Preload,
+	"os":       nil,
+	"io":       nil,
+}
+
+// sandboxOS lists the functions of the os library left in the sandbox.
+var sandboxOS = []string{"clock", "date", "difftime", "time"}
+
+// newSandboxState returns a VM with the safe parts of the standard library,
+// the sandboxed modules and whatever else the permissions grant.
+// Requests made with http_tls go through the cassette, if one is given.
+func newSandboxState(permissions Permissions, options lua.Options, cassette *Cassette) *lua.LState {
+	options.SkipOpenLibs = true
+	L := lua.NewState(options)
+
+	libs := []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.LoadLibName, lua.OpenPackage},
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+		{lua.CoroutineLibName, lua.OpenCoroutine},
+		{lua.OsLibName, lua.OpenOs},
+	}
+	if permissions.AllowsModule("io") {
+		libs = append(libs, struct {
+			name string
+			open lua.LGFunction
+		}{lua.IoLibName, lua.OpenIo})
+	}
+
+	for _, lib := range libs {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	// No code from files outside of the script itself; registerModules points require at its modules.
+	L.SetGlobal("dofile", lua.LNil)
+	L.SetGlobal("loadfile", lua.LNil)
+	if pkg, ok := L.GetGlobal(lua.LoadLibName).(*lua.LTable); ok {
+		pkg.RawSetString("path", lua.LString(""))
+		pkg.RawSetString("cpath", lua.LString(""))
+	}
+
+	if !permissions.AllowsModule("os") {
+		full := L.GetGlobal(lua.OsLibName).(*lua.LTable)
+		restricted := L.NewTable()
+		for _, name := range sandboxOS {
+			restricted.RawSetString(name, full.RawGetString(name))
+		}
+		L.SetGlobal(lua.OsLibName, restricted)
+	}
+
+	for _, preload := range sandboxModules {
+		preload(L)
+	}
+
+	for name, preload := range restrictedModules {
+		switch {
+		case preload == nil:
+		case permissions.AllowsModule(name):
+			preload(L)
+		default:
+			L.PreloadModule(name, notPermitted(name))
+		}
+	}
+
+	allow := func(rawURL string) error { return permissions.AllowsURL(rawURL) }
+	files := permissions.AllowsModule("io")
+
+	// template reads any file given to render_file or named by a mustache partial
+	if files {
+		template.Preload(L)
+	} else {
+		L.PreloadModule("template", func(L *lua.LState) int {
+			L.RaiseError("module template reads local files and is not permitted; declare \"-- @permission modules io\"")
+			return 0
+		})
+	}
+
+	L.PreloadModule("http", guardedHTTP(luahttp.Loader, allow, files, cassette))
+	L.PreloadModule("http_client", guardedHTTP(httpclient.Loader, allow, files, cassette))
+	registerTLSClient(L, tlsClient{allow: allow, cassette: cassette})
+
+	return L
+}
+
+// notPermitted is the loader of a module the script has no permission for.
+func notPermitted(module string) lua.LGFunction {
+	return func(L *lua.LState) int {
+		L.RaiseError("module %s is not permitted; declare it with \"-- @permission modules %s\"", module, module)
+		return 0
+	}
+}
+
+// guardedHTTP wraps the loader of an http module so that clients only reach allowed URLs.
+// Unless files is set, file_request, which uploads local files, is refused.
+// With a cassette, requests are recorded to it or replayed from it like those of http_tls.
+func guardedHTTP(loader lua.LGFunction, allow urlPolicy, files bool, cassette *Cassette) lua.LGFunction {
+	return func(L *lua.LState) int {
+		n := loader(L)
+
+		if mod, ok := L.Get(-1).(*lua.LTable); ok && !files {
+			mod.RawSetString("file_request", L.NewFunction(func(L *lua.LState) int {
+				L.RaiseError("file_request reads local files and is not permitted; declare \"-- @permission modules io\"")
+				return 0
+			}))
+		}
+
+		mt, ok := L.GetTypeMetatable("http_client_ud").(*lua.LTable)
+		if !ok {
+			return n
+		}
+		index, ok := mt.RawGetString("__index").(*lua.LTable)
+		if !ok {
+			return n
+		}
+
+		index.RawSetString("do_request", L.NewFunction(func(L *lua.LState) int {
+			client, _ := L.CheckUserData(1).Value.(*httpclient.LuaClient)
+			field := requestField(L.CheckUserData(2))
+			if client == nil || !field.IsValid() {
+				// Never reach the network while replaying
+				if cassette != nil {
+					L.Push(lua.LNil)
+					L.Push(lua.LString("request cannot go through the cassette"))
+					return 2
+				}
+				return httpclient.DoRequest(L)
+			}
+
+			// Bind the request to the call, so it is aborted with it.
+			req := field.Interface().(*http.Request).WithContext(luaContext(L))
+			field.Set(reflect.ValueOf(req))
+
+			if err := allow(req.URL.String()); err != nil {
+				L.Push(lua.LNil)
+				L.Push(lua.LString(err.Error()))
+				return 2
+			}
+
+			client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				return allow(req.URL.String())
+			}
+
+			if cassette != nil {
+				return doRecorded(L, cassette, client, req)
+			}
+			return httpclient.DoRequest(L)
+		}))
+
+		return n
+	}
+}
+
+// doRecorded is do_request going through the cassette. Replayed responses have no headers,
+// as cassettes do not keep them.
+func doRecorded(L *lua.LState, cassette *Cassette, client *httpclient.LuaClient, req *http.Request) int {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	headers := L.NewTable()
+	response, status, err := cassette.do(req.Context(), req.Method, req.URL.String(), string(body), func() (string, int, error) {
+		res, err := client.DoRequest(req)
+		if err != nil {
+			return "", 0, err
+		}
+		defer res.Body.Close()
+
+		for k, v := range res.Header {
+			if len(v) > 0 {
+				headers.RawSetString(k, lua.LString(v[0]))
+			}
+		}
+
+		data, err := io.ReadAll(res.Body)
+		return string(data), res.StatusCode, err
+	})
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result := L.NewTable()
+	L.SetField(result, "code", lua.LNumber(status))
+	L.SetField(result, "body", lua.LString(response))
+	L.SetField(result, "headers", headers)
+	L.Push(result)
+	return 1
+}
+
+// requestField returns the settable request wrapped by an http_request_ud value,
+// or the zero value if ud holds something else.
+func requestField(ud *lua.LUserData) reflect.Value {
+	v := reflect.ValueOf(ud.Value)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+
+	field := v.Elem().FieldByName("Request")
+	if !field.IsValid() || !field.CanSet() || field.Type() != reflect.TypeOf((*http.Request)(nil)) || field.IsNil() {
+		return reflect.Value{}
+	}
+
+	return field
+}
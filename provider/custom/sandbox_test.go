@@ -0,0 +1,227 @@
+package custom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/key"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestSandbox(t *testing.T) {
+	Convey("Given a sandboxed VM", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+
+		L := newSandboxState(Permissions{Hosts: []string{"*.example.com"}}, lua.Options{}, nil)
+		defer L.Close()
+
+		Convey("Dangerous standard functions are gone", func() {
+			So(L.DoString(`assert(io == nil)
+				assert(dofile == nil and loadfile == nil)
+				assert(os.execute == nil and os.remove == nil and os.getenv == nil)
+				assert(type(os.time()) == "number")`), ShouldBeNil)
+		})
+
+		Convey("Safe modules load, restricted ones need a permission", func() {
+			So(L.DoString(`local json = require("json"); assert(json.encode({1}) == "[1]")`), ShouldBeNil)
+
+			err := L.DoString(`require("ioutil")`)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "@permission modules ioutil")
+		})
+
+		Convey("Requests to undeclared hosts are refused", func() {
+			L.SetGlobal("target", lua.LString(server.URL))
+			err := L.DoString(`http_tls.get(target)`)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "is not permitted")
+
+			So(L.DoString(`
+				local http = require("http")
+				local res, err = http.client():do_request(http.request("GET", target))
+				assert(res == nil and string.find(err, "is not permitted"))`), ShouldBeNil)
+		})
+
+		Convey("Requests to declared hosts go through", func() {
+			allowed := newSandboxState(Permissions{Hosts: []string{"127.0.0.1"}}, lua.Options{}, nil)
+			defer allowed.Close()
+
+			allowed.SetGlobal("target", lua.LString(server.URL))
+			So(allowed.DoString(`
+				local http = require("http")
+				local res, err = http.client():do_request(http.request("GET", target))
+				assert(err == nil, err)
+				assert(res.body == "ok")`), ShouldBeNil)
+		})
+
+		Convey("Requests of the http module go through the cassette", func() {
+			cassette := NewCassette(filepath.Join(t.TempDir(), "http.json"))
+			recording := newSandboxState(Permissions{Hosts: []string{"127.0.0.1"}}, lua.Options{}, cassette)
+			defer recording.Close()
+
+			request := `
+				local http = require("http")
+				local res, err = http.client():do_request(http.request("POST", target, "query"))
+				assert(err == nil, err)
+				assert(res.code == 200 and res.body == "ok")`
+			recording.SetGlobal("target", lua.LString(server.URL))
+			So(recording.DoString(request), ShouldBeNil)
+			So(cassette.Interactions, ShouldHaveLength, 1)
+			So(cassette.Interactions[0].Body, ShouldEqual, "query")
+			So(cassette.Save(), ShouldBeNil)
+
+			Convey("And are replayed without network", func() {
+				target := server.URL
+				server.Close()
+
+				replay, err := LoadCassette(cassette.Path())
+				So(err, ShouldBeNil)
+				replaying := newSandboxState(Permissions{Hosts: []string{"127.0.0.1"}}, lua.Options{}, replay)
+				defer replaying.Close()
+
+				replaying.SetGlobal("target", lua.LString(target))
+				So(replaying.DoString(request), ShouldBeNil)
+
+				replaying.SetGlobal("target", lua.LString(target+"/unrecorded"))
+				So(replaying.DoString(`
+					local http = require("http")
+					local res, err = http.client():do_request(http.request("GET", target))
+					assert(res == nil and string.find(err, "has no response"))`), ShouldBeNil)
+			})
+		})
+
+		Convey("Local files cannot be uploaded without the io permission", func() {
+			allowed := newSandboxState(Permissions{Hosts: []string{"127.0.0.1"}}, lua.Options{}, nil)
+			defer allowed.Close()
+
+			allowed.SetGlobal("target", lua.LString(server.URL))
+			for _, module := range []string{"http", "http_client"} {
+				err := allowed.DoString(`require("` + module + `").file_request(target, {{ fieldname = "f", path = "/etc/passwd" }})`)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "file_request reads local files and is not permitted")
+			}
+		})
+
+		Convey("Local files cannot be read with templates without the io permission", func() {
+			for _, script := range []string{
+				`require("template").render_file("/etc/passwd")`,
+				`require("template").choose("mustache"):render_file("/etc/passwd", {})`,
+				`require("template").choose("mustache"):render("{{> /etc/passwd}}", {})`,
+			} {
+				denied := newSandboxState(Permissions{Hosts: []string{"*.example.com"}}, lua.Options{}, nil)
+				err := denied.DoString(script)
+				denied.Close()
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "module template reads local files and is not permitted")
+			}
+		})
+
+		Convey("Granted modules become available", func() {
+			granted := newSandboxState(Permissions{Modules: []string{"io", "goos"}}, lua.Options{}, nil)
+			defer granted.Close()
+
+			So(granted.DoString(`assert(io ~= nil); require("goos")`), ShouldBeNil)
+			So(granted.DoString(`assert(require("template").choose("mustache"):render("{{a}}", { a = "b" }) == "b")`), ShouldBeNil)
+		})
+	})
+}
+
+func TestPermissions(t *testing.T) {
+	Convey("Hosts", t, func() {
+		p := Permissions{Hosts: []string{"*.allanime.day", "*s3taku*", "api.example.com"}}
+		So(p.AllowsHost("allanime.day"), ShouldBeTrue)
+		So(p.AllowsHost("api.allanime.day"), ShouldBeTrue)
+		So(p.AllowsHost("evilallanime.day"), ShouldBeFalse)
+		So(p.AllowsHost("embed.s3taku.net"), ShouldBeTrue)
+		So(p.AllowsHost("API.example.com."), ShouldBeTrue)
+		So(p.AllowsHost("example.com"), ShouldBeFalse)
+		So(Permissions{Hosts: []string{"*"}}.AllowsHost("anything.io"), ShouldBeTrue)
+	})
+
+	Convey("Given a script requesting permissions", t, func() {
+		filesystem.SetMemMapFs()
+		grants.loaded = nil
+		defer func() { PermissionPrompt = nil }()
+
+		manifest := &Manifest{
+			URL:         "https://example.com",
+			Permissions: Permissions{Hosts: []string{"cdn.example.net"}, Modules: []string{"headless"}},
+		}
+		So(manifest.RequestedPermissions().Hosts, ShouldResemble, []string{"cdn.example.net", "example.com"})
+
+		Convey("It fails to load without a way to ask", func() {
+			err := CheckPermissions("example", manifest)
+			So(err, ShouldHaveSameTypeAs, &PermissionError{})
+			So(err.Error(), ShouldContainSubstring, "sources grant example")
+		})
+
+		Convey("It asks once and remembers the answer", func() {
+			asked := 0
+			PermissionPrompt = func(string, Permissions) bool {
+				asked++
+				return true
+			}
+
+			So(CheckPermissions("example", manifest), ShouldBeNil)
+			So(CheckPermissions("example", manifest), ShouldBeNil)
+			So(asked, ShouldEqual, 1)
+
+			Convey("And asks again when a new version wants more", func() {
+				manifest.Permissions.Hosts = append(manifest.Permissions.Hosts, "other.net")
+				So(CheckPermissions("example", manifest), ShouldBeNil)
+				So(asked, ShouldEqual, 2)
+			})
+		})
+
+		Convey("Loading it where nobody can be asked tells how to grant them", func() {
+			filesystem.SetOsFs()
+			defer filesystem.SetMemMapFs()
+			viper.Set(key.SourcesSandbox, true)
+			defer viper.Set(key.SourcesSandbox, nil)
+
+			script := filepath.Join(t.TempDir(), "example.lua")
+			So(os.WriteFile(script, []byte("-- @url https://example.com\nfunction SearchAnimes() end"), 0o644), ShouldBeNil)
+
+			_, err := LoadSource(script)
+			So(err, ShouldHaveSameTypeAs, &PermissionError{})
+			So(err.Error(), ShouldContainSubstring, `run "anisan sources grant example"`)
+		})
+
+		Convey("A packaged source is asked for the permissions of its init.lua", func() {
+			pkg := filepath.Join("sources", "example")
+			So(filesystem.API().MkdirAll(pkg, os.ModePerm), ShouldBeNil)
+			So(filesystem.API().WriteFile(filepath.Join(pkg, PackageScript), []byte("-- @url https://example.com\nfunction SearchAnimes() end"), 0o644), ShouldBeNil)
+
+			var asked string
+			PermissionPrompt = func(script string, requested Permissions) bool {
+				asked = script
+				return true
+			}
+
+			So(ReviewPermissions(pkg), ShouldBeNil)
+			So(asked, ShouldEqual, "example")
+
+			granted, err := Granted("example")
+			So(err, ShouldBeNil)
+			So(granted.Hosts, ShouldContain, "example.com")
+		})
+
+		Convey("A refusal is not remembered", func() {
+			PermissionPrompt = func(string, Permissions) bool { return false }
+			So(CheckPermissions("example", manifest), ShouldNotBeNil)
+
+			granted, err := Granted("example")
+			So(err, ShouldBeNil)
+			So(granted.IsEmpty(), ShouldBeTrue)
+		})
+	})
+}
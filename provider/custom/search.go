@@ -2,28 +2,65 @@
 package custom
 
 import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+
 	"github.com/anisan-cli/anisan/constant"
 	"github.com/anisan-cli/anisan/internal/cache"
+	"github.com/anisan-cli/anisan/log"
 	"github.com/anisan-cli/anisan/source"
 	lua "github.com/yuin/gopher-lua"
 )
 
 func (s *luaSource) Search(query string) ([]*source.Anime, error) {
-	cacheKey := cache.GenerateKey(query, s.Name())
-	var cachedAnimes []*source.Anime
-	if cache.Read(cacheKey, &cachedAnimes) {
-		for _, a := range cachedAnimes {
+	return s.SearchContext(context.Background(), query)
+}
+
+func (s *luaSource) SearchContext(ctx context.Context, query string) ([]*source.Anime, error) {
+	page, err := s.SearchPage(ctx, source.SearchRequest{Query: query, Page: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Animes, nil
+}
+
+// SearchPage passes the structured request to SearchAnimes as its second argument,
+// and the anime being looked for, if any, as its third.
+// Scripts may return either a plain list of animes or a page table of the form
+// { animes = {...}, has_more = true, cursor = "..." }.
+func (s *luaSource) SearchPage(ctx context.Context, request source.SearchRequest) (*source.SearchPage, error) {
+	if request.Page < 1 {
+		request.Page = 1
+	}
+
+	cacheKey := cache.GenerateKey(searchCacheKey(request), s.Name())
+	var cached cachedSearchPage
+	if cache.Read(cacheKey, &cached) {
+		for _, a := range cached.Animes {
 			a.Source = s
 		}
-		return cachedAnimes, nil
+		source.FromCache(ctx)
+		return &source.SearchPage{Animes: cached.Animes, HasMore: cached.HasMore, Cursor: cached.Cursor}, nil
 	}
 
-	val, err := s.call(constant.SearchAnimesFn, lua.LTTable, lua.LString(query))
+	val, err := s.call(ctx, constant.SearchAnimesFn, lua.LTTable, func(L *lua.LState) []lua.LValue {
+		return searchArgs(L, request)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	page := &source.SearchPage{}
 	table := val.(*lua.LTable)
+	if animes, ok := table.RawGetString("animes").(*lua.LTable); ok {
+		page.HasMore = lua.LVAsBool(table.RawGetString("has_more"))
+		page.Cursor = getString(table, "cursor")
+		table = animes
+	}
+
 	// Pre-allocate slice capacity based on the Lua table length to minimize reallocations.
 	animes := make([]*source.Anime, 0, table.Len())
 
@@ -50,9 +87,78 @@ func (s *luaSource) Search(query string) ([]*source.Anime, error) {
 		return nil, errs[0]
 	}
 
+	page.Animes = animes
+
 	if len(animes) > 0 {
-		_ = cache.Write(cacheKey, animes)
+		_ = cache.Write(cacheKey, cachedSearchPage{Animes: animes, HasMore: page.HasMore, Cursor: page.Cursor})
+	}
+
+	return page, nil
+}
+
+// Filters returns the filters declared by the optional SearchFilters function of the script.
+// They are read once, a failed attempt is retried on the next call.
+func (s *luaSource) Filters() []source.Filter {
+	s.filtersMutex.Lock()
+	defer s.filtersMutex.Unlock()
+
+	if s.filtersLoaded {
+		return s.filters
+	}
+
+	filters, err := s.loadFilters()
+	if err != nil {
+		log.Warnf("%s: %s failed: %v", s.name, constant.SearchFiltersFn, err)
+		return nil
+	}
+
+	s.filters, s.filtersLoaded = filters, true
+	return s.filters
+}
+
+// loadFilters calls SearchFilters within the budget of the source. Scripts without it have no filters.
+func (s *luaSource) loadFilters() ([]source.Filter, error) {
+	ret, err := s.call(context.Background(), constant.SearchFiltersFn, lua.LTTable, func(*lua.LState) []lua.LValue {
+		return nil
+	})
+	if errors.Is(err, errUndefined) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return filtersFromTable(ret.(*lua.LTable)), nil
+}
+
+type cachedSearchPage struct {
+	Animes  []*source.Anime `json:"animes"`
+	HasMore bool            `json:"has_more"`
+	Cursor  string          `json:"cursor"`
+}
+
+// searchCacheKey identifies a request so that every page and filter combination is cached separately.
+func searchCacheKey(request source.SearchRequest) string {
+	key := request.Query
+	if request.Page > 1 || request.Cursor != "" {
+		key += "\x00page=" + strconv.Itoa(request.Page) + "\x00cursor=" + request.Cursor
+	}
+
+	// Scripts may answer differently once they know which anime is looked for
+	if request.Anime != nil {
+		anilistID, malID := request.Anime.TrackerIDs()
+		key += "\x00anime=" + strconv.Itoa(anilistID) + "/" + strconv.Itoa(malID)
+	}
+
+	names := make([]string, 0, len(request.Filters))
+	for name := range request.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		key += "\x00" + name + "=" + request.Filters[name]
 	}
 
-	return animes, nil
+	return key
 }
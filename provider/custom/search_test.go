@@ -0,0 +1,45 @@
+package custom
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestFilters(t *testing.T) {
+	Convey("Given a source whose first VM fails to build", t, func() {
+		builds := 0
+		pool := newStatePool(1, func() (*lua.LState, error) {
+			builds++
+			if builds == 1 {
+				return nil, errors.New("offline")
+			}
+
+			L := lua.NewState()
+			return L, L.DoString(`function SearchFilters()
+				return { { name = "genre", label = "Genre", options = { "action", "drama" } } }
+			end`)
+		})
+		defer pool.Close()
+
+		src, _ := newLuaSource("filtered", pool)
+
+		Convey("When its filters are asked for twice", func() {
+			first := src.Filters()
+			second := src.Filters()
+
+			Convey("Then the failure is not remembered", func() {
+				So(first, ShouldBeEmpty)
+				So(second, ShouldHaveLength, 1)
+				So(second[0].Name, ShouldEqual, "genre")
+			})
+
+			Convey("Then the filters are read once", func() {
+				So(src.Filters(), ShouldResemble, second)
+				So(builds, ShouldEqual, 2)
+			})
+		})
+	})
+}
@@ -1,16 +1,26 @@
 package custom
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 
+	"github.com/anisan-cli/anisan/source"
 	lua "github.com/yuin/gopher-lua"
 )
 
+// errUndefined is returned when the script does not define the function called.
+var errUndefined = errors.New("is not defined")
+
 type luaSource struct {
-	name  string
-	state *lua.LState
-	mu    sync.Mutex // Protects the non-thread-safe Lua stack from concurrent access
+	name   string
+	pool   *statePool // One VM per concurrent call, as the Lua stack is not thread-safe
+	budget Budget     // Bounds every call into the script
+
+	filtersMutex  sync.Mutex
+	filtersLoaded bool
+	filters       []source.Filter
 }
 
 func (s *luaSource) Name() string {
@@ -21,37 +31,69 @@ func (s *luaSource) ID() string {
 	return IDfromName(s.name) // Defined in loader.go
 }
 
-func newLuaSource(name string, state *lua.LState) (*luaSource, error) {
+// Close frees the Lua VMs backing the source.
+func (s *luaSource) Close() error {
+	s.pool.Close()
+	return nil
+}
+
+func newLuaSource(name string, pool *statePool) (*luaSource, error) {
 	s := &luaSource{
-		name:  name,
-		state: state,
+		name: name,
+		pool: pool,
 	}
 
 	return s, nil
 }
 
-func (s *luaSource) call(fn string, retType lua.LValueType, args ...lua.LValue) (lua.LValue, error) {
-	// Acquire lock to prevent stack corruption from concurrent TUI background updates.
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// call runs a global Lua function on a pooled VM. Arguments are built by args
+// on the VM that runs the call.
+func (s *luaSource) call(ctx context.Context, fn string, retType lua.LValueType, args func(L *lua.LState) []lua.LValue) (lua.LValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	L, err := s.pool.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	luaFn := s.state.GetGlobal(fn)
+	luaFn := L.GetGlobal(fn)
 	if luaFn.Type() != lua.LTFunction {
-		return nil, fmt.Errorf("function %s is not defined", fn)
+		s.pool.release(L)
+		return nil, fmt.Errorf("function %s %w", fn, errUndefined)
 	}
 
-	err := s.state.CallByParam(lua.P{
+	// The VM checks the context between instructions and http_tls reads it back
+	// through L.Context(), so cancelling ctx aborts both Lua code and pending requests.
+	// The meter wrapping it enforces the budget of the call the same way.
+	m := s.budget.meter(ctx)
+	L.SetContext(m)
+	err = L.CallByParam(lua.P{
 		Fn:      luaFn,
 		NRet:    1,
 		Protect: true,
-	}, args...)
+	}, args(L)...)
+	L.RemoveContext()
+	m.stop()
 
 	if err != nil {
+		// An interrupted call may leave a half-built stack behind, so its VM is not reused.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			s.pool.discard(L)
+			return nil, ctxErr
+		}
+		if limit := m.exceeded(err); limit != "" {
+			s.pool.discard(L)
+			return nil, &BudgetError{Source: s.name, Function: fn, Limit: limit, Budget: s.budget}
+		}
+		s.pool.release(L)
 		return nil, err
 	}
 
-	retval := s.state.Get(-1)
-	s.state.Pop(1) // Clean stack
+	retval := L.Get(-1)
+	L.Pop(1) // Clean stack
+	s.pool.release(L)
 
 	if retval.Type() != retType {
 		return nil, fmt.Errorf("%s returned %s, expected %s", fn, retval.Type(), retType)
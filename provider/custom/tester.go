@@ -0,0 +1,213 @@
+package custom
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/anisan-cli/anisan/constant"
+	"github.com/anisan-cli/anisan/source"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// TestStep is the outcome of calling one function of a source.
+type TestStep struct {
+	Function string
+	// Input is what the function was called with.
+	Input string
+	// Results is the number of entries that passed the translator.
+	Results int
+	// Problems are the entries the translator rejected or would mishandle.
+	Problems []string
+	// Err is set if the call itself failed.
+	Err      error
+	Duration time.Duration
+}
+
+// Passed reports whether the call succeeded and every entry it returned is valid.
+func (s *TestStep) Passed() bool {
+	return s.Err == nil && len(s.Problems) == 0
+}
+
+func (s *TestStep) problem(format string, args ...any) {
+	s.Problems = append(s.Problems, fmt.Sprintf(format, args...))
+}
+
+// TestReport is the outcome of Test.
+type TestReport struct {
+	Source string
+	Steps  []*TestStep
+}
+
+// Passed reports whether every function of the source was called and passed.
+func (r *TestReport) Passed() bool {
+	if len(r.Steps) < 3 {
+		return false
+	}
+
+	for _, step := range r.Steps {
+		if !step.Passed() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Test loads the script at path and calls SearchAnimes with the query, AnimeEpisodes
+// with the first anime found and EpisodeVideos with the first episode, checking what
+// every call returns against the rules of the translator. The http_tls requests of
+// the script go through the cassette, if one is given. Caches are bypassed.
+// It stops at the first function that returns nothing usable.
+func Test(ctx context.Context, path, query string, cassette *Cassette) (*TestReport, error) {
+	src, err := loadLuaSource(path, LoadOptions{Cassette: cassette})
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	report := &TestReport{Source: src.Name()}
+
+	step := &TestStep{Function: constant.SearchAnimesFn, Input: fmt.Sprintf("%q", query)}
+	report.Steps = append(report.Steps, step)
+	animes := testAnimes(ctx, src, query, step)
+	if len(animes) == 0 {
+		return report, nil
+	}
+
+	anime := animes[0]
+	step = &TestStep{Function: constant.AnimeEpisodesFn, Input: anime.Name}
+	report.Steps = append(report.Steps, step)
+	episodes := testEpisodes(ctx, src, anime, step)
+	if len(episodes) == 0 {
+		return report, nil
+	}
+
+	episode := episodes[0]
+	step = &TestStep{Function: constant.EpisodeVideosFn, Input: episode.Name}
+	report.Steps = append(report.Steps, step)
+	testVideos(ctx, src, episode, step)
+
+	return report, nil
+}
+
+// testCall calls fn and passes every entry of the returned list to each.
+// The list may also come wrapped in a page table under the given field.
+func testCall(
+	ctx context.Context,
+	src *luaSource,
+	step *TestStep,
+	page string,
+	args func(L *lua.LState) []lua.LValue,
+	each func(table *lua.LTable, index uint16) error,
+) {
+	start := time.Now()
+	val, err := src.call(ctx, step.Function, lua.LTTable, args)
+	step.Duration = time.Since(start)
+	if err != nil {
+		step.Err = err
+		return
+	}
+
+	table := val.(*lua.LTable)
+	if page != "" {
+		if list, ok := table.RawGetString(page).(*lua.LTable); ok {
+			table = list
+		}
+	}
+
+	table.ForEach(func(k, v lua.LValue) {
+		if k.Type() != lua.LTNumber {
+			step.problem("key %s: entries must be in a list, got %s key", k, k.Type())
+			return
+		}
+
+		if v.Type() != lua.LTTable {
+			step.problem("#%s: expected a table, got %s", k, v.Type())
+			return
+		}
+
+		if err := each(v.(*lua.LTable), uint16(k.(lua.LNumber))); err != nil {
+			step.problem("#%s: %v", k, err)
+			return
+		}
+
+		step.Results++
+	})
+
+	if step.Results == 0 && len(step.Problems) == 0 {
+		step.problem("returned nothing")
+	}
+}
+
+func testAnimes(ctx context.Context, src *luaSource, query string, step *TestStep) []*source.Anime {
+	var animes []*source.Anime
+	urls := make(map[string]uint16)
+
+	request := source.SearchRequest{Query: query, Page: 1}
+	testCall(ctx, src, step, "animes", func(L *lua.LState) []lua.LValue {
+		return searchArgs(L, request)
+	}, func(table *lua.LTable, index uint16) error {
+		anime, err := animeFromTable(table, index)
+		if err != nil {
+			return err
+		}
+
+		if other, ok := urls[anime.URL]; ok {
+			return fmt.Errorf("url %q is already used by #%d", anime.URL, other)
+		}
+		urls[anime.URL] = index
+
+		anime.Source = src
+		animes = append(animes, anime)
+		return nil
+	})
+
+	return animes
+}
+
+func testEpisodes(ctx context.Context, src *luaSource, anime *source.Anime, step *TestStep) []*source.Episode {
+	var episodes []*source.Episode
+	urls := make(map[string]uint16)
+
+	testCall(ctx, src, step, "", func(L *lua.LState) []lua.LValue {
+		return []lua.LValue{animeToTable(L, anime)}
+	}, func(table *lua.LTable, index uint16) error {
+		episode, err := episodeFromTable(table, anime, index)
+		if err != nil {
+			return err
+		}
+
+		if other, ok := urls[episode.URL]; ok {
+			return fmt.Errorf("url %q is already used by #%d", episode.URL, other)
+		}
+		urls[episode.URL] = index
+
+		episodes = append(episodes, episode)
+		return nil
+	})
+
+	return episodes
+}
+
+func testVideos(ctx context.Context, src *luaSource, episode *source.Episode, step *TestStep) {
+	testCall(ctx, src, step, "", func(L *lua.LState) []lua.LValue {
+		return []lua.LValue{episodeToTable(L, episode)}
+	}, func(table *lua.LTable, index uint16) error {
+		video, err := videoFromTable(table, index)
+		if err != nil {
+			return err
+		}
+
+		if u, err := url.Parse(video.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("video url %q is not an http(s) link", video.URL)
+		}
+
+		if subtitles, ok := table.RawGetString("subtitles").(*lua.LTable); ok && subtitles.Len() != len(video.Subtitles) {
+			return fmt.Errorf("%d of %d subtitles have no url", subtitles.Len()-len(video.Subtitles), subtitles.Len())
+		}
+
+		return nil
+	})
+}
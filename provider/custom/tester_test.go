@@ -0,0 +1,107 @@
+package custom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anisan-cli/anisan/filesystem"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testerScript = `
+local base = os.getenv("TEST_SERVER")
+
+function SearchAnimes(query)
+	local body = http_tls.get(base .. "/search?q=" .. query)
+	return {
+		{ name = body, url = "/anime/1" },
+		{ name = "No url" },
+	}
+end
+
+function AnimeEpisodes(anime)
+	local res = http_tls.request({ method = "POST", url = base .. anime.url, body = "episodes" })
+	return { { name = res.body, url = "/episode/1" } }
+end
+
+function EpisodeVideos(episode)
+	return { { url = "https://cdn.example.com/1.m3u8", quality = "1080p" } }
+end
+`
+
+func TestTest(t *testing.T) {
+	Convey("Given a source and a server it scrapes", t, func() {
+		filesystem.SetOsFs()
+
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if r.Method == http.MethodPost {
+				_, _ = w.Write([]byte("Episode 1"))
+				return
+			}
+			_, _ = w.Write([]byte("Found " + r.URL.Query().Get("q")))
+		}))
+		defer server.Close()
+		t.Setenv("TEST_SERVER", server.URL)
+
+		dir := t.TempDir()
+		script := filepath.Join(dir, "example.lua")
+		So(os.WriteFile(script, []byte(testerScript), 0o644), ShouldBeNil)
+		path := filepath.Join(dir, "example.json")
+
+		Convey("Recording runs every function against the network", func() {
+			cassette := NewCassette(path)
+			report, err := Test(context.Background(), script, "naruto", cassette)
+			So(err, ShouldBeNil)
+			So(requests, ShouldEqual, 2)
+			So(cassette.Interactions, ShouldHaveLength, 2)
+			So(cassette.Interactions[1].Body, ShouldEqual, "episodes")
+
+			So(report.Steps, ShouldHaveLength, 3)
+			So(report.Steps[0].Results, ShouldEqual, 1)
+			So(report.Steps[0].Problems, ShouldResemble, []string{"#2: anime must have name and url"})
+			So(report.Steps[1].Passed(), ShouldBeTrue)
+			So(report.Steps[2].Passed(), ShouldBeTrue)
+			So(report.Passed(), ShouldBeFalse)
+
+			Convey("And replaying the cassette needs no network", func() {
+				So(cassette.Save(), ShouldBeNil)
+				server.Close()
+
+				replay, err := LoadCassette(path)
+				So(err, ShouldBeNil)
+
+				report, err := Test(context.Background(), script, "naruto", replay)
+				So(err, ShouldBeNil)
+				So(requests, ShouldEqual, 2)
+				So(report.Steps, ShouldHaveLength, 3)
+				So(report.Steps[0].Input, ShouldEqual, `"naruto"`)
+				So(report.Steps[1].Input, ShouldEqual, "Found naruto")
+				So(report.Steps[2].Input, ShouldEqual, "Episode 1")
+			})
+
+			Convey("And requests missing from the cassette fail", func() {
+				So(cassette.Save(), ShouldBeNil)
+
+				replay, err := LoadCassette(path)
+				So(err, ShouldBeNil)
+
+				report, err := Test(context.Background(), script, "bleach", replay)
+				So(err, ShouldBeNil)
+				So(report.Steps, ShouldHaveLength, 1)
+				So(report.Steps[0].Err.Error(), ShouldContainSubstring, "has no response for GET")
+			})
+		})
+
+		Convey("Replaying a missing cassette fails", func() {
+			_, err := LoadCassette(path)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "record one first")
+		})
+	})
+}
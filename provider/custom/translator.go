@@ -3,6 +3,7 @@ package custom
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -67,6 +68,8 @@ func animeFromTable(table *lua.LTable, index uint16) (*source.Anime, error) {
 	return anime, nil
 }
 
+var episodeNumber = regexp.MustCompile(`\d+(\.\d+)?`)
+
 func episodeFromTable(table *lua.LTable, anime *source.Anime, index uint16) (*source.Episode, error) {
 	name := getString(table, "name")
 	url := getString(table, "url")
@@ -75,43 +78,44 @@ func episodeFromTable(table *lua.LTable, anime *source.Anime, index uint16) (*so
 		return nil, fmt.Errorf("episode must have name and url")
 	}
 
-	finalIndex := index
-
-	// Always try to parse from name first (Most reliable for sorting)
-	// Matches "25", "25.5", "Episode 25", etc.
-	re := regexp.MustCompile(`(\d+(\.\d+)?)`)
-	matches := re.FindAllString(name, -1)
-	parsedFromName := false
-	if len(matches) > 0 {
-		// Take the last number found (usually the episode number in "Season 1 Episode 25")
-		lastMatch := matches[len(matches)-1]
-		if parsed, err := strconv.ParseFloat(lastMatch, 64); err == nil {
-			finalIndex = uint16(parsed)
-			parsedFromName = true
-		}
+	// The number declared by the script wins, names may hold other numbers such as "Episode 3 (1080p)"
+	var number float64
+	switch val := table.RawGetString("number"); val.Type() {
+	case lua.LTNumber:
+		number = float64(val.(lua.LNumber))
+	case lua.LTString:
+		number, _ = strconv.ParseFloat(strings.TrimSpace(val.String()), 64)
 	}
 
-	// Only check Lua 'number' if we couldn't parse from name
-	if !parsedFromName {
-		val := table.RawGetString("number")
-		if val.Type() == lua.LTNumber {
-			finalIndex = uint16(val.(lua.LNumber))
-		} else if val.Type() == lua.LTString {
-			if parsed, err := strconv.ParseUint(val.String(), 10, 16); err == nil {
-				finalIndex = uint16(parsed)
-			}
+	// Otherwise it is parsed from the name: "25", "25.5", "Episode 25", etc.
+	if number == 0 {
+		if matches := episodeNumber.FindAllString(name, -1); len(matches) > 0 {
+			// Take the last number found (usually the episode number in "Season 1 Episode 25")
+			number, _ = strconv.ParseFloat(matches[len(matches)-1], 64)
 		}
 	}
 
-	// Discovery verification can be performed here for diagnostic purposes.
+	// Scripts may declare the kind, otherwise it is guessed from the name and number
+	kind, ok := source.ParseEpisodeKind(getString(table, "kind"))
+	if !ok || getString(table, "kind") == "" {
+		kind = source.GuessEpisodeKind(name, number)
+	}
+	if kind == source.KindRegular {
+		kind = ""
+	}
 
 	ep := &source.Episode{
 		Name:   name,
 		URL:    url,
-		Index:  finalIndex,
+		Index:  index,
+		Number: number,
+		Kind:   kind,
 		ID:     url,
 		Volume: getString(table, "volume"),
 		Anime:  anime,
+
+		Translation: strings.ToLower(getString(table, "translation")),
+		Language:    getString(table, "language"),
 	}
 
 	return ep, nil
@@ -131,6 +135,9 @@ func videoFromTable(table *lua.LTable, index uint16) (*source.Video, error) {
 		Extension: getString(table, "extension"),
 		Index:     index,
 		Headers:   make(map[string]string),
+
+		Translation: strings.ToLower(getString(table, "translation")),
+		Language:    getString(table, "language"),
 	}
 
 	// Headers
@@ -141,13 +148,90 @@ func videoFromTable(table *lua.LTable, index uint16) (*source.Video, error) {
 		})
 	}
 
+	if subtitlesTbl, ok := table.RawGetString("subtitles").(*lua.LTable); ok {
+		video.Subtitles = subtitlesFromTable(subtitlesTbl)
+	}
+
 	return video, nil
 }
 
+// subtitlesFromTable reads a list of { url = "...", language = "...", format = "...", label = "..." } tables.
+// Entries without a url are skipped.
+func subtitlesFromTable(table *lua.LTable) []*source.Subtitle {
+	var subtitles []*source.Subtitle
+	table.ForEach(func(_, v lua.LValue) {
+		tbl, ok := v.(*lua.LTable)
+		if !ok {
+			return
+		}
+
+		url := getString(tbl, "url")
+		if url == "" {
+			return
+		}
+
+		language := getString(tbl, "language")
+		if language == "" {
+			language = getString(tbl, "lang")
+		}
+
+		format := strings.ToLower(getString(tbl, "format"))
+		if format == "" {
+			format = subtitleFormat(url)
+		}
+
+		subtitles = append(subtitles, &source.Subtitle{
+			URL:      url,
+			Language: language,
+			Format:   format,
+			Label:    getString(tbl, "label"),
+		})
+	})
+
+	return subtitles
+}
+
+// subtitleFormat guesses the subtitle format from the file extension of url.
+func subtitleFormat(url string) string {
+	path, _, _ := strings.Cut(url, "?")
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".vtt", ".srt", ".ass", ".ssa":
+		return ext[1:]
+	default:
+		return ""
+	}
+}
+
+// animeToTable passes anime to the script with everything known about it,
+// so that scripts can match it by tracker ID or tell its seasons apart.
 func animeToTable(L *lua.LState, anime *source.Anime) *lua.LTable {
 	table := L.NewTable()
 	table.RawSetString("name", lua.LString(anime.Name))
 	table.RawSetString("url", lua.LString(anime.URL))
+	table.RawSetString("id", lua.LString(anime.ID))
+	if anime.Metadata.Title != "" {
+		table.RawSetString("title", lua.LString(anime.Metadata.Title))
+	}
+
+	synonyms := L.NewTable()
+	for _, synonym := range anime.Metadata.Synonyms {
+		synonyms.Append(lua.LString(synonym))
+	}
+	table.RawSetString("synonyms", synonyms)
+
+	if year := anime.Metadata.StartDate.Year; year > 0 {
+		table.RawSetString("year", lua.LNumber(year))
+	}
+
+	anilistID, malID := anime.TrackerIDs()
+	if anilistID != 0 {
+		table.RawSetString("anilist_id", lua.LNumber(anilistID))
+	}
+	if malID != 0 {
+		table.RawSetString("mal_id", lua.LNumber(malID))
+	}
+
 	return table
 }
 
@@ -155,5 +239,77 @@ func episodeToTable(L *lua.LState, episode *source.Episode) *lua.LTable {
 	table := L.NewTable()
 	table.RawSetString("name", lua.LString(episode.Name))
 	table.RawSetString("url", lua.LString(episode.URL))
+	table.RawSetString("id", lua.LString(episode.ID))
+	table.RawSetString("index", lua.LNumber(episode.Index))
+	if episode.Number > 0 {
+		table.RawSetString("number", lua.LNumber(episode.Number))
+	}
+	if episode.Kind != "" {
+		table.RawSetString("kind", lua.LString(episode.Kind))
+	}
+	if episode.Volume != "" {
+		table.RawSetString("volume", lua.LString(episode.Volume))
+	}
+	if episode.Translation != "" {
+		table.RawSetString("translation", lua.LString(episode.Translation))
+	}
+	if episode.Language != "" {
+		table.RawSetString("language", lua.LString(episode.Language))
+	}
+	if episode.Anime != nil {
+		table.RawSetString("anime", animeToTable(L, episode.Anime))
+	}
+	return table
+}
+
+// searchArgs returns the arguments SearchAnimes is called with: the query, the full request
+// and the anime being looked for, or nil when the search is not about a known anime.
+func searchArgs(L *lua.LState, request source.SearchRequest) []lua.LValue {
+	var known lua.LValue = lua.LNil
+	if request.Anime != nil {
+		known = animeToTable(L, request.Anime)
+	}
+
+	return []lua.LValue{lua.LString(request.Query), searchRequestToTable(L, request), known}
+}
+
+func searchRequestToTable(L *lua.LState, request source.SearchRequest) *lua.LTable {
+	table := L.NewTable()
+	table.RawSetString("query", lua.LString(request.Query))
+	table.RawSetString("page", lua.LNumber(request.Page))
+	if request.Cursor != "" {
+		table.RawSetString("cursor", lua.LString(request.Cursor))
+	}
+
+	filters := L.NewTable()
+	for name, value := range request.Filters {
+		filters.RawSetString(name, lua.LString(value))
+	}
+	table.RawSetString("filters", filters)
+
 	return table
 }
+
+// filtersFromTable reads a list of { name = "...", label = "...", options = {...} } tables.
+func filtersFromTable(table *lua.LTable) []source.Filter {
+	var filters []source.Filter
+	table.ForEach(func(_, v lua.LValue) {
+		tbl, ok := v.(*lua.LTable)
+		if !ok {
+			return
+		}
+
+		name := getString(tbl, "name")
+		if name == "" {
+			return
+		}
+
+		filters = append(filters, source.Filter{
+			Name:    name,
+			Label:   getString(tbl, "label"),
+			Options: getStringList(tbl, "options"),
+		})
+	})
+
+	return filters
+}
@@ -3,7 +3,9 @@ package custom
 import (
 	"testing"
 
+	"github.com/anisan-cli/anisan/anilist"
 	"github.com/anisan-cli/anisan/source"
+	"github.com/samber/mo"
 	. "github.com/smartystreets/goconvey/convey"
 	lua "github.com/yuin/gopher-lua"
 )
@@ -80,6 +82,33 @@ func TestVideoFromTable(t *testing.T) {
 			So(video.Headers["User-Agent"], ShouldEqual, "Mozilla/5.0")
 		})
 
+		Convey("Should extract subtitle tracks", func() {
+			tbl := L.NewTable()
+			tbl.RawSetString("url", lua.LString("https://example.com/stream.m3u8"))
+
+			english := L.NewTable()
+			english.RawSetString("url", lua.LString("https://example.com/subs/en.vtt?token=1"))
+			english.RawSetString("lang", lua.LString("en"))
+
+			spanish := L.NewTable()
+			spanish.RawSetString("url", lua.LString("https://example.com/subs/es"))
+			spanish.RawSetString("language", lua.LString("es"))
+			spanish.RawSetString("format", lua.LString("ASS"))
+			spanish.RawSetString("label", lua.LString("Español"))
+
+			subtitles := L.NewTable()
+			subtitles.Append(english)
+			subtitles.Append(spanish)
+			subtitles.Append(L.NewTable()) // no url, skipped
+			tbl.RawSetString("subtitles", subtitles)
+
+			video, err := videoFromTable(tbl, 0)
+			So(err, ShouldBeNil)
+			So(video.Subtitles, ShouldHaveLength, 2)
+			So(*video.Subtitles[0], ShouldResemble, source.Subtitle{URL: "https://example.com/subs/en.vtt?token=1", Language: "en", Format: "vtt"})
+			So(*video.Subtitles[1], ShouldResemble, source.Subtitle{URL: "https://example.com/subs/es", Language: "es", Format: "ass", Label: "Español"})
+		})
+
 		Convey("Should fail when URL is missing", func() {
 			tbl := L.NewTable()
 			tbl.RawSetString("quality", lua.LString("720p"))
@@ -107,5 +136,110 @@ func TestEpisodeFromTable(t *testing.T) {
 			So(episode.URL, ShouldEqual, "https://example.com/ep1")
 			So(episode.Anime, ShouldEqual, anime)
 		})
+
+		Convey("Should read the translation variant and audio language", func() {
+			tbl := L.NewTable()
+			tbl.RawSetString("name", lua.LString("Episode 1"))
+			tbl.RawSetString("url", lua.LString("show:1:dub"))
+			tbl.RawSetString("translation", lua.LString("DUB"))
+			tbl.RawSetString("language", lua.LString("en"))
+
+			episode, err := episodeFromTable(tbl, &source.Anime{}, 0)
+			So(err, ShouldBeNil)
+			So(episode.Translation, ShouldEqual, source.TranslationDub)
+			So(episode.Language, ShouldEqual, "en")
+
+			back := episodeToTable(L, episode)
+			So(back.RawGetString("translation").String(), ShouldEqual, source.TranslationDub)
+		})
+
+		Convey("Should keep fractional numbers and tell specials apart", func() {
+			episode := func(name string, fields map[string]lua.LValue) *source.Episode {
+				tbl := L.NewTable()
+				tbl.RawSetString("name", lua.LString(name))
+				tbl.RawSetString("url", lua.LString(name))
+				for k, v := range fields {
+					tbl.RawSetString(k, v)
+				}
+
+				ep, err := episodeFromTable(tbl, &source.Anime{}, 7)
+				So(err, ShouldBeNil)
+				return ep
+			}
+
+			regular := episode("Episode 25", nil)
+			So(regular.Number, ShouldEqual, 25)
+			So(regular.Index, ShouldEqual, 7)
+			So(regular.Kind, ShouldBeEmpty)
+
+			special := episode("Episode 25.5", nil)
+			So(special.Number, ShouldEqual, 25.5)
+			So(special.Kind, ShouldEqual, source.KindSpecial)
+			So(episodeToTable(L, special).RawGetString("number"), ShouldEqual, lua.LNumber(25.5))
+
+			So(episode("Recap 2", nil).Kind, ShouldEqual, source.KindRecap)
+			So(episode("Bonus", map[string]lua.LValue{"number": lua.LString("3.5")}).Number, ShouldEqual, 3.5)
+			So(episode("Episode 3 (1080p)", map[string]lua.LValue{"number": lua.LNumber(3)}).Number, ShouldEqual, 3)
+			So(episode("Episode 3 (1080p)", map[string]lua.LValue{"number": lua.LNumber(3)}).Kind, ShouldBeEmpty)
+			So(episode("Episode 26", map[string]lua.LValue{"kind": lua.LString("OVA")}).Kind, ShouldEqual, source.KindOVA)
+			So(episode("Movie Recap Special", map[string]lua.LValue{"kind": lua.LString("regular")}).Kind, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestAnimeToTable(t *testing.T) {
+	Convey("Given an anime bound to a tracker", t, func() {
+		L := lua.NewState()
+		defer L.Close()
+
+		anime := &source.Anime{
+			Name:    "Mushishi Zoku Shou",
+			URL:     "https://example.com/mushishi-2",
+			ID:      "mushishi-2",
+			Anilist: mo.Some(&anilist.Anime{ID: 21939, IDMal: 24701}),
+		}
+		anime.Metadata.Synonyms = []string{"Mushi-Shi: The Next Passage"}
+		anime.Metadata.StartDate.Year = 2014
+
+		episode := &source.Episode{Name: "Episode 3", URL: "https://example.com/mushishi-2/3", Index: 2, Number: 3, Volume: "Part 1", Anime: anime}
+
+		Convey("When it is passed to a script", func() {
+			table := animeToTable(L, anime)
+
+			Convey("Then its IDs, synonyms and year are included", func() {
+				So(getString(table, "id"), ShouldEqual, "mushishi-2")
+				So(getStringList(table, "synonyms"), ShouldResemble, []string{"Mushi-Shi: The Next Passage"})
+				So(table.RawGetString("year"), ShouldEqual, lua.LNumber(2014))
+				So(table.RawGetString("anilist_id"), ShouldEqual, lua.LNumber(21939))
+				So(table.RawGetString("mal_id"), ShouldEqual, lua.LNumber(24701))
+			})
+		})
+
+		Convey("When one of its episodes is passed to a script", func() {
+			table := episodeToTable(L, episode)
+
+			Convey("Then its position, volume and anime are included", func() {
+				So(table.RawGetString("index"), ShouldEqual, lua.LNumber(2))
+				So(getString(table, "volume"), ShouldEqual, "Part 1")
+				So(getString(table.RawGetString("anime").(*lua.LTable), "url"), ShouldEqual, anime.URL)
+			})
+		})
+
+		Convey("When a search is about it", func() {
+			args := searchArgs(L, source.SearchRequest{Query: "mushishi", Page: 1, Anime: anime})
+
+			Convey("Then it is the third argument of SearchAnimes", func() {
+				So(args, ShouldHaveLength, 3)
+				So(args[2].(*lua.LTable).RawGetString("anilist_id"), ShouldEqual, lua.LNumber(21939))
+			})
+		})
+
+		Convey("When a search is about no anime in particular", func() {
+			args := searchArgs(L, source.SearchRequest{Query: "mushishi", Page: 1})
+
+			Convey("Then the third argument is nil", func() {
+				So(args[2], ShouldEqual, lua.LNil)
+			})
+		})
 	})
 }
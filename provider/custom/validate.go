@@ -0,0 +1,513 @@
+package custom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anisan-cli/anisan/constant"
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/samber/lo"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/ast"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Diagnostic is a problem Validate found in a script.
+type Diagnostic struct {
+	// Line is 0 for problems of the script as a whole.
+	Line    int
+	Message string
+	// Warning is set for problems that do not keep the script from working.
+	Warning bool
+}
+
+func (d Diagnostic) String() string {
+	severity := "error"
+	if d.Warning {
+		severity = "warning"
+	}
+
+	if d.Line == 0 {
+		return severity + ": " + d.Message
+	}
+
+	return fmt.Sprintf("line %d: %s: %s", d.Line, severity, d.Message)
+}
+
+// luaKind is the Lua type of a field, or a set of accepted types.
+type luaKind string
+
+const (
+	kindString       luaKind = "a string"
+	kindNumber       luaKind = "a number or a string"
+	kindList         luaKind = "a string or a list"
+	kindTable        luaKind = "a table"
+	kindUnknown      luaKind = ""
+	kindOtherLiteral luaKind = "other"
+)
+
+// shape describes the tables a function of a script returns a list of.
+// It follows what the translator reads.
+type shape struct {
+	entry    string
+	required []string
+	fields   map[string]luaKind
+}
+
+var shapes = map[string]shape{
+	constant.SearchAnimesFn: {
+		entry:    "anime",
+		required: []string{"name", "url"},
+		fields: map[string]luaKind{
+			"name": kindString, "url": kindString, "summary": kindString, "cover": kindString,
+			"banner": kindString, "status": kindString, "genres": kindList, "synonyms": kindList,
+		},
+	},
+	constant.AnimeEpisodesFn: {
+		entry:    "episode",
+		required: []string{"name", "url"},
+		fields: map[string]luaKind{
+			"name": kindString, "url": kindString, "number": kindNumber, "volume": kindString,
+			"translation": kindString, "language": kindString, "kind": kindString,
+		},
+	},
+	constant.EpisodeVideosFn: {
+		entry:    "video",
+		required: []string{"url"},
+		fields: map[string]luaKind{
+			"url": kindString, "quality": kindString, "extension": kindString, "headers": kindTable,
+			"subtitles": kindTable, "translation": kindString, "language": kindString,
+		},
+	},
+}
+
+// Validate checks the script at path without running it: its manifest, its syntax,
+// the global functions anisan requires and the shape of the tables they return
+// wherever those are written out literally. Packages are validated by their init.lua.
+func Validate(path string) ([]Diagnostic, error) {
+	name := SourceName(path)
+	path = ScriptPath(path)
+
+	data, err := filesystem.API().ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &validator{}
+
+	manifest, err := ParseManifest(bytes.NewReader(data))
+	var manifestErr *ManifestError
+	switch {
+	case errors.As(err, &manifestErr):
+		v.errorf(manifestErr.Line, "%v", manifestErr.Err)
+	case err != nil:
+		return nil, err
+	default:
+		if err := manifest.CheckCompatibility(name); err != nil {
+			v.errorf(0, "%v", err)
+		}
+	}
+
+	chunk, err := parse.Parse(bytes.NewReader(data), path)
+	if err != nil {
+		var syntaxErr *parse.Error
+		if errors.As(err, &syntaxErr) {
+			v.errorf(max(syntaxErr.Pos.Line, 0), "%s near '%s'", syntaxErr.Message, syntaxErr.Token)
+			return v.sorted(), nil
+		}
+		return nil, err
+	}
+
+	if _, err := lua.Compile(chunk, path); err != nil {
+		var compileErr *lua.CompileError
+		if errors.As(err, &compileErr) {
+			v.errorf(compileErr.Line, "%s", compileErr.Message)
+		} else {
+			v.errorf(0, "%v", err)
+		}
+		return v.sorted(), nil
+	}
+
+	functions := v.globalFunctions(chunk)
+	for _, name := range []string{constant.SearchAnimesFn, constant.AnimeEpisodesFn, constant.EpisodeVideosFn} {
+		fn, ok := functions[name]
+		if !ok {
+			v.errorf(0, "function %s is required but not defined", name)
+			continue
+		}
+
+		v.checkReturns(name, fn, shapes[name])
+	}
+
+	return v.sorted(), nil
+}
+
+type validator struct {
+	diagnostics []Diagnostic
+}
+
+func (v *validator) errorf(line int, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(line int, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{Line: line, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+func (v *validator) sorted() []Diagnostic {
+	sort.SliceStable(v.diagnostics, func(i, j int) bool {
+		return v.diagnostics[i].Line < v.diagnostics[j].Line
+	})
+	return v.diagnostics
+}
+
+// globalFunctions returns the functions the chunk assigns to globals at its top level.
+// Required functions declared local are reported, since anisan cannot see them.
+func (v *validator) globalFunctions(chunk []ast.Stmt) map[string]*ast.FunctionExpr {
+	functions := make(map[string]*ast.FunctionExpr)
+
+	for _, stmt := range chunk {
+		switch stmt := stmt.(type) {
+		case *ast.FuncDefStmt:
+			if ident, ok := stmt.Name.Func.(*ast.IdentExpr); ok && stmt.Name.Receiver == nil {
+				functions[ident.Value] = stmt.Func
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range stmt.Lhs {
+				ident, ok := lhs.(*ast.IdentExpr)
+				if !ok || i >= len(stmt.Rhs) {
+					continue
+				}
+				if fn, ok := stmt.Rhs[i].(*ast.FunctionExpr); ok {
+					functions[ident.Value] = fn
+				}
+			}
+		case *ast.LocalAssignStmt:
+			for _, name := range stmt.Names {
+				if _, ok := shapes[name]; ok {
+					v.errorf(stmt.Line(), "function %s must be global, remove \"local\"", name)
+				}
+			}
+		}
+	}
+
+	return functions
+}
+
+// checkReturns checks what fn returns against the shape. Entries are checked
+// where they are written out literally: in the returned table itself, or added
+// to a returned variable with table.insert or t[#t + 1] = {...}.
+func (v *validator) checkReturns(name string, fn *ast.FunctionExpr, s shape) {
+	returned := make(map[string]bool)
+
+	walkStmts(fn.Stmts, 0, func(stmt ast.Stmt, depth int) {
+		ret, ok := stmt.(*ast.ReturnStmt)
+		if !ok || depth > 0 {
+			return
+		}
+
+		if len(ret.Exprs) == 0 {
+			v.errorf(ret.Line(), "%s returns nothing, expected a list of %ss", name, s.entry)
+			return
+		}
+
+		switch expr := ret.Exprs[0].(type) {
+		case *ast.IdentExpr:
+			returned[expr.Value] = true
+		case *ast.TableExpr:
+			v.checkList(name, expr, s)
+		default:
+			if kind := kindOf(expr); kind != kindUnknown {
+				v.errorf(ret.Line(), "%s must return a list of %ss, not %s", name, s.entry, describe(expr))
+			}
+		}
+	})
+
+	if len(returned) == 0 {
+		return
+	}
+
+	walkStmts(fn.Stmts, 0, func(stmt ast.Stmt, _ int) {
+		switch stmt := stmt.(type) {
+		case *ast.LocalAssignStmt:
+			for i, local := range stmt.Names {
+				if table, ok := exprAt(stmt.Exprs, i).(*ast.TableExpr); ok && returned[local] {
+					v.checkList(name, table, s)
+				}
+			}
+		case *ast.FuncCallStmt:
+			call, ok := stmt.Expr.(*ast.FuncCallExpr)
+			if !ok || !isTableInsert(call) || len(call.Args) < 2 {
+				return
+			}
+			if list, ok := call.Args[0].(*ast.IdentExpr); ok && returned[list.Value] {
+				if entry, ok := call.Args[len(call.Args)-1].(*ast.TableExpr); ok {
+					v.checkEntry(entry, s)
+				}
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range stmt.Lhs {
+				get, ok := lhs.(*ast.AttrGetExpr)
+				if !ok {
+					continue
+				}
+				if _, isField := get.Key.(*ast.StringExpr); isField {
+					continue
+				}
+				if list, ok := get.Object.(*ast.IdentExpr); ok && returned[list.Value] {
+					if entry, ok := exprAt(stmt.Rhs, i).(*ast.TableExpr); ok {
+						v.checkEntry(entry, s)
+					}
+				}
+			}
+		}
+	})
+}
+
+// checkList checks the entries of a returned list. SearchAnimes may also return
+// a page table with the list under "animes".
+func (v *validator) checkList(name string, list *ast.TableExpr, s shape) {
+	if name == constant.SearchAnimesFn {
+		for _, field := range list.Fields {
+			if key, ok := field.Key.(*ast.StringExpr); ok && key.Value == "animes" {
+				if animes, ok := field.Value.(*ast.TableExpr); ok {
+					v.checkList("", animes, s)
+				}
+				return
+			}
+		}
+	}
+
+	for _, field := range list.Fields {
+		if field.Key != nil {
+			if key, ok := field.Key.(*ast.StringExpr); ok {
+				v.warnf(field.Value.Line(), "field %q of the returned list is ignored, expected a list of %ss", key.Value, s.entry)
+			}
+			continue
+		}
+
+		switch entry := field.Value.(type) {
+		case *ast.TableExpr:
+			v.checkEntry(entry, s)
+		default:
+			if kind := kindOf(entry); kind != kindUnknown {
+				v.errorf(entry.Line(), "%s entries must be tables, not %s", s.entry, describe(entry))
+			}
+		}
+	}
+}
+
+// checkEntry checks the fields of a literal anime, episode or video table.
+func (v *validator) checkEntry(entry *ast.TableExpr, s shape) {
+	present := make(map[string]bool)
+	dynamic := false
+
+	for _, field := range entry.Fields {
+		key, ok := field.Key.(*ast.StringExpr)
+		if !ok {
+			if field.Key == nil {
+				v.warnf(field.Value.Line(), "%s has a value without a field name, it is ignored", s.entry)
+			} else {
+				dynamic = true
+			}
+			continue
+		}
+
+		want, known := s.fields[key.Value]
+		if !known {
+			v.warnf(field.Value.Line(), "%s field %q is not read by anisan", s.entry, key.Value)
+			continue
+		}
+
+		if _, isNil := field.Value.(*ast.NilExpr); isNil {
+			continue
+		}
+		present[key.Value] = true
+
+		got := kindOf(field.Value)
+		if got == kindUnknown {
+			continue
+		}
+
+		if !accepts(want, got) {
+			v.errorf(field.Value.Line(), "%s field %q must be %s, not %s", s.entry, key.Value, want, describe(field.Value))
+			continue
+		}
+
+		// Only literal urls can be checked, concatenations are built at runtime.
+		if literal, ok := field.Value.(*ast.StringExpr); ok && key.Value == "url" && s.entry == "video" {
+			if url := literal.Value; !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+				v.errorf(field.Value.Line(), "video url %q is not an http(s) link", url)
+			}
+		}
+
+		if key.Value == "subtitles" {
+			v.checkSubtitles(field.Value.(*ast.TableExpr))
+		}
+	}
+
+	if dynamic {
+		return
+	}
+
+	for _, name := range s.required {
+		if !present[name] {
+			v.errorf(entry.Line(), "%s is missing %q and will be skipped", s.entry, name)
+		}
+	}
+}
+
+func (v *validator) checkSubtitles(list *ast.TableExpr) {
+	for _, field := range list.Fields {
+		subtitle, ok := field.Value.(*ast.TableExpr)
+		if !ok {
+			continue
+		}
+
+		hasURL := lo.SomeBy(subtitle.Fields, func(f *ast.Field) bool {
+			key, ok := f.Key.(*ast.StringExpr)
+			return !ok || key.Value == "url"
+		})
+		if !hasURL {
+			v.errorf(subtitle.Line(), "subtitle is missing \"url\" and will be skipped")
+		}
+	}
+}
+
+// kindOf returns the type of a literal expression, or kindUnknown
+// for expressions whose value is only known at runtime.
+func kindOf(expr ast.Expr) luaKind {
+	switch expr.(type) {
+	case *ast.StringExpr, *ast.StringConcatOpExpr:
+		return kindString
+	case *ast.NumberExpr, *ast.ArithmeticOpExpr, *ast.UnaryMinusOpExpr, *ast.UnaryLenOpExpr:
+		return kindNumber
+	case *ast.TableExpr:
+		return kindTable
+	case *ast.TrueExpr, *ast.FalseExpr, *ast.NilExpr, *ast.FunctionExpr, *ast.RelationalOpExpr, *ast.UnaryNotOpExpr:
+		return kindOtherLiteral
+	}
+
+	return kindUnknown
+}
+
+// accepts reports whether a field declared as want may hold a value of kind got.
+func accepts(want, got luaKind) bool {
+	switch want {
+	case kindNumber:
+		return got == kindNumber || got == kindString
+	case kindList:
+		return got == kindString || got == kindTable
+	}
+
+	return want == got
+}
+
+// describe names the type of a literal expression for messages.
+func describe(expr ast.Expr) string {
+	switch expr.(type) {
+	case *ast.StringExpr, *ast.StringConcatOpExpr:
+		return "a string"
+	case *ast.NumberExpr, *ast.ArithmeticOpExpr, *ast.UnaryMinusOpExpr, *ast.UnaryLenOpExpr:
+		return "a number"
+	case *ast.TableExpr:
+		return "a table"
+	case *ast.NilExpr:
+		return "nil"
+	case *ast.FunctionExpr:
+		return "a function"
+	}
+
+	return "a boolean"
+}
+
+func exprAt(exprs []ast.Expr, i int) ast.Expr {
+	if i < len(exprs) {
+		return exprs[i]
+	}
+	return nil
+}
+
+func isTableInsert(call *ast.FuncCallExpr) bool {
+	get, ok := call.Func.(*ast.AttrGetExpr)
+	if !ok {
+		return false
+	}
+
+	object, ok := get.Object.(*ast.IdentExpr)
+	key, isString := get.Key.(*ast.StringExpr)
+	return ok && isString && object.Value == "table" && key.Value == "insert"
+}
+
+// walkStmts calls visit for every statement in stmts and in the blocks nested in them,
+// including the bodies of functions defined inside, with depth counting how deep
+// those functions are nested.
+func walkStmts(stmts []ast.Stmt, depth int, visit func(stmt ast.Stmt, depth int)) {
+	for _, stmt := range stmts {
+		visit(stmt, depth)
+
+		var exprs []ast.Expr
+		switch stmt := stmt.(type) {
+		case *ast.AssignStmt:
+			exprs = append(append([]ast.Expr{}, stmt.Lhs...), stmt.Rhs...)
+		case *ast.LocalAssignStmt:
+			exprs = stmt.Exprs
+		case *ast.FuncCallStmt:
+			exprs = []ast.Expr{stmt.Expr}
+		case *ast.ReturnStmt:
+			exprs = stmt.Exprs
+		case *ast.FuncDefStmt:
+			walkStmts(stmt.Func.Stmts, depth+1, visit)
+		case *ast.DoBlockStmt:
+			walkStmts(stmt.Stmts, depth, visit)
+		case *ast.WhileStmt:
+			exprs = []ast.Expr{stmt.Condition}
+			walkStmts(stmt.Stmts, depth, visit)
+		case *ast.RepeatStmt:
+			exprs = []ast.Expr{stmt.Condition}
+			walkStmts(stmt.Stmts, depth, visit)
+		case *ast.IfStmt:
+			exprs = []ast.Expr{stmt.Condition}
+			walkStmts(stmt.Then, depth, visit)
+			walkStmts(stmt.Else, depth, visit)
+		case *ast.NumberForStmt:
+			exprs = []ast.Expr{stmt.Init, stmt.Limit, stmt.Step}
+			walkStmts(stmt.Stmts, depth, visit)
+		case *ast.GenericForStmt:
+			exprs = stmt.Exprs
+			walkStmts(stmt.Stmts, depth, visit)
+		}
+
+		for _, expr := range exprs {
+			walkFunctions(expr, depth, visit)
+		}
+	}
+}
+
+// walkFunctions walks the bodies of the functions defined within expr.
+func walkFunctions(expr ast.Expr, depth int, visit func(stmt ast.Stmt, depth int)) {
+	var children []ast.Expr
+	switch expr := expr.(type) {
+	case *ast.FunctionExpr:
+		walkStmts(expr.Stmts, depth+1, visit)
+	case *ast.FuncCallExpr:
+		children = append([]ast.Expr{expr.Func, expr.Receiver}, expr.Args...)
+	case *ast.TableExpr:
+		for _, field := range expr.Fields {
+			children = append(children, field.Key, field.Value)
+		}
+	case *ast.AttrGetExpr:
+		children = []ast.Expr{expr.Object, expr.Key}
+	case *ast.LogicalOpExpr:
+		children = []ast.Expr{expr.Lhs, expr.Rhs}
+	}
+
+	for _, child := range children {
+		if child != nil {
+			walkFunctions(child, depth, visit)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package custom
+
+import (
+	"testing"
+
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/samber/lo"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidate(t *testing.T) {
+	Convey("Given scripts to validate", t, func() {
+		filesystem.SetMemMapFs()
+
+		validate := func(script string) []Diagnostic {
+			So(filesystem.API().WriteFile("script.lua", []byte(script), 0o644), ShouldBeNil)
+			diagnostics, err := Validate("script.lua")
+			So(err, ShouldBeNil)
+			return diagnostics
+		}
+
+		errors := func(diagnostics []Diagnostic) []string {
+			return lo.FilterMap(diagnostics, func(d Diagnostic, _ int) (string, bool) {
+				return d.String(), !d.Warning
+			})
+		}
+
+		Convey("A complete script passes", func() {
+			diagnostics := validate(`-- @name ok
+function SearchAnimes(query)
+	local animes = {}
+	for _, item in ipairs(items(query)) do
+		table.insert(animes, { name = item.title, url = item.href })
+	end
+	return animes
+end
+function AnimeEpisodes(anime)
+	return { { name = "Episode 1", url = anime.url .. "/1", number = "1" } }
+end
+function EpisodeVideos(episode)
+	return { { url = "https://cdn.example.com/1.m3u8", headers = { Referer = episode.url } } }
+end`)
+			So(diagnostics, ShouldBeEmpty)
+		})
+
+		Convey("Syntax errors are reported with their line", func() {
+			So(errors(validate("function SearchAnimes()\n\treturn {\nend\n")), ShouldResemble, []string{
+				"line 3: error: syntax error near 'end'",
+			})
+		})
+
+		Convey("Missing and local functions are reported", func() {
+			So(errors(validate(`function SearchAnimes() return {} end
+local function AnimeEpisodes() return {} end`)), ShouldResemble, []string{
+				"error: function AnimeEpisodes is required but not defined",
+				"error: function EpisodeVideos is required but not defined",
+				"line 2: error: function AnimeEpisodes must be global, remove \"local\"",
+			})
+		})
+
+		Convey("Returned tables are checked against the translator", func() {
+			diagnostics := validate(`-- @version 1.0
+function SearchAnimes()
+	local list = {}
+	list[#list + 1] = { name = "x", titel = "y" }
+	return list
+end
+function AnimeEpisodes() return "episodes" end
+function EpisodeVideos()
+	return { { url = "ftp://example.com", headers = "Referer" } }
+end`)
+			So(errors(diagnostics), ShouldResemble, []string{
+				"line 1: error: invalid version \"1.0\", expected major.minor.patch",
+				"line 4: error: anime is missing \"url\" and will be skipped",
+				"line 7: error: AnimeEpisodes must return a list of episodes, not a string",
+				"line 9: error: video url \"ftp://example.com\" is not an http(s) link",
+				"line 9: error: video field \"headers\" must be a table, not a string",
+			})
+			So(diagnostics, ShouldContain, Diagnostic{Line: 4, Message: "anime field \"titel\" is not read by anisan", Warning: true})
+		})
+
+		Convey("Urls built at runtime are accepted", func() {
+			diagnostics := validate(`function SearchAnimes() return {} end
+function AnimeEpisodes() return {} end
+function EpisodeVideos(e)
+	return { { url = "https://example.com" .. e.url } }
+end`)
+			So(errors(diagnostics), ShouldBeEmpty)
+		})
+	})
+}
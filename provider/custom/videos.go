@@ -2,15 +2,23 @@
 package custom
 
 import (
+	"context"
+
 	"github.com/anisan-cli/anisan/constant"
 	"github.com/anisan-cli/anisan/source"
 	lua "github.com/yuin/gopher-lua"
 )
 
 func (s *luaSource) VideosOf(episode *source.Episode) ([]*source.Video, error) {
+	return s.VideosOfContext(context.Background(), episode)
+}
+
+func (s *luaSource) VideosOfContext(ctx context.Context, episode *source.Episode) ([]*source.Video, error) {
 	// No caching for videos (links expire)
 
-	val, err := s.call(constant.EpisodeVideosFn, lua.LTTable, episodeToTable(s.state, episode))
+	val, err := s.call(ctx, constant.EpisodeVideosFn, lua.LTTable, func(L *lua.LState) []lua.LValue {
+		return []lua.LValue{episodeToTable(L, episode)}
+	})
 	if err != nil {
 		return nil, err
 	}
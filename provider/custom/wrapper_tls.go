@@ -23,12 +23,14 @@
 //
 //	http_tls.get(url)              → returns body string
 //	http_tls.get(url, headers_tbl) → returns body string with custom headers
-//	http_tls.request(options_tbl)  → returns {status, body, headers}
+//	http_tls.request(options_tbl)  → returns {status, body}
+//	http_tls.batch(requests_tbl)   → returns a result for each request, in order
 package custom
 
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -43,24 +45,69 @@ import (
 	"golang.org/x/net/http2"
 )
 
-const httpTimeout = 30 * time.Second
+const (
+	httpTimeout  = 30 * time.Second
+	maxRedirects = 10
+
+	// batchConcurrency is how many requests of a batch are in flight at once, unless the script asks for fewer.
+	batchConcurrency = 8
+)
+
+// urlPolicy decides whether a script may request a URL. A nil policy allows any URL.
+type urlPolicy func(rawURL string) error
+
+// tlsClient is what the http_tls module of a VM sends its requests through.
+type tlsClient struct {
+	allow    urlPolicy // Rejects URLs the script may not request
+	cassette *Cassette // Records or replays every exchange, if set
+}
+
+// storeOf returns the store of the named source, the one of the cassette if there is one.
+func (c tlsClient) storeOf(name string) Store {
+	if c.cassette != nil {
+		return c.cassette.storeOf(name)
+	}
+	return StoreOf(name)
+}
+
+// do performs the request, going through the cassette if there is one.
+func (c tlsClient) do(ctx context.Context, method, rawURL string, headers map[string]string, body string) (string, int, error) {
+	if c.allow != nil {
+		if err := c.allow(rawURL); err != nil {
+			return "", 0, err
+		}
+	}
+
+	live := func() (string, int, error) {
+		return doTLSRequest(ctx, c.allow, method, rawURL, headers, body)
+	}
+
+	if c.cassette == nil {
+		return live()
+	}
+
+	return c.cassette.do(ctx, method, rawURL, body, live)
+}
 
 // registerTLSClient injects the "http_tls" global module into the Lua state.
 // This is called during source loading in loader.go.
-func registerTLSClient(L *lua.LState) {
+func registerTLSClient(L *lua.LState, client tlsClient) {
 	mod := L.NewTable()
 
 	// http_tls.get(url [, headers_table]) → body_string
-	L.SetField(mod, "get", L.NewFunction(httpTLSGet))
+	L.SetField(mod, "get", L.NewFunction(func(L *lua.LState) int { return httpTLSGet(L, client) }))
 
-	// http_tls.request({method, url, headers, body}) → {status, body, headers}
-	L.SetField(mod, "request", L.NewFunction(httpTLSRequest))
+	// http_tls.request({method, url, headers, body}) → {status, body}
+	L.SetField(mod, "request", L.NewFunction(func(L *lua.LState) int { return httpTLSRequest(L, client) }))
+
+	// http_tls.batch({url or {method, url, headers, body}, ...} [, concurrency]) → {{status, body} or {error}, ...}
+	L.SetField(mod, "batch", L.NewFunction(func(L *lua.LState) int { return httpTLSBatch(L, client) }))
 
 	L.SetGlobal("http_tls", mod)
 }
 
 // httpTLSGet implements http_tls.get(url [, headers]) → body string
-func httpTLSGet(L *lua.LState) int {
+func httpTLSGet(L *lua.LState, client tlsClient) int {
 	url := L.CheckString(1)
 	headersTable := L.OptTable(2, nil)
 
@@ -71,7 +118,7 @@ func httpTLSGet(L *lua.LState) int {
 		})
 	}
 
-	body, _, err := doTLSRequest("GET", url, headers, "")
+	body, _, err := client.do(luaContext(L), "GET", url, headers, "")
 	if err != nil {
 		L.RaiseError("http_tls.get failed: %s", err.Error())
 		return 0
@@ -81,68 +128,162 @@ func httpTLSGet(L *lua.LState) int {
 	return 1
 }
 
-// httpTLSRequest implements http_tls.request(options) → {status, body, headers}
-func httpTLSRequest(L *lua.LState) int {
-	opts := L.CheckTable(1)
+// tlsRequest is a request read from the options table of http_tls.request.
+type tlsRequest struct {
+	method  string
+	url     string
+	headers map[string]string
+	body    string
+	cache   bool // Whether a successful response is cached and reused
+}
 
-	method := getStringField(opts, "method", "GET")
-	url := getStringField(opts, "url", "")
-	reqBody := getStringField(opts, "body", "")
+// tlsResponse is the outcome of a request, as cached.
+type tlsResponse struct {
+	Status int    `json:"status"`
+	Body   string `json:"body"`
+}
 
-	if url == "" {
-		L.RaiseError("http_tls.request: url is required")
-		return 0
+// readRequest reads the request described by the options table {method, url, headers, body, cache}.
+func readRequest(opts *lua.LTable, client tlsClient) tlsRequest {
+	req := tlsRequest{
+		method:  getStringField(opts, "method", "GET"),
+		url:     getStringField(opts, "url", ""),
+		body:    getStringField(opts, "body", ""),
+		headers: make(map[string]string),
 	}
 
-	shouldCache := false
-	if cacheVal := opts.RawGetString("cache"); cacheVal != lua.LNil {
-		shouldCache = lua.LVAsBool(cacheVal)
+	// Cached responses would bypass the cassette.
+	if cacheVal := opts.RawGetString("cache"); cacheVal != lua.LNil && client.cassette == nil {
+		req.cache = lua.LVAsBool(cacheVal)
 	}
 
-	headers := make(map[string]string)
-	headersTbl := opts.RawGetString("headers")
-	if tbl, ok := headersTbl.(*lua.LTable); ok {
+	if tbl, ok := opts.RawGetString("headers").(*lua.LTable); ok {
 		tbl.ForEach(func(k, v lua.LValue) {
-			headers[k.String()] = v.String()
+			req.headers[k.String()] = v.String()
 		})
 	}
 
-	type tlsCacheEntry struct {
-		Status int    `json:"status"`
-		Body   string `json:"body"`
-	}
+	return req
+}
 
+// send performs the request, answering from the cache when it may.
+// It does not touch the Lua state, so that batches can send from several goroutines.
+func (c tlsClient) send(ctx context.Context, req tlsRequest) (tlsResponse, error) {
 	var cacheKey string
-	if shouldCache {
-		cacheKey = cache.GenerateKey(url+reqBody, method)
-		var entry tlsCacheEntry
+	if req.cache {
+		cacheKey = cache.GenerateKey(req.url+req.body, req.method)
+		var entry tlsResponse
 		if cache.Read(cacheKey, &entry) {
-			result := L.NewTable()
-			L.SetField(result, "status", lua.LNumber(entry.Status))
-			L.SetField(result, "body", lua.LString(entry.Body))
-			L.Push(result)
-			return 1
+			return entry, nil
 		}
 	}
 
-	respBody, statusCode, err := doTLSRequest(method, url, headers, reqBody)
+	body, status, err := c.do(ctx, req.method, req.url, req.headers, req.body)
+	if err != nil {
+		return tlsResponse{}, err
+	}
+
+	response := tlsResponse{Status: status, Body: body}
+	if req.cache && status == 200 {
+		_ = cache.Write(cacheKey, response)
+	}
+
+	return response, nil
+}
+
+// responseTable converts the response to the {status, body} table returned to scripts.
+func responseTable(L *lua.LState, response tlsResponse) *lua.LTable {
+	result := L.NewTable()
+	L.SetField(result, "status", lua.LNumber(response.Status))
+	L.SetField(result, "body", lua.LString(response.Body))
+	return result
+}
+
+// httpTLSRequest implements http_tls.request(options) → {status, body}
+func httpTLSRequest(L *lua.LState, client tlsClient) int {
+	req := readRequest(L.CheckTable(1), client)
+	if req.url == "" {
+		L.RaiseError("http_tls.request: url is required")
+		return 0
+	}
+
+	response, err := client.send(luaContext(L), req)
 	if err != nil {
 		L.RaiseError("http_tls.request failed: %s", err.Error())
 		return 0
 	}
 
-	if shouldCache && statusCode == 200 {
-		entry := tlsCacheEntry{
-			Status: statusCode,
-			Body:   respBody,
+	L.Push(responseTable(L, response))
+	return 1
+}
+
+// httpTLSBatch implements http_tls.batch(requests [, concurrency]) → results
+//
+// Every request is a url or an options table as taken by http_tls.request. They are sent
+// concurrently, at most concurrency at a time, and the results come back in the same order:
+// {status, body} for a response, {error = message} for a request that failed. Failures are not raised.
+func httpTLSBatch(L *lua.LState, client tlsClient) int {
+	list := L.CheckTable(1)
+	concurrency := L.OptInt(2, batchConcurrency)
+	if concurrency < 1 {
+		L.ArgError(2, "concurrency must be positive")
+		return 0
+	}
+	concurrency = min(concurrency, batchConcurrency)
+
+	// The Lua state is not safe for concurrent use: requests are read before
+	// the goroutines start and results are converted once they are done.
+	requests := make([]tlsRequest, list.Len())
+	for i := range requests {
+		switch value := list.RawGetInt(i + 1).(type) {
+		case lua.LString:
+			requests[i] = tlsRequest{method: "GET", url: string(value)}
+		case *lua.LTable:
+			requests[i] = readRequest(value, client)
+		default:
+			L.ArgError(1, fmt.Sprintf("request %d must be a url or a table, got %s", i+1, value.Type()))
+			return 0
 		}
-		_ = cache.Write(cacheKey, entry)
 	}
 
-	result := L.NewTable()
-	L.SetField(result, "status", lua.LNumber(statusCode))
-	L.SetField(result, "body", lua.LString(respBody))
-	L.Push(result)
+	var (
+		ctx       = luaContext(L)
+		responses = make([]tlsResponse, len(requests))
+		errs      = make([]error, len(requests))
+		slots     = make(chan struct{}, concurrency)
+		wg        sync.WaitGroup
+	)
+
+	for i, req := range requests {
+		if req.url == "" {
+			errs[i] = errors.New("url is required")
+			continue
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, req tlsRequest) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			responses[i], errs[i] = client.send(ctx, req)
+		}(i, req)
+	}
+	wg.Wait()
+
+	results := L.NewTable()
+	for i, response := range responses {
+		if errs[i] != nil {
+			failed := L.NewTable()
+			L.SetField(failed, "error", lua.LString(errs[i].Error()))
+			results.Append(failed)
+			continue
+		}
+		results.Append(responseTable(L, response))
+	}
+
+	L.Push(results)
 	return 1
 }
 
@@ -155,6 +296,21 @@ func getStringField(tbl *lua.LTable, key string, def string) string {
 	return val.String()
 }
 
+// luaContext returns the context of the Lua call in progress, so that requests
+// are aborted together with the source call that issued them.
+// Requests poll it outside of the VM, so they get the context under the meter
+// and do not count as instructions.
+func luaContext(L *lua.LState) context.Context {
+	switch ctx := L.Context().(type) {
+	case nil:
+		return context.Background()
+	case *meter:
+		return ctx.Context
+	default:
+		return ctx
+	}
+}
+
 // h2Transport is a shared HTTP/2 transport for servers that negotiate h2.
 var (
 	h2Transport     *http2.Transport
@@ -183,14 +339,31 @@ var h1Transport = &http.Transport{
 // doTLSRequest performs an HTTP request with Chrome TLS fingerprint spoofing.
 // It automatically handles both H2 and HTTP/1.1 by pre-connecting to determine
 // the negotiated protocol, then routing to the appropriate transport.
+// Requests and redirects to URLs the policy rejects fail.
 // Returns (body, statusCode, error).
-func doTLSRequest(method, rawURL string, headers map[string]string, body string) (string, int, error) {
+func doTLSRequest(ctx context.Context, allow urlPolicy, method, rawURL string, headers map[string]string, body string) (string, int, error) {
+	if allow != nil {
+		if err := allow(rawURL); err != nil {
+			return "", 0, err
+		}
+	}
+
+	checkRedirect := func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if allow != nil {
+			return allow(req.URL.String())
+		}
+		return nil
+	}
+
 	var reqBody io.Reader
 	if body != "" {
 		reqBody = strings.NewReader(body)
 	}
 
-	req, err := http.NewRequest(method, rawURL, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reqBody)
 	if err != nil {
 		return "", 0, fmt.Errorf("create request: %w", err)
 	}
@@ -207,22 +380,28 @@ func doTLSRequest(method, rawURL string, headers map[string]string, body string)
 
 	// Try H2 transport first (works for allanime.day and other modern servers)
 	client := &http.Client{
-		Timeout:   httpTimeout,
-		Transport: getH2Transport(),
+		Timeout:       httpTimeout,
+		Transport:     getH2Transport(),
+		CheckRedirect: checkRedirect,
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", 0, ctx.Err()
+		}
+
 		// If H2 fails, fallback to H1 transport
 		if body != "" {
 			reqBody = strings.NewReader(body) // reset reader
 		}
-		req2, _ := http.NewRequest(method, rawURL, reqBody)
+		req2, _ := http.NewRequestWithContext(ctx, method, rawURL, reqBody)
 		req2.Header = req.Header
 
 		h1Client := &http.Client{
-			Timeout:   httpTimeout,
-			Transport: h1Transport,
+			Timeout:       httpTimeout,
+			Transport:     h1Transport,
+			CheckRedirect: checkRedirect,
 		}
 		resp, err = h1Client.Do(req2)
 		if err != nil {
@@ -259,7 +438,7 @@ func dialTLS(ctx context.Context, network, addr string) (net.Conn, error) {
 		MinVersion:         tls.VersionTLS12,
 	}, utls.HelloChrome_120)
 
-	if err := tlsConn.Handshake(); err != nil {
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
 		conn.Close()
 		return nil, fmt.Errorf("tls handshake: %w", err)
 	}
@@ -287,7 +466,7 @@ func dialTLSH1(ctx context.Context, network, addr string) (net.Conn, error) {
 		NextProtos:         []string{"http/1.1"},
 	}, utls.HelloChrome_120)
 
-	if err := tlsConn.Handshake(); err != nil {
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
 		conn.Close()
 		return nil, fmt.Errorf("tls handshake: %w", err)
 	}
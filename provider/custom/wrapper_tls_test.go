@@ -0,0 +1,82 @@
+package custom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestBatch(t *testing.T) {
+	Convey("Given a VM and a slow server", t, func() {
+		var inFlight, peak atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				old := peak.Load()
+				if current <= old || peak.CompareAndSwap(old, current) {
+					break
+				}
+			}
+
+			time.Sleep(50 * time.Millisecond)
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+			_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + " " + r.Header.Get("X-Server")))
+		}))
+		defer server.Close()
+
+		L := newSandboxState(Permissions{Hosts: []string{"127.0.0.1"}}, lua.Options{}, nil)
+		defer L.Close()
+		L.SetGlobal("target", lua.LString(server.URL))
+
+		Convey("When several requests are sent at once", func() {
+			err := L.DoString(`
+				results = http_tls.batch({
+					target .. "/one",
+					{ method = "POST", url = target .. "/two", headers = { ["X-Server"] = "vidstream" } },
+					"https://forbidden.example/three",
+					target .. "/missing",
+					{ url = "" },
+				}, 3)`)
+			So(err, ShouldBeNil)
+
+			results := L.GetGlobal("results").(*lua.LTable)
+			field := func(i int, name string) string {
+				return results.RawGetInt(i).(*lua.LTable).RawGetString(name).String()
+			}
+
+			Convey("Then the results come back in order", func() {
+				So(results.Len(), ShouldEqual, 5)
+				So(field(1, "body"), ShouldEqual, "GET /one ")
+				So(field(2, "body"), ShouldEqual, "POST /two vidstream")
+				So(field(4, "status"), ShouldEqual, "404")
+			})
+
+			Convey("Then failed requests hold their error instead of raising", func() {
+				So(field(3, "error"), ShouldContainSubstring, "is not permitted")
+				So(field(5, "error"), ShouldContainSubstring, "url is required")
+				So(field(1, "error"), ShouldEqual, "nil")
+			})
+
+			Convey("Then they run concurrently, within the limit", func() {
+				So(peak.Load(), ShouldBeBetweenOrEqual, 2, 3)
+			})
+		})
+
+		Convey("When a request is neither a url nor a table", func() {
+			err := L.DoString(`http_tls.batch({ 42 })`)
+
+			Convey("Then the call fails", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "request 1 must be a url or a table")
+			})
+		})
+	})
+}
@@ -1,8 +1,38 @@
 package provider
 
+import (
+	"github.com/anisan-cli/anisan/key"
+	"github.com/anisan-cli/anisan/provider/custom"
+	"github.com/anisan-cli/anisan/provider/jellyfin"
+	"github.com/anisan-cli/anisan/provider/local"
+	"github.com/anisan-cli/anisan/source"
+	"github.com/spf13/viper"
+)
+
 const CustomProviderExtension = ".lua"
 
 func init() {
-	// Builtin providers are removed in favor of Lua scrapers
-	// Use generic.Configuration if we ever decide to add a Go-based provider back
+	Register(&Provider{
+		ID:           local.ID,
+		Name:         local.Name,
+		Capabilities: []string{custom.CapabilitySearch, custom.CapabilityEpisodes, custom.CapabilityVideos},
+		CreateSource: func() (source.Source, error) {
+			return local.New(viper.GetStringSlice(key.SourcesLibrary)), nil
+		},
+	})
+
+	Register(&Provider{
+		ID:           jellyfin.ID,
+		Name:         jellyfin.Name,
+		Capabilities: []string{custom.CapabilitySearch, custom.CapabilityEpisodes, custom.CapabilityVideos},
+		CreateSource: func() (source.Source, error) {
+			return jellyfin.New(jellyfin.Config{
+				URL:                  viper.GetString(key.SourcesJellyfinURL),
+				APIKey:               viper.GetString(key.SourcesJellyfinAPIKey),
+				UserID:               viper.GetString(key.SourcesJellyfinUserID),
+				ReportProgress:       viper.GetBool(key.SourcesJellyfinReportProgress),
+				CompletionPercentage: viper.GetFloat64(key.PlayerCompletionPercentage),
+			})
+		},
+	})
 }
@@ -0,0 +1,115 @@
+package jellyfin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// item is the part of a Jellyfin BaseItemDto the source reads.
+type item struct {
+	ID                string `json:"Id"`
+	Name              string
+	Type              string
+	Overview          string
+	Genres            []string
+	IndexNumber       int
+	ParentIndexNumber int
+	SeriesID          string `json:"SeriesId"`
+	SeasonID          string `json:"SeasonId"`
+	RunTimeTicks      int64
+	MediaSources      []mediaSource
+}
+
+type mediaSource struct {
+	ID           string `json:"Id"`
+	Container    string
+	MediaStreams []mediaStream
+}
+
+type mediaStream struct {
+	Type                 string
+	Index                int
+	Codec                string
+	Language             string
+	DisplayTitle         string
+	Width                int
+	Height               int
+	BitRate              int
+	IsExternal           bool
+	IsTextSubtitleStream bool
+}
+
+type itemsResponse struct {
+	Items []item
+}
+
+// authorization is the header Jellyfin reads the API key from. It has no commas,
+// so that players taking a comma separated list of headers pass it on intact.
+func (s *Source) authorization() map[string]string {
+	return map[string]string{"Authorization": fmt.Sprintf(`MediaBrowser Token="%s"`, s.config.APIKey)}
+}
+
+// endpoint returns the URL of the API path with the query, scoped to the configured user if any.
+func (s *Source) endpoint(path string, query url.Values) string {
+	if query == nil {
+		query = url.Values{}
+	}
+	if s.config.UserID != "" && !query.Has("userId") {
+		query.Set("userId", s.config.UserID)
+	}
+
+	if encoded := query.Encode(); encoded != "" {
+		return s.base + path + "?" + encoded
+	}
+	return s.base + path
+}
+
+func (s *Source) get(ctx context.Context, path string, query url.Values, out any) error {
+	return s.do(ctx, http.MethodGet, s.endpoint(path, query), nil, out)
+}
+
+func (s *Source) post(ctx context.Context, path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	return s.do(ctx, http.MethodPost, s.base+path, data, nil)
+}
+
+func (s *Source) do(ctx context.Context, method, target string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for name, value := range s.authorization() {
+		req.Header.Set(name, value)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("jellyfin: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("jellyfin: the server refused the API key")
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("jellyfin: %s %s returned %d", method, req.URL.Path, resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
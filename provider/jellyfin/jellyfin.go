@@ -0,0 +1,299 @@
+// Package jellyfin implements the built-in source that lists the series of a Jellyfin server.
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/anisan-cli/anisan/key"
+	"github.com/anisan-cli/anisan/network"
+	"github.com/anisan-cli/anisan/source"
+	"github.com/samber/lo"
+)
+
+const (
+	Name = "jellyfin"
+	ID   = Name + " builtin"
+)
+
+// Config locates the server and tells how to report progress to it.
+type Config struct {
+	// URL of the server, e.g. http://localhost:8096.
+	URL    string
+	APIKey string
+	// UserID scopes requests to a user. Progress is reported to this user.
+	UserID string
+	// ReportProgress enables ReportProgress.
+	ReportProgress bool
+	// CompletionPercentage is the progress from which episodes count as played.
+	CompletionPercentage float64
+}
+
+// Source lists the series of a Jellyfin server. Series with several seasons are listed
+// once per season, as trackers list them. Videos play directly from the server,
+// with a transcoded HLS stream as a fallback.
+type Source struct {
+	config Config
+	base   string
+	client *http.Client
+}
+
+// New returns a source of the server in config.
+func New(config Config) (*Source, error) {
+	if config.URL == "" || config.APIKey == "" {
+		return nil, fmt.Errorf("jellyfin is not configured, set %s and %s", key.SourcesJellyfinURL, key.SourcesJellyfinAPIKey)
+	}
+
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid jellyfin server address %q, expected http(s)://host[:port]", config.URL)
+	}
+
+	return &Source{
+		config: config,
+		base:   strings.TrimRight(config.URL, "/"),
+		client: network.Client,
+	}, nil
+}
+
+func (s *Source) Name() string {
+	return Name
+}
+
+func (s *Source) ID() string {
+	return ID
+}
+
+func (s *Source) Search(query string) ([]*source.Anime, error) {
+	return s.SearchContext(context.Background(), query)
+}
+
+func (s *Source) EpisodesOf(anime *source.Anime) ([]*source.Episode, error) {
+	return s.EpisodesOfContext(context.Background(), anime)
+}
+
+func (s *Source) VideosOf(episode *source.Episode) ([]*source.Video, error) {
+	return s.VideosOfContext(context.Background(), episode)
+}
+
+func (s *Source) SearchContext(ctx context.Context, query string) ([]*source.Anime, error) {
+	var series itemsResponse
+	err := s.get(ctx, "/Items", url.Values{
+		"searchTerm":       {query},
+		"includeItemTypes": {"Series"},
+		"recursive":        {"true"},
+		"fields":           {"Overview,Genres"},
+		"limit":            {"50"},
+	}, &series)
+	if err != nil {
+		return nil, err
+	}
+
+	var animes []*source.Anime
+	for _, show := range series.Items {
+		var seasons itemsResponse
+		if err := s.get(ctx, "/Shows/"+show.ID+"/Seasons", nil, &seasons); err != nil {
+			return nil, err
+		}
+
+		if len(seasons.Items) <= 1 {
+			animes = append(animes, s.anime(show, nil))
+			continue
+		}
+
+		for _, season := range seasons.Items {
+			animes = append(animes, s.anime(show, &season))
+		}
+	}
+
+	for i, anime := range animes {
+		anime.Index = uint16(i + 1)
+	}
+
+	return animes, nil
+}
+
+// anime describes a series, or a season of it.
+func (s *Source) anime(show item, season *item) *source.Anime {
+	anime := &source.Anime{
+		Name:   show.Name,
+		URL:    s.base + "/web/#/details?id=" + show.ID,
+		ID:     show.ID,
+		Source: s,
+	}
+
+	if season != nil {
+		anime.ID = show.ID + "/" + season.ID
+		anime.URL = s.base + "/web/#/details?id=" + season.ID
+		switch {
+		case season.IndexNumber == 0:
+			anime.Name += " Specials"
+		case season.IndexNumber > 1:
+			anime.Name += " Season " + strconv.Itoa(season.IndexNumber)
+		}
+	}
+
+	anime.Metadata.Summary = show.Overview
+	anime.Metadata.Genres = show.Genres
+	anime.Metadata.Cover.ExtraLarge = s.base + "/Items/" + show.ID + "/Images/Primary"
+
+	return anime
+}
+
+func (s *Source) EpisodesOfContext(ctx context.Context, anime *source.Anime) ([]*source.Episode, error) {
+	seriesID, seasonID, _ := strings.Cut(anime.ID, "/")
+
+	query := url.Values{"fields": {"Overview"}}
+	if seasonID != "" {
+		query.Set("seasonId", seasonID)
+	}
+
+	var items itemsResponse
+	if err := s.get(ctx, "/Shows/"+url.PathEscape(seriesID)+"/Episodes", query, &items); err != nil {
+		return nil, err
+	}
+
+	episodes := make([]*source.Episode, 0, len(items.Items))
+	for _, it := range items.Items {
+		episode := &source.Episode{
+			ID:     it.ID,
+			Name:   episodeName(it),
+			URL:    s.base + "/web/#/details?id=" + it.ID,
+			Number: float64(it.IndexNumber),
+			Anime:  anime,
+		}
+		if it.ParentIndexNumber == 0 {
+			episode.Kind = source.KindSpecial
+		}
+
+		episodes = append(episodes, episode)
+	}
+
+	source.SortEpisodes(episodes)
+	for i, episode := range episodes {
+		episode.Index = uint16(i + 1)
+	}
+
+	anime.Episodes = episodes
+	return episodes, nil
+}
+
+func episodeName(it item) string {
+	if it.IndexNumber == 0 {
+		return it.Name
+	}
+
+	number := "Episode " + strconv.Itoa(it.IndexNumber)
+	if it.Name == "" || strings.HasPrefix(it.Name, "Episode ") {
+		return number
+	}
+
+	return number + " - " + it.Name
+}
+
+func (s *Source) VideosOfContext(ctx context.Context, episode *source.Episode) ([]*source.Video, error) {
+	var it item
+	if err := s.get(ctx, "/Items/"+url.PathEscape(episode.ID), url.Values{"fields": {"MediaSources"}}, &it); err != nil {
+		return nil, err
+	}
+
+	var videos []*source.Video
+	for _, media := range it.MediaSources {
+		picture, _ := lo.Find(media.MediaStreams, func(stream mediaStream) bool { return stream.Type == "Video" })
+		query := url.Values{"mediaSourceId": {media.ID}}
+
+		direct := s.video(picture)
+		query.Set("static", "true")
+		direct.URL = s.base + "/Videos/" + url.PathEscape(it.ID) + "/stream?" + query.Encode()
+		direct.Extension, _, _ = strings.Cut(media.Container, ",")
+		// Embedded subtitles play from the file itself
+		direct.Subtitles = s.subtitles(it.ID, media, true)
+
+		hls := s.video(picture)
+		query.Del("static")
+		hls.URL = s.base + "/Videos/" + url.PathEscape(it.ID) + "/master.m3u8?" + query.Encode()
+		hls.Extension = "m3u8"
+		hls.Subtitles = s.subtitles(it.ID, media, false)
+
+		videos = append(videos, direct, hls)
+	}
+
+	if len(videos) == 0 {
+		return nil, fmt.Errorf("jellyfin has no media for %s", episode.Name)
+	}
+
+	for i, video := range videos {
+		video.Index = uint16(i + 1)
+	}
+
+	return videos, nil
+}
+
+func (s *Source) video(picture mediaStream) *source.Video {
+	video := &source.Video{
+		Headers:   s.authorization(),
+		Width:     picture.Width,
+		Height:    picture.Height,
+		Bandwidth: picture.BitRate,
+	}
+	if picture.Height > 0 {
+		video.Quality = strconv.Itoa(picture.Height) + "p"
+	}
+
+	return video
+}
+
+// subtitles returns the text subtitle tracks of the media, only the external ones if externalOnly.
+func (s *Source) subtitles(itemID string, media mediaSource, externalOnly bool) []*source.Subtitle {
+	var subtitles []*source.Subtitle
+	for _, stream := range media.MediaStreams {
+		if stream.Type != "Subtitle" || !stream.IsTextSubtitleStream || (externalOnly && !stream.IsExternal) {
+			continue
+		}
+
+		format := strings.ToLower(stream.Codec)
+		switch format {
+		case "subrip":
+			format = "srt"
+		case "webvtt":
+			format = "vtt"
+		}
+
+		subtitles = append(subtitles, &source.Subtitle{
+			URL:      fmt.Sprintf("%s/Videos/%s/%s/Subtitles/%d/Stream.%s", s.base, url.PathEscape(itemID), url.PathEscape(media.ID), stream.Index, format),
+			Language: stream.Language,
+			Format:   format,
+			Label:    stream.DisplayTitle,
+		})
+	}
+
+	return subtitles
+}
+
+// ReportProgress saves the playback position of the episode for the configured user,
+// marking it played once it passes the completion percentage.
+func (s *Source) ReportProgress(ctx context.Context, episode *source.Episode, percentage float64) error {
+	if !s.config.ReportProgress || s.config.UserID == "" {
+		return nil
+	}
+
+	var it item
+	if err := s.get(ctx, "/Items/"+url.PathEscape(episode.ID), nil, &it); err != nil {
+		return err
+	}
+
+	played := s.config.CompletionPercentage > 0 && percentage >= s.config.CompletionPercentage
+	position := int64(float64(it.RunTimeTicks) * percentage / 100)
+	if played {
+		position = 0
+	}
+
+	return s.post(ctx, "/Users/"+url.PathEscape(s.config.UserID)+"/Items/"+url.PathEscape(episode.ID)+"/UserData", map[string]any{
+		"PlaybackPositionTicks": position,
+		"Played":                played,
+	})
+}
@@ -0,0 +1,149 @@
+package jellyfin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anisan-cli/anisan/source"
+	"github.com/samber/lo"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// stub serves canned responses of the Jellyfin API and records what was posted to it.
+func stub(posted map[string]map[string]any) *httptest.Server {
+	responses := map[string]string{
+		"/Items?Series": `{"Items": [
+			{"Id": "s1", "Name": "Frieren", "Overview": "An elf.", "Genres": ["Fantasy"]},
+			{"Id": "s2", "Name": "Mushishi"}
+		]}`,
+		"/Shows/s1/Seasons": `{"Items": [{"Id": "season1", "IndexNumber": 1}]}`,
+		"/Shows/s2/Seasons": `{"Items": [
+			{"Id": "sp", "IndexNumber": 0},
+			{"Id": "m1", "IndexNumber": 1},
+			{"Id": "m2", "IndexNumber": 2}
+		]}`,
+		"/Shows/s1/Episodes": `{"Items": [
+			{"Id": "e2", "Name": "Episode 2", "IndexNumber": 2, "ParentIndexNumber": 1},
+			{"Id": "e1", "Name": "The Journey's End", "IndexNumber": 1, "ParentIndexNumber": 1},
+			{"Id": "e0", "Name": "Recap", "IndexNumber": 1, "ParentIndexNumber": 0}
+		]}`,
+		"/Items/e1": `{"Id": "e1", "RunTimeTicks": 1000, "MediaSources": [{
+			"Id": "ms1", "Container": "mkv,webm",
+			"MediaStreams": [
+				{"Type": "Video", "Index": 0, "Width": 1920, "Height": 1080, "BitRate": 5000000},
+				{"Type": "Subtitle", "Index": 2, "Codec": "subrip", "Language": "eng", "DisplayTitle": "English", "IsExternal": true, "IsTextSubtitleStream": true},
+				{"Type": "Subtitle", "Index": 3, "Codec": "ass", "Language": "jpn", "IsTextSubtitleStream": true},
+				{"Type": "Subtitle", "Index": 4, "Codec": "PGSSUB"}
+			]
+		}]}`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != `MediaBrowser Token="secret"` {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			posted[r.URL.Path] = body
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		path := r.URL.Path
+		if path == "/Items" {
+			path += "?" + r.URL.Query().Get("includeItemTypes")
+		}
+
+		response, ok := responses[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, response)
+	}))
+}
+
+func TestSource(t *testing.T) {
+	Convey("Given a Jellyfin server", t, func() {
+		posted := make(map[string]map[string]any)
+		server := stub(posted)
+		defer server.Close()
+
+		s, err := New(Config{URL: server.URL + "/", APIKey: "secret", UserID: "u1", ReportProgress: true, CompletionPercentage: 80})
+		So(err, ShouldBeNil)
+
+		Convey("Series are listed once per season", func() {
+			animes, err := s.Search("frieren")
+			So(err, ShouldBeNil)
+			So(lo.Map(animes, func(a *source.Anime, _ int) string { return a.Name }), ShouldResemble, []string{
+				"Frieren", "Mushishi Specials", "Mushishi", "Mushishi Season 2",
+			})
+			So(animes[0].ID, ShouldEqual, "s1")
+			So(animes[3].ID, ShouldEqual, "s2/m2")
+			So(animes[0].Metadata.Summary, ShouldEqual, "An elf.")
+
+			Convey("Episodes are numbered, with season 0 as specials", func() {
+				episodes, err := s.EpisodesOf(animes[0])
+				So(err, ShouldBeNil)
+				So(lo.Map(episodes, func(e *source.Episode, _ int) string { return e.Name }), ShouldResemble, []string{
+					"Episode 1 - The Journey's End", "Episode 1 - Recap", "Episode 2",
+				})
+				So(episodes[1].Kind, ShouldEqual, source.KindSpecial)
+
+				Convey("Videos play directly with HLS as a fallback", func() {
+					videos, err := s.VideosOf(episodes[0])
+					So(err, ShouldBeNil)
+					So(videos, ShouldHaveLength, 2)
+
+					direct, hls := videos[0], videos[1]
+					So(direct.URL, ShouldEqual, server.URL+"/Videos/e1/stream?mediaSourceId=ms1&static=true")
+					So(direct.Extension, ShouldEqual, "mkv")
+					So(direct.Quality, ShouldEqual, "1080p")
+					So(direct.Headers, ShouldResemble, map[string]string{"Authorization": `MediaBrowser Token="secret"`})
+					So(direct.Subtitles, ShouldHaveLength, 1)
+					So(direct.Subtitles[0].URL, ShouldEqual, server.URL+"/Videos/e1/ms1/Subtitles/2/Stream.srt")
+
+					So(hls.URL, ShouldEqual, server.URL+"/Videos/e1/master.m3u8?mediaSourceId=ms1")
+					So(hls.Extension, ShouldEqual, "m3u8")
+					So(hls.Subtitles, ShouldHaveLength, 2)
+				})
+
+				Convey("Progress is reported to the user", func() {
+					So(source.ReportProgress(context.Background(), episodes[0], 50), ShouldBeNil)
+					So(posted["/Users/u1/Items/e1/UserData"], ShouldResemble, map[string]any{
+						"PlaybackPositionTicks": float64(500), "Played": false,
+					})
+
+					So(source.ReportProgress(context.Background(), episodes[0], 95), ShouldBeNil)
+					So(posted["/Users/u1/Items/e1/UserData"]["Played"], ShouldBeTrue)
+				})
+			})
+		})
+
+		Convey("A wrong API key is reported", func() {
+			s, err := New(Config{URL: server.URL, APIKey: "wrong"})
+			So(err, ShouldBeNil)
+
+			_, err = s.Search("frieren")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "refused the API key")
+		})
+	})
+
+	Convey("The server address and API key are required", t, func() {
+		_, err := New(Config{APIKey: "secret"})
+		So(err, ShouldNotBeNil)
+
+		_, err = New(Config{URL: "ftp://example.com", APIKey: "secret"})
+		So(err, ShouldNotBeNil)
+		So(strings.Contains(err.Error(), "invalid jellyfin server address"), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,320 @@
+// Package local implements the built-in source that lists downloaded video files.
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/key"
+	"github.com/anisan-cli/anisan/log"
+	"github.com/anisan-cli/anisan/source"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+)
+
+const (
+	Name = "local"
+	ID   = Name + " builtin"
+)
+
+var (
+	videoExtensions    = []string{".mkv", ".mp4", ".m4v", ".webm", ".avi", ".mov", ".wmv", ".flv", ".ts"}
+	subtitleExtensions = []string{".ass", ".ssa", ".srt", ".vtt"}
+)
+
+// Source lists the video files of the library directories, grouped into animes by their names.
+// Videos point at the files themselves, which players open directly.
+type Source struct {
+	dirs []string
+}
+
+// New returns a source of the video files under dirs.
+func New(dirs []string) *Source {
+	return &Source{dirs: dirs}
+}
+
+func (s *Source) Name() string {
+	return Name
+}
+
+func (s *Source) ID() string {
+	return ID
+}
+
+// Search returns the animes of the library whose names contain every word of the query.
+// An empty query lists the whole library.
+func (s *Source) Search(query string) ([]*source.Anime, error) {
+	shows, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	var animes []*source.Anime
+	for _, show := range shows {
+		name := strings.ToLower(show.name)
+		if !lo.EveryBy(words, func(word string) bool { return strings.Contains(name, word) }) {
+			continue
+		}
+
+		animes = append(animes, &source.Anime{
+			Name:   show.name,
+			URL:    show.dir,
+			ID:     show.key,
+			Index:  uint16(len(animes) + 1),
+			Source: s,
+		})
+	}
+
+	return animes, nil
+}
+
+// EpisodesOf lists one episode per number. Further files of the same episode,
+// e.g. other resolutions, are offered as videos of it.
+func (s *Source) EpisodesOf(anime *source.Anime) ([]*source.Episode, error) {
+	show, err := s.show(anime)
+	if err != nil {
+		return nil, err
+	}
+
+	var episodes []*source.Episode
+	seen := make(map[string]bool)
+	for _, f := range show.files {
+		if f.release.Number > 0 {
+			if seen[f.episodeKey()] {
+				continue
+			}
+			seen[f.episodeKey()] = true
+		}
+
+		episodes = append(episodes, &source.Episode{
+			ID:     f.path,
+			Name:   f.episodeName(),
+			URL:    f.path,
+			Number: f.release.Number,
+			Kind:   f.release.Kind,
+			Anime:  anime,
+		})
+	}
+
+	source.SortEpisodes(episodes)
+	for i, episode := range episodes {
+		episode.Index = uint16(i + 1)
+	}
+
+	anime.Episodes = episodes
+	return episodes, nil
+}
+
+// VideosOf returns every file of the episode along with the subtitle files next to them.
+func (s *Source) VideosOf(episode *source.Episode) ([]*source.Video, error) {
+	paths := []string{episode.URL}
+
+	if episode.Number > 0 && episode.Anime != nil {
+		if show, err := s.show(episode.Anime); err == nil {
+			episodeKey := file{release: Release{Number: episode.Number, Kind: episode.Kind}}.episodeKey()
+			paths = lo.FilterMap(show.files, func(f file, _ int) (string, bool) {
+				return f.path, f.episodeKey() == episodeKey
+			})
+		}
+	}
+
+	videos := make([]*source.Video, 0, len(paths))
+	for i, path := range paths {
+		if _, err := filesystem.API().Stat(path); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		release := ParseRelease(path)
+		video := &source.Video{
+			URL:       path,
+			Quality:   release.Resolution,
+			Extension: strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."),
+			Height:    resolution(release),
+			Index:     uint16(i + 1),
+			Subtitles: subtitlesOf(path),
+		}
+
+		videos = append(videos, video)
+	}
+
+	if len(videos) == 0 {
+		return nil, fmt.Errorf("%s is no longer in the library", episode.URL)
+	}
+
+	return videos, nil
+}
+
+// show is an anime of the library.
+type show struct {
+	key   string
+	name  string
+	dir   string
+	files []file
+}
+
+// file is a video file of the library.
+type file struct {
+	path    string
+	release Release
+}
+
+func (f file) episodeKey() string {
+	return string(f.release.Kind) + "\x00" + source.FormatEpisodeNumber(f.release.Number)
+}
+
+func (f file) episodeName() string {
+	if f.release.Number == 0 {
+		return strings.TrimSuffix(filepath.Base(f.path), filepath.Ext(f.path))
+	}
+
+	label := "Episode"
+	switch f.release.Kind {
+	case source.KindOVA:
+		label = "OVA"
+	case source.KindSpecial:
+		label = "Special"
+	case source.KindRecap:
+		label = "Recap"
+	case source.KindMovie:
+		label = "Movie"
+	}
+
+	return label + " " + source.FormatEpisodeNumber(f.release.Number)
+}
+
+func (s *Source) show(anime *source.Anime) (*show, error) {
+	shows, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	found, ok := lo.Find(shows, func(show *show) bool { return show.key == anime.ID })
+	if !ok {
+		return nil, fmt.Errorf("%s is no longer in the library", anime.Name)
+	}
+
+	return found, nil
+}
+
+// scan walks the library directories and groups the video files in them by anime.
+// Files whose names carry no title take the name of their directory.
+func (s *Source) scan() ([]*show, error) {
+	if len(s.dirs) == 0 {
+		return nil, fmt.Errorf("no library directories, add some to %s", key.SourcesLibrary)
+	}
+
+	shows := make(map[string]*show)
+	for _, dir := range s.dirs {
+		dir = expandHome(dir)
+		err := afero.Walk(filesystem.API(), dir, func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if strings.HasPrefix(info.Name(), ".") && path != dir {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if info.IsDir() || !lo.Contains(videoExtensions, strings.ToLower(filepath.Ext(path))) {
+				return nil
+			}
+
+			release := ParseRelease(path)
+			if release.Title == "" {
+				parent := parseDirectory(filepath.Dir(path))
+				release.Title = parent.Title
+				if release.Season == 0 {
+					release.Season = parent.Season
+				}
+			}
+
+			name := release.Name()
+			showKey := strings.ToLower(name)
+			if shows[showKey] == nil {
+				shows[showKey] = &show{key: showKey, name: name, dir: filepath.Dir(path)}
+			}
+			shows[showKey].files = append(shows[showKey].files, file{path: path, release: release})
+			return nil
+		})
+
+		if err != nil {
+			log.Warnf("local: skipping %s: %v", dir, err)
+		}
+	}
+
+	sorted := lo.Values(shows)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	for _, show := range sorted {
+		sort.SliceStable(show.files, func(i, j int) bool {
+			// Higher resolutions first, so that they become the episode URL
+			return resolution(show.files[i].release) > resolution(show.files[j].release)
+		})
+	}
+
+	return sorted, nil
+}
+
+// subtitlesOf returns the subtitle files named after the video at path,
+// e.g. "Title - 01.ass" or "Title - 01.en.srt".
+func subtitlesOf(path string) []*source.Subtitle {
+	dir := filepath.Dir(path)
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	entries, err := filesystem.API().ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var subtitles []*source.Subtitle
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !lo.Contains(subtitleExtensions, ext) {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if name != stem && !strings.HasPrefix(name, stem+".") {
+			continue
+		}
+
+		subtitles = append(subtitles, &source.Subtitle{
+			URL:      filepath.Join(dir, entry.Name()),
+			Language: strings.TrimPrefix(strings.TrimPrefix(name, stem), "."),
+			Format:   strings.TrimPrefix(ext, "."),
+		})
+	}
+
+	return subtitles
+}
+
+func resolution(r Release) int {
+	var height int
+	_, _ = fmt.Sscanf(r.Resolution, "%dp", &height)
+	return height
+}
+
+func expandHome(dir string) string {
+	if dir != "~" && !strings.HasPrefix(dir, "~"+string(filepath.Separator)) {
+		return dir
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return dir
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(dir, "~"))
+}
@@ -0,0 +1,67 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/source"
+	"github.com/samber/lo"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSource(t *testing.T) {
+	Convey("Given a library of downloaded episodes", t, func() {
+		filesystem.SetMemMapFs()
+		for _, path := range []string{
+			"/library/Frieren/[SubsPlease] Sousou no Frieren - 01 (1080p).mkv",
+			"/library/Frieren/[SubsPlease] Sousou no Frieren - 01 (720p).mkv",
+			"/library/Frieren/[SubsPlease] Sousou no Frieren - 01 (1080p).en.ass",
+			"/library/Frieren/[SubsPlease] Sousou no Frieren - 02 (1080p).mkv",
+			"/library/Frieren/[SubsPlease] Sousou no Frieren - 01.5 (1080p).mkv",
+			"/library/Mushishi Season 2/03.mp4",
+			"/library/Mushishi Season 2/notes.txt",
+			"/library/.trash/Other - 01.mkv",
+		} {
+			So(filesystem.API().WriteFile(path, nil, 0o644), ShouldBeNil)
+		}
+
+		s := New([]string{"/library"})
+
+		Convey("Files are grouped into animes", func() {
+			animes, err := s.Search("")
+			So(err, ShouldBeNil)
+			So(lo.Map(animes, func(a *source.Anime, _ int) string { return a.Name }), ShouldResemble, []string{
+				"Mushishi Season 2", "Sousou no Frieren",
+			})
+
+			animes, err = s.Search("frieren")
+			So(err, ShouldBeNil)
+			So(animes, ShouldHaveLength, 1)
+
+			Convey("With one episode per number", func() {
+				episodes, err := s.EpisodesOf(animes[0])
+				So(err, ShouldBeNil)
+				So(lo.Map(episodes, func(e *source.Episode, _ int) string { return e.Name }), ShouldResemble, []string{
+					"Episode 1", "Special 1.5", "Episode 2",
+				})
+				So(episodes[0].URL, ShouldEndWith, "01 (1080p).mkv")
+
+				Convey("And every file of an episode as its videos", func() {
+					videos, err := s.VideosOf(episodes[0])
+					So(err, ShouldBeNil)
+					So(videos, ShouldHaveLength, 2)
+					So(videos[0].Height, ShouldEqual, 1080)
+					So(videos[1].Quality, ShouldEqual, "720p")
+					So(videos[0].Subtitles, ShouldHaveLength, 1)
+					So(videos[0].Subtitles[0].Language, ShouldEqual, "en")
+					So(videos[0].Subtitles[0].Format, ShouldEqual, "ass")
+				})
+			})
+		})
+
+		Convey("An empty library configuration is an error", func() {
+			_, err := New(nil).Search("")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,176 @@
+package local
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/anisan-cli/anisan/source"
+)
+
+// Release is what a release-style file name, such as
+// "[SubsPlease] Sousou no Frieren - 12 (1080p) [A1B2C3D4].mkv", tells about the video in it.
+type Release struct {
+	// Group that released the file, e.g. "SubsPlease".
+	Group string
+	// Title of the anime without its season, e.g. "Sousou no Frieren".
+	Title string
+	// Season of the anime, 0 if the name does not tell.
+	Season int
+	// Number of the episode, 0 if the name does not tell.
+	Number float64
+	// Kind of the episode, empty for regular ones.
+	Kind source.EpisodeKind
+	// Resolution of the video, e.g. "1080p".
+	Resolution string
+}
+
+// Name is the title of the anime followed by its season after the first.
+func (r Release) Name() string {
+	if r.Season > 1 {
+		return r.Title + " Season " + strconv.Itoa(r.Season)
+	}
+	return r.Title
+}
+
+var (
+	leadingGroup    = regexp.MustCompile(`^\s*\[([^\]]+)\]`)
+	trailingGroup   = regexp.MustCompile(`-([A-Za-z0-9]+)$`)
+	bracketed       = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)|【[^】]*】`)
+	resolutionTag   = regexp.MustCompile(`(?i)\b(\d{3,4})[pi]\b`)
+	dimensionsTag   = regexp.MustCompile(`\b\d{3,4}x(\d{3,4})\b`)
+	seasonTitle     = regexp.MustCompile(`(?i)\s(?:S(\d{1,2})|Season\s(\d{1,2})|(\d{1,2})(?:st|nd|rd|th)\sSeason)$`)
+	releaseNumber   = `(\d{1,4}(?:\.\d)?)(?:v\d)?`
+	episodePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bS(\d{1,2})\s?E` + releaseNumber + `\b`),
+		regexp.MustCompile(`\s-\s` + releaseNumber + `(?:\s|$)`),
+		regexp.MustCompile(`(?i)\b(?:E|EP|Episode)\s?` + releaseNumber + `\b`),
+		regexp.MustCompile(`(?i)\b(OVA|OAD|ONA|SP|Specials?|Recap|Movie)(?:\s?` + releaseNumber + `)?\s*$`),
+		regexp.MustCompile(`(?:^|\s)` + releaseNumber + `$`),
+	}
+)
+
+// ParseRelease reads the release-style name of a video file.
+// Names it cannot find an episode number in are taken for a title.
+func ParseRelease(filename string) Release {
+	r, name := clean(strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+
+	title, rest := name, ""
+	for i, pattern := range episodePatterns {
+		m := pattern.FindStringSubmatchIndex(name)
+		if m == nil {
+			continue
+		}
+
+		title, rest = name[:m[0]], name[m[0]:]
+		switch i {
+		case 0:
+			r.Season, _ = strconv.Atoi(name[m[2]:m[3]])
+			r.Number, _ = strconv.ParseFloat(name[m[4]:m[5]], 64)
+		case 3:
+			r.Kind, _ = source.ParseEpisodeKind(name[m[2]:m[3]])
+			if m[4] >= 0 {
+				r.Number, _ = strconv.ParseFloat(name[m[4]:m[5]], 64)
+			}
+		default:
+			r.Number, _ = strconv.ParseFloat(name[m[2]:m[3]], 64)
+		}
+		break
+	}
+
+	if r.Kind == "" {
+		// Only what follows the title tells the kind, as titles may well contain "Movie"
+		r.Kind = source.GuessEpisodeKind(rest, r.Number)
+	}
+	if r.Kind == source.KindRegular {
+		r.Kind = ""
+	}
+
+	title, season := splitSeason(title)
+	r.Title = title
+	if season > 0 {
+		r.Season = season
+	}
+
+	return r
+}
+
+// parseDirectory reads the title and season of an anime from the name of its directory.
+func parseDirectory(dir string) Release {
+	r, name := clean(filepath.Base(dir))
+	r.Title, r.Season = splitSeason(name)
+	return r
+}
+
+// clean takes the group and resolution tags off a name and returns the words left.
+func clean(name string) (Release, string) {
+	var r Release
+
+	if m := leadingGroup.FindStringSubmatch(name); m != nil {
+		r.Group = strings.TrimSpace(m[1])
+		name = name[len(m[0]):]
+	}
+
+	if m := resolutionTag.FindStringSubmatch(name); m != nil {
+		r.Resolution = m[1] + "p"
+	} else if m := dimensionsTag.FindStringSubmatch(name); m != nil {
+		r.Resolution = m[1] + "p"
+	}
+
+	name = bracketed.ReplaceAllString(name, " ")
+
+	// Scene releases separate words with dots and end with the group, e.g. "Title.S01E02.1080p.WEB-GROUP"
+	if !strings.Contains(strings.TrimSpace(name), " ") {
+		if m := trailingGroup.FindStringSubmatch(name); m != nil && r.Group == "" && strings.ContainsAny(name, "._") {
+			r.Group = m[1]
+			name = name[:len(name)-len(m[0])]
+		}
+		name = spaceSeparators(name)
+	}
+
+	return r, strings.Join(strings.Fields(name), " ")
+}
+
+// splitSeason splits a title such as "Mushishi 2nd Season" into the title and its season.
+func splitSeason(title string) (string, int) {
+	title = strings.Trim(title, " -_.")
+
+	m := seasonTitle.FindStringSubmatchIndex(title)
+	if m == nil {
+		return title, 0
+	}
+
+	var season int
+	for i := 2; i < len(m); i += 2 {
+		if m[i] >= 0 {
+			season, _ = strconv.Atoi(title[m[i]:m[i+1]])
+		}
+	}
+
+	return strings.Trim(title[:m[0]], " -_."), season
+}
+
+// spaceSeparators turns the dots and underscores between words into spaces,
+// keeping the dots of numbers such as 12.5.
+func spaceSeparators(name string) string {
+	runes := []rune(name)
+	for i, r := range runes {
+		if r == '_' {
+			runes[i] = ' '
+			continue
+		}
+
+		if r != '.' {
+			continue
+		}
+
+		decimal := i > 0 && i < len(runes)-1 && unicode.IsDigit(runes[i-1]) && unicode.IsDigit(runes[i+1])
+		if !decimal {
+			runes[i] = ' '
+		}
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,48 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/anisan-cli/anisan/source"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseRelease(t *testing.T) {
+	Convey("Release-style names are parsed", t, func() {
+		cases := map[string]Release{
+			"[SubsPlease] Sousou no Frieren - 12 (1080p) [A1B2C3D4].mkv": {
+				Group: "SubsPlease", Title: "Sousou no Frieren", Number: 12, Resolution: "1080p",
+			},
+			"[Erai-raws] Kimetsu no Yaiba S2 - 05v2 [720p][Multiple Subtitle].mkv": {
+				Group: "Erai-raws", Title: "Kimetsu no Yaiba", Season: 2, Number: 5, Resolution: "720p",
+			},
+			"Cowboy.Bebop.S01E03.1080p.BluRay.x264-GROUP.mkv": {
+				Group: "GROUP", Title: "Cowboy Bebop", Season: 1, Number: 3, Resolution: "1080p",
+			},
+			"Mushishi 2nd Season - 13.5.mp4": {
+				Title: "Mushishi", Season: 2, Number: 13.5, Kind: source.KindSpecial,
+			},
+			"[Group] Made in Abyss - OVA 02 [1920x1080].mkv": {
+				Group: "Group", Title: "Made in Abyss", Number: 2, Kind: source.KindOVA, Resolution: "1080p",
+			},
+			"Haikyuu_Episode_07.mkv": {
+				Title: "Haikyuu", Number: 7,
+			},
+			"[Group] 04 [480p].mkv": {
+				Group: "Group", Number: 4, Resolution: "480p",
+			},
+			"Kimi no Na wa. (2016) [1080p].mkv": {
+				Title: "Kimi no Na wa", Resolution: "1080p",
+			},
+		}
+
+		for name, want := range cases {
+			So(ParseRelease("/anime/"+name), ShouldResemble, want)
+		}
+	})
+
+	Convey("Seasons after the first are part of the name", t, func() {
+		So(Release{Title: "Mushishi", Season: 2}.Name(), ShouldEqual, "Mushishi Season 2")
+		So(Release{Title: "Mushishi", Season: 1}.Name(), ShouldEqual, "Mushishi")
+	})
+}
@@ -9,11 +9,15 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/anisan-cli/anisan/internal/scraper"
 	"github.com/anisan-cli/anisan/log"
+	"github.com/anisan-cli/anisan/provider/custom"
 	"github.com/anisan-cli/anisan/where"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samber/lo"
 )
 
 const RepoRawURL = "https://raw.githubusercontent.com/santosh-k22/anisan-cli/main/config/sources/"
@@ -35,6 +39,15 @@ func UpdateScrapers() tea.Cmd {
 		client := &http.Client{}
 
 		for _, file := range filesToUpdate {
+			// Sources installed as packages are updated as a whole
+			name := strings.TrimSuffix(file, CustomProviderExtension)
+			if custom.IsPackage(filepath.Join(where.Sources(), name)) {
+				if updatePackage(ctx, client, name) {
+					updated = true
+				}
+				continue
+			}
+
 			if updateSingleFile(ctx, client, file) {
 				updated = true
 			}
@@ -105,6 +118,109 @@ func updateSingleFile(ctx context.Context, client *http.Client, filename string)
 		return false
 	}
 
+	scraper.Forget(localPath)
 	log.Infof("OTA updated scraper script: %s", filename)
 	return true
 }
+
+// updatePackage fetches the init.lua of a package and, following its requires, the modules in it.
+// Modules the package does not have are shared ones, updated on their own. The directory is
+// swapped at once, so that a source never runs modules of different versions.
+func updatePackage(ctx context.Context, client *http.Client, name string) bool {
+	files := make(map[string][]byte)
+	queue := []string{custom.PackageScript}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		if _, done := files[file]; done {
+			continue
+		}
+
+		body, status, err := fetch(ctx, client, RepoRawURL+name+"/"+file)
+		if err != nil {
+			log.Warnf("OTA network failure for %s/%s: %v", name, file, err)
+			return false
+		}
+
+		if status == http.StatusNotFound && file != custom.PackageScript {
+			continue
+		}
+
+		if status != http.StatusOK {
+			log.Warnf("OTA returned non-200 for %s/%s: %d", name, file, status)
+			return false
+		}
+
+		files[file] = body
+		for _, module := range custom.Requires(body) {
+			queue = append(queue, strings.ReplaceAll(module, ".", "/")+CustomProviderExtension)
+		}
+	}
+
+	localDir := filepath.Join(where.Sources(), name)
+	unchanged := lo.EveryBy(lo.Keys(files), func(file string) bool {
+		local, err := os.ReadFile(filepath.Join(localDir, filepath.FromSlash(file)))
+		return err == nil && sha256.Sum256(local) == sha256.Sum256(files[file])
+	})
+	if unchanged {
+		return false
+	}
+
+	staging := filepath.Join(where.Sources(), "."+name+".tmp")
+	_ = os.RemoveAll(staging)
+	for file, body := range files {
+		path := filepath.Join(staging, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			log.Warnf("OTA failed to stage %s/%s: %v", name, file, err)
+			_ = os.RemoveAll(staging)
+			return false
+		}
+		if err := os.WriteFile(path, body, 0644); err != nil {
+			log.Warnf("OTA failed to stage %s/%s: %v", name, file, err)
+			_ = os.RemoveAll(staging)
+			return false
+		}
+	}
+
+	// Move the old package aside rather than deleting it, to put it back if the swap fails
+	backup := filepath.Join(where.Sources(), "."+name+".old")
+	_ = os.RemoveAll(backup)
+	if err := os.Rename(localDir, backup); err != nil {
+		log.Warnf("OTA failed to swap package %s: %v", name, err)
+		_ = os.RemoveAll(staging)
+		return false
+	}
+
+	if err := os.Rename(staging, localDir); err != nil {
+		_ = os.Rename(backup, localDir)
+		_ = os.RemoveAll(staging)
+		log.Warnf("OTA failed to swap package %s: %v", name, err)
+		return false
+	}
+
+	_ = os.RemoveAll(backup)
+	scraper.Forget(localDir)
+	log.Infof("OTA updated scraper package: %s", name)
+	return true
+}
+
+// fetch downloads url, returning the body of successful responses along with the status.
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
+}
@@ -1,14 +1,15 @@
 package provider
 
 import (
-	"bytes"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/anisan-cli/anisan/filesystem"
 	"github.com/anisan-cli/anisan/provider/custom"
 	"github.com/anisan-cli/anisan/source"
-	"github.com/anisan-cli/anisan/util"
 	"github.com/anisan-cli/anisan/where"
+	"github.com/samber/lo"
 )
 
 type Provider struct {
@@ -17,14 +18,53 @@ type Provider struct {
 	UsesHeadless bool // Indicates whether the provider requires a headless browser.
 	IsCustom     bool // Reserved for Lua-based providers.
 	CreateSource func() (source.Source, error)
+
+	// Metadata declared by the provider. For custom providers it comes from the script manifest.
+	Version      string
+	Author       string
+	License      string
+	URL          string
+	Language     string
+	MinVersion   string
+	Capabilities []string
+	Permissions  custom.Permissions // What the script may use beyond the sandbox.
+	Options      []custom.Option    // Settings users can change with "anisan config set".
+	Mirrors      []custom.Mirror    // Domains of the site, the working one is picked at runtime.
+	Path         string             // Location of the script for custom providers.
+
+	// Err is set when the provider cannot be used, e.g. its script is malformed
+	// or requires a newer anisan. CreateSource returns it.
+	Err error
 }
 
 func (p *Provider) String() string {
 	return p.Name
 }
 
+// builtins are the providers compiled into anisan, by name.
+var builtins = make(map[string]*Provider)
+
+// Register adds a provider compiled into anisan. It is meant to be called from init
+// functions and panics when the name is taken, as that is a programming error.
+func Register(p *Provider) {
+	if p.Name == "" || p.CreateSource == nil {
+		panic("provider: Register needs a name and CreateSource")
+	}
+
+	if _, exists := builtins[p.Name]; exists {
+		panic("provider: Register called twice for " + p.Name)
+	}
+
+	builtins[p.Name] = p
+}
+
+// Builtins returns the registered providers sorted by name.
 func Builtins() []*Provider {
-	return []*Provider{}
+	providers := lo.Values(builtins)
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name < providers[j].Name
+	})
+	return providers
 }
 
 func Customs() []*Provider {
@@ -32,7 +72,23 @@ func Customs() []*Provider {
 	return providers
 }
 
+// RegisterCustomOptions makes the options declared by the manifests of the custom sources configuration keys.
+// Scripts named like a builtin are skipped, as the builtin is used instead and its keys are its own.
+func RegisterCustomOptions() {
+	for _, p := range Customs() {
+		if _, ok := builtins[p.Name]; ok {
+			continue
+		}
+		custom.RegisterOptions(p.Name, &custom.Manifest{Options: p.Options, Mirrors: p.Mirrors})
+	}
+}
+
+// Get finds a provider by name. Builtins take precedence over custom scripts of the same name.
 func Get(name string) (*Provider, bool) {
+	if p, ok := builtins[name]; ok {
+		return p, true
+	}
+
 	for _, p := range Customs() {
 		if p.Name == name {
 			return p, true
@@ -41,6 +97,8 @@ func Get(name string) (*Provider, bool) {
 	return nil, false
 }
 
+// CustomProviders lists the Lua sources: scripts and package directories with an init.lua.
+// Scripts that return a value, such as common.lua, are modules shared by sources and are left out.
 func CustomProviders() ([]*Provider, error) {
 	files, err := filesystem.API().ReadDir(where.Sources())
 	if err != nil {
@@ -49,46 +107,63 @@ func CustomProviders() ([]*Provider, error) {
 
 	var providers []*Provider
 	for _, f := range files {
-		if filepath.Ext(f.Name()) != ".lua" {
-			continue
-		}
+		path := filepath.Join(where.Sources(), f.Name())
 
-		if f.Name() == "common.lua" {
+		if f.IsDir() {
+			if strings.HasPrefix(f.Name(), ".") {
+				continue
+			}
+			if exists, _ := filesystem.API().Exists(filepath.Join(path, custom.PackageScript)); !exists {
+				continue
+			}
+		} else if filepath.Ext(f.Name()) != CustomProviderExtension || custom.IsModule(path) {
 			continue
 		}
 
-		path := filepath.Join(where.Sources(), f.Name())
-		name := util.FileStem(f.Name())
-
-		providers = append(providers, &Provider{
-			ID:           custom.IDfromName(name),
-			Name:         name,
-			UsesHeadless: isHeadless(path),
-			IsCustom:     true,
-			CreateSource: func() (source.Source, error) {
-				return custom.LoadSource(path)
-			},
-		})
+		providers = append(providers, newCustomProvider(path))
 	}
 
 	return providers, nil
 }
 
-func isHeadless(path string) bool {
-	content, err := filesystem.API().ReadFile(path)
-	if err != nil {
-		return false
+// newCustomProvider describes the Lua script or package at path using its manifest.
+func newCustomProvider(path string) *Provider {
+	name := custom.SourceName(path)
+	p := &Provider{
+		ID:       custom.IDfromName(name),
+		Name:     name,
+		IsCustom: true,
+		Path:     path,
+		CreateSource: func() (source.Source, error) {
+			return custom.LoadSource(path)
+		},
+	}
+
+	manifest, err := custom.ReadManifest(custom.ScriptPath(path))
+	if err == nil {
+		err = manifest.CheckCompatibility(name)
 	}
 
-	match := [][]byte{
-		[]byte("require(\"headless\")"),
-		[]byte("require('headless')"),
+	if manifest != nil {
+		p.UsesHeadless = manifest.UsesHeadless()
+		p.Version = manifest.Version
+		p.Author = manifest.Author
+		p.License = manifest.License
+		p.URL = manifest.URL
+		p.Language = manifest.Language
+		p.MinVersion = manifest.MinVersion
+		p.Capabilities = manifest.Capabilities
+		p.Permissions = manifest.RequestedPermissions()
+		p.Options = manifest.Options
+		p.Mirrors = manifest.Mirrors
 	}
 
-	for _, m := range match {
-		if bytes.Contains(content, m) {
-			return true
+	if err != nil {
+		p.Err = err
+		p.CreateSource = func() (source.Source, error) {
+			return nil, err
 		}
 	}
-	return false
+
+	return p
 }
@@ -1,8 +1,14 @@
 package provider
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/anisan-cli/anisan/config"
+	"github.com/anisan-cli/anisan/filesystem"
+	"github.com/anisan-cli/anisan/where"
+	"github.com/samber/lo"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -14,3 +20,37 @@ func TestGet(t *testing.T) {
 		})
 	})
 }
+
+func TestCustomProviders(t *testing.T) {
+	Convey("Given scripts, packages and shared modules", t, func() {
+		filesystem.SetOsFs()
+		t.Setenv(where.EnvConfigPath, t.TempDir())
+
+		for path, content := range map[string]string{
+			"flat.lua":           "function SearchAnimes() end",
+			"common.lua":         "return {}",
+			"packaged/init.lua":  "function SearchAnimes() end",
+			"packaged/util.lua":  "return {}",
+			"notes/readme.txt":   "",
+			".packaged.tmp/init": "",
+		} {
+			path = filepath.Join(where.Sources(), path)
+			So(os.MkdirAll(filepath.Dir(path), os.ModePerm), ShouldBeNil)
+			So(os.WriteFile(path, []byte(content), 0o644), ShouldBeNil)
+		}
+
+		Convey("Scripts and packages are listed as sources", func() {
+			providers, err := CustomProviders()
+			So(err, ShouldBeNil)
+			So(lo.Map(providers, func(p *Provider, _ int) string { return p.Name }), ShouldResemble, []string{"flat", "packaged"})
+		})
+
+		Convey("Options declared by scripts become configuration keys", func() {
+			path := filepath.Join(where.Sources(), "optioned.lua")
+			So(os.WriteFile(path, []byte("-- @option server vidstream\nfunction SearchAnimes() end"), 0o644), ShouldBeNil)
+
+			RegisterCustomOptions()
+			So(config.Default, ShouldContainKey, "sources.optioned.server")
+		})
+	})
+}
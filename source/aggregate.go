@@ -0,0 +1,310 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/anisan-cli/anisan/log"
+	levenshtein "github.com/ka-weihe/fast-levenshtein"
+	"github.com/samber/lo"
+)
+
+// Health rates how reliable a source has been, higher is better.
+// Grouped animes list the alternatives of healthier sources first.
+// When unset every source is considered equally healthy.
+var Health func(s Source) float64
+
+// GroupAnimes merges the search results of several sources into one anime per show.
+// Results are matched by tracker ID when known, otherwise by their normalized titles and synonyms.
+// A source never contributes two results to the same group, and results bound to different
+// tracker entries, e.g. a show and its remake, are never grouped.
+//
+// Each group is represented by the result of its healthiest source, the others are kept
+// in its Alternatives. Groups are ranked by how closely their titles match the query,
+// then by the number of sources they were found in and by popularity.
+func GroupAnimes(query string, animes []*Anime) []*Anime {
+	return rankGroups(query, groupInto(nil, animes))
+}
+
+// MergeAnimes groups the results of a further page of the same search with the listed ones,
+// as returned by GroupAnimes. Results of a show already listed join its alternatives,
+// the representative staying the same. The listed animes are left untouched since they may
+// still be in use: merged holds, for each of them, either itself or a copy of its grown group.
+// The other results are grouped and ranked like GroupAnimes and returned as added.
+func MergeAnimes(query string, listed, animes []*Anime) (merged, added []*Anime) {
+	existing := make([][]*Anime, len(listed))
+	for i, anime := range listed {
+		existing[i] = append([]*Anime{anime}, anime.Alternatives...)
+	}
+
+	groups := groupInto(existing, animes)
+	merged = make([]*Anime, len(listed))
+	for i, group := range groups[:len(existing)] {
+		if len(group) == len(listed[i].Alternatives)+1 {
+			merged[i] = listed[i]
+			continue
+		}
+
+		group = lo.Map(group, func(anime *Anime, _ int) *Anime {
+			clone := *anime
+			return &clone
+		})
+		sortAlternatives(group[1:])
+		linkAlternatives(group)
+		merged[i] = group[0]
+	}
+
+	return merged, rankGroups(query, groups[len(existing):])
+}
+
+// groupInto adds every anime to the first of groups it matches, or to a new group.
+func groupInto(groups [][]*Anime, animes []*Anime) [][]*Anime {
+	keys := make(map[*Anime]groupKey)
+	keyOf := func(anime *Anime) groupKey {
+		key, ok := keys[anime]
+		if !ok {
+			key = groupKeyOf(anime)
+			keys[anime] = key
+		}
+		return key
+	}
+
+	for _, anime := range animes {
+		key := keyOf(anime)
+
+		_, found, _ := lo.FindIndexOf(groups, func(group []*Anime) bool {
+			return !lo.SomeBy(group, func(a *Anime) bool { return a.Source == anime.Source || keyOf(a).conflicts(key) }) &&
+				lo.SomeBy(group, func(a *Anime) bool { return lo.Some(keyOf(a).keys, key.keys) })
+		})
+		if found != -1 {
+			groups[found] = append(groups[found], anime)
+		} else {
+			groups = append(groups, []*Anime{anime})
+		}
+	}
+
+	return groups
+}
+
+// rankGroups orders every group from its healthiest source down, links the alternatives
+// of each group and returns the representatives of the groups, most relevant first.
+func rankGroups(query string, groups [][]*Anime) []*Anime {
+	query = normalizeTitle(query)
+	relevance := make(map[*Anime]float64, len(groups))
+	representatives := make([]*Anime, len(groups))
+
+	for i, group := range groups {
+		sortAlternatives(group)
+		linkAlternatives(group)
+
+		representatives[i] = group[0]
+		relevance[group[0]] = lo.Max(lo.FlatMap(group, func(a *Anime, _ int) []float64 {
+			return lo.Map(titlesOf(a), func(title string, _ int) float64 { return similarity(query, title) })
+		}))
+	}
+
+	sort.SliceStable(representatives, func(i, j int) bool {
+		a, b := representatives[i], representatives[j]
+		switch {
+		case relevance[a] != relevance[b]:
+			return relevance[a] > relevance[b]
+		case len(a.Alternatives) != len(b.Alternatives):
+			return len(a.Alternatives) > len(b.Alternatives)
+		default:
+			return a.Metadata.Score > b.Metadata.Score
+		}
+	})
+
+	return representatives
+}
+
+// linkAlternatives sets the alternatives of every anime of the group to the others, in order.
+func linkAlternatives(group []*Anime) {
+	for i, anime := range group {
+		anime.Alternatives = append(append([]*Anime{}, group[:i]...), group[i+1:]...)
+	}
+}
+
+// EpisodesOfAny lists the episodes of anime like EpisodesOf, trying its alternatives
+// in turn when the source fails or has no episodes. The episodes belong to whichever anime answered.
+func EpisodesOfAny(ctx context.Context, anime *Anime) ([]*Episode, error) {
+	var lastErr error
+
+	for _, candidate := range append([]*Anime{anime}, anime.Alternatives...) {
+		episodes, err := EpisodesOf(ctx, candidate)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if err == nil && len(episodes) > 0 {
+			if candidate != anime {
+				log.Infof("using %s for %s", candidate.Source.Name(), anime.Name)
+			}
+			return episodes, nil
+		}
+
+		if err != nil {
+			log.Warnf("%s failed to list episodes of %s: %v", candidate.Source.Name(), candidate.Name, err)
+			lastErr = err
+		}
+	}
+
+	return nil, lastErr
+}
+
+// VideosOfAny resolves the streams of episode like VideosOf. When the source fails or has
+// no streams, the same episode is looked up in the alternatives of its anime.
+func VideosOfAny(ctx context.Context, episode *Episode) ([]*Video, error) {
+	videos, err := VideosOf(ctx, episode)
+	if ctx.Err() != nil || (err == nil && len(videos) > 0) || episode.Anime == nil {
+		return videos, err
+	}
+
+	if err != nil {
+		log.Warnf("%s failed to resolve %s: %v", episode.Source().Name(), episode.Name, err)
+	}
+
+	for _, alternative := range episode.Anime.Alternatives {
+		episodes, listErr := EpisodesOf(ctx, alternative)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if listErr != nil {
+			log.Warnf("%s failed to list episodes of %s: %v", alternative.Source.Name(), alternative.Name, listErr)
+			continue
+		}
+
+		match, ok := matchingEpisode(episode, episodes)
+		if !ok {
+			log.Infof("%s has no %s of %s", alternative.Source.Name(), episode.Name, alternative.Name)
+			continue
+		}
+
+		log.Infof("resolving %s with %s", episode.Name, alternative.Source.Name())
+		alternativeVideos, alternativeErr := VideosOf(ctx, match)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if alternativeErr == nil && len(alternativeVideos) > 0 {
+			return alternativeVideos, nil
+		}
+		if alternativeErr != nil {
+			log.Warnf("%s failed to resolve %s: %v", alternative.Source.Name(), match.Name, alternativeErr)
+			err = alternativeErr
+		}
+	}
+
+	if err == nil && len(videos) == 0 && len(episode.Anime.Alternatives) > 0 {
+		return nil, fmt.Errorf("no streams found for %s in any source", episode.Name)
+	}
+
+	return videos, err
+}
+
+// matchingEpisode finds the episode with the same number and kind, preferring the same translation.
+// Episodes without a number cannot be told apart, so they match none.
+func matchingEpisode(episode *Episode, episodes []*Episode) (*Episode, bool) {
+	if episode.Number <= 0 {
+		return nil, false
+	}
+
+	candidates := lo.Filter(episodes, func(e *Episode, _ int) bool {
+		return e.Number == episode.Number && e.Kind == episode.Kind
+	})
+
+	if match, ok := lo.Find(candidates, func(e *Episode) bool { return e.Translation == episode.Translation }); ok {
+		return match, true
+	}
+
+	return lo.First(candidates)
+}
+
+// sortAlternatives orders the animes of a group from the healthiest source down.
+func sortAlternatives(group []*Anime) {
+	health := func(a *Anime) float64 {
+		if Health == nil || a.Source == nil {
+			return 0
+		}
+		return Health(a.Source)
+	}
+
+	sort.SliceStable(group, func(i, j int) bool {
+		a, b := group[i], group[j]
+		if ha, hb := health(a), health(b); ha != hb {
+			return ha > hb
+		}
+		if a.Index != b.Index {
+			return a.Index < b.Index
+		}
+		return sourceName(a) < sourceName(b)
+	})
+}
+
+func sourceName(a *Anime) string {
+	if a.Source == nil {
+		return ""
+	}
+	return a.Source.Name()
+}
+
+// groupKey is what an anime is matched with the results of other sources by.
+type groupKey struct {
+	keys      []string
+	anilistID int
+	malID     int
+}
+
+// conflicts reports whether the keys belong to different tracker entries.
+func (k groupKey) conflicts(other groupKey) bool {
+	return (k.anilistID != 0 && other.anilistID != 0 && k.anilistID != other.anilistID) ||
+		(k.malID != 0 && other.malID != 0 && k.malID != other.malID)
+}
+
+// groupKeyOf returns the key under which anime is matched with the results of other sources.
+func groupKeyOf(anime *Anime) groupKey {
+	var key groupKey
+
+	key.anilistID, key.malID = anime.TrackerIDs()
+	if key.anilistID != 0 {
+		key.keys = append(key.keys, fmt.Sprintf("anilist:%d", key.anilistID))
+	}
+	if key.malID != 0 {
+		key.keys = append(key.keys, fmt.Sprintf("mal:%d", key.malID))
+	}
+
+	for _, title := range titlesOf(anime) {
+		key.keys = append(key.keys, "title:"+title)
+	}
+
+	return key
+}
+
+// titlesOf returns the normalized, non-empty titles of anime.
+func titlesOf(anime *Anime) []string {
+	titles := append([]string{anime.Name, anime.Metadata.Title}, anime.Metadata.Synonyms...)
+	return lo.Uniq(lo.Filter(lo.Map(titles, func(title string, _ int) string {
+		return normalizeTitle(title)
+	}), func(title string, _ int) bool {
+		return title != ""
+	}))
+}
+
+// normalizeTitle lowercases title and keeps only letters and digits, separated by single spaces.
+func normalizeTitle(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// similarity returns how close two normalized titles are, from 0 to 1.
+func similarity(a, b string) float64 {
+	longest := lo.Max([]int{len([]rune(a)), len([]rune(b))})
+	if longest == 0 {
+		return 0
+	}
+
+	return 1 - float64(levenshtein.Distance(a, b))/float64(longest)
+}
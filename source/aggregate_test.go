@@ -0,0 +1,213 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/anisan-cli/anisan/anilist"
+	"github.com/samber/mo"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// mirrorSource serves the same episodes for every anime, numbered from 1 unless unnumbered.
+type mirrorSource struct {
+	testSource
+	name       string
+	episodes   int
+	unnumbered bool
+	err        error
+}
+
+func (s *mirrorSource) Name() string { return s.name }
+
+func (s *mirrorSource) EpisodesOf(anime *Anime) ([]*Episode, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	episodes := make([]*Episode, s.episodes)
+	for i := range episodes {
+		episodes[i] = &Episode{Name: s.name, Number: float64(i + 1), Anime: anime}
+		if s.unnumbered {
+			episodes[i].Number = 0
+		}
+	}
+	return episodes, nil
+}
+
+func (s *mirrorSource) VideosOf(episode *Episode) ([]*Video, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []*Video{{URL: s.name + "/video"}}, nil
+}
+
+func TestGroupAnimes(t *testing.T) {
+	Convey("Given results of the same shows from several sources", t, func() {
+		a := &mirrorSource{name: "a", episodes: 12}
+		b := &mirrorSource{name: "b", episodes: 12}
+		c := &mirrorSource{name: "c", episodes: 12}
+
+		animes := []*Anime{
+			{Name: "Frieren: Beyond Journey's End", Source: a, Index: 1},
+			{Name: "Sousou no Frieren", Source: a, Index: 0},
+			{Name: "Frieren - Beyond Journey's End", Source: b, Index: 0},
+			{Name: "Sousou no Frieren", Source: c, Index: 3, Metadata: Metadata{Synonyms: []string{"Frieren: Beyond Journey's End"}}},
+		}
+
+		Convey("When they are grouped", func() {
+			groups := GroupAnimes("sousou no frieren", animes)
+
+			Convey("Then matching titles and synonyms are merged, one result per source", func() {
+				// Found in three sources, the group outranks the equally relevant single result
+				So(groups, ShouldHaveLength, 2)
+				So(groups[0].Source, ShouldEqual, b)
+				So(groups[0].Alternatives, ShouldHaveLength, 2)
+				So(groups[1].Source, ShouldEqual, a)
+				So(groups[1].Name, ShouldEqual, "Sousou no Frieren")
+				So(groups[1].Alternatives, ShouldHaveLength, 0)
+			})
+
+			Convey("Then every member knows the others", func() {
+				for _, alternative := range groups[0].Alternatives {
+					So(alternative.Alternatives, ShouldHaveLength, 2)
+					So(alternative.Alternatives, ShouldContain, groups[0])
+				}
+			})
+		})
+
+		Convey("When a source is healthier", func() {
+			Health = func(s Source) float64 {
+				if s == c {
+					return 1
+				}
+				return 0.5
+			}
+			defer func() { Health = nil }()
+
+			groups := GroupAnimes("sousou no frieren", animes)
+
+			Convey("Then its result represents the group", func() {
+				So(groups[0].Source, ShouldEqual, c)
+			})
+		})
+	})
+}
+
+func TestGroupAnimesByTracker(t *testing.T) {
+	Convey("Given a show and its remake, titled alike", t, func() {
+		a := &mirrorSource{name: "a"}
+		b := &mirrorSource{name: "b"}
+		c := &mirrorSource{name: "c"}
+
+		original := &Anime{Name: "Hunter x Hunter", Source: a, Anilist: mo.Some(&anilist.Anime{ID: 136})}
+		remake := &Anime{Name: "Hunter x Hunter", Source: b, Anilist: mo.Some(&anilist.Anime{ID: 11061})}
+		unbound := &Anime{Name: "Hunter x Hunter", Source: c, Index: 1}
+
+		Convey("When they are grouped", func() {
+			groups := GroupAnimes("hunter x hunter", []*Anime{original, remake, unbound})
+
+			Convey("Then results bound to different tracker entries stay apart", func() {
+				So(groups, ShouldHaveLength, 2)
+				So(original.Alternatives, ShouldNotContain, remake)
+				So(remake.Alternatives, ShouldBeEmpty)
+				So(original.Alternatives, ShouldContain, unbound)
+			})
+		})
+	})
+}
+
+func TestMergeAnimes(t *testing.T) {
+	Convey("Given grouped results of a first page", t, func() {
+		a := &mirrorSource{name: "a"}
+		b := &mirrorSource{name: "b"}
+		c := &mirrorSource{name: "c"}
+
+		listed := GroupAnimes("monster", []*Anime{
+			{Name: "Monster", Source: a},
+			{Name: "Monster", Source: b, Index: 1},
+		})
+		So(listed, ShouldHaveLength, 1)
+
+		Convey("When a further page is merged", func() {
+			late := &Anime{Name: "Monster", Source: c, Index: 2}
+			other := &Anime{Name: "Pluto", Source: c, Index: 3}
+			merged, added := MergeAnimes("monster", listed, []*Anime{late, other})
+
+			Convey("Then results of listed shows join a copy of their group", func() {
+				So(added, ShouldResemble, []*Anime{other})
+				So(merged, ShouldHaveLength, 1)
+				So(merged[0], ShouldNotEqual, listed[0])
+				So(merged[0].Source, ShouldEqual, a)
+				So(merged[0].Alternatives, ShouldHaveLength, 2)
+				So(merged[0].Alternatives[1].Source, ShouldEqual, c)
+				So(merged[0].Alternatives[1].Alternatives, ShouldContain, merged[0])
+			})
+
+			Convey("Then the listed animes are left untouched", func() {
+				So(listed[0].Alternatives, ShouldHaveLength, 1)
+				So(listed[0].Alternatives[0].Alternatives, ShouldResemble, []*Anime{listed[0]})
+				So(late.Alternatives, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestFailover(t *testing.T) {
+	Convey("Given a grouped anime whose preferred source is broken", t, func() {
+		broken := &mirrorSource{name: "broken", err: errors.New("site is down")}
+		short := &mirrorSource{name: "short", episodes: 2}
+		working := &mirrorSource{name: "working", episodes: 12}
+
+		groups := GroupAnimes("monster", []*Anime{
+			{Name: "Monster", Source: broken},
+			{Name: "Monster", Source: short, Index: 1},
+			{Name: "Monster", Source: working, Index: 2},
+		})
+		So(groups, ShouldHaveLength, 1)
+		anime := groups[0]
+		So(anime.Source, ShouldEqual, broken)
+
+		Convey("When its episodes are listed", func() {
+			episodes, err := EpisodesOfAny(context.Background(), anime)
+
+			Convey("Then the next alternative answers", func() {
+				So(err, ShouldBeNil)
+				So(episodes, ShouldHaveLength, 2)
+				So(episodes[0].Anime.Source, ShouldEqual, short)
+			})
+		})
+
+		Convey("When an episode only another source has fails to resolve", func() {
+			episode := &Episode{Name: "Episode 10", Number: 10, Anime: anime}
+			videos, err := VideosOfAny(context.Background(), episode)
+
+			Convey("Then it is resolved by the source that has it", func() {
+				So(err, ShouldBeNil)
+				So(videos, ShouldHaveLength, 1)
+				So(videos[0].URL, ShouldEqual, "working/video")
+			})
+		})
+
+		Convey("When an episode without a number fails to resolve", func() {
+			untitled := &mirrorSource{name: "untitled", episodes: 3, unnumbered: true}
+			lonely := &Anime{Name: "Monster", Source: broken, Alternatives: []*Anime{{Name: "Monster", Source: untitled}}}
+			episode := &Episode{Name: "Special", Anime: lonely}
+			_, err := VideosOfAny(context.Background(), episode)
+
+			Convey("Then no other episode is played instead", func() {
+				So(err, ShouldEqual, broken.err)
+			})
+		})
+
+		Convey("When every source fails", func() {
+			lonely := &Anime{Name: "Monster", Source: broken}
+			_, err := EpisodesOfAny(context.Background(), lonely)
+
+			Convey("Then the last error is returned", func() {
+				So(err, ShouldEqual, broken.err)
+			})
+		})
+	})
+}
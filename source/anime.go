@@ -40,6 +40,10 @@ type Anime struct {
 
 	Episodes []*Episode `json:"episodes"`
 
+	// Alternatives are the same anime as found by other sources, most preferred first.
+	// Only set for animes grouped by GroupAnimes.
+	Alternatives []*Anime `json:"-"`
+
 	// Tracker integrations
 	Anilist  mo.Option[*anilist.Anime] `json:"anilist"`
 	Mal      mo.Option[*mal.Anime]     `json:"mal"`
@@ -118,6 +122,26 @@ func (a *Anime) GetCover() (string, error) {
 	return "", fmt.Errorf("no cover found")
 }
 
+// TrackerIDs returns the AniList and MyAnimeList IDs of the anime, zero when unknown.
+// Animes not bound yet are looked up in the relations cached by earlier bindings.
+func (a *Anime) TrackerIDs() (anilistID, malID int) {
+	if al, ok := a.Anilist.Get(); ok && al != nil {
+		anilistID, malID = al.ID, al.IDMal
+	} else if al := anilist.GetCachedRelation(a.Name); al != nil {
+		anilistID, malID = al.ID, al.IDMal
+	}
+
+	if m, ok := a.Mal.Get(); ok && m != nil {
+		malID = m.ID
+	} else if malID == 0 {
+		if m := mal.GetCachedRelation(a.Name); m != nil {
+			malID = m.ID
+		}
+	}
+
+	return anilistID, malID
+}
+
 func (a *Anime) BindWithTracker() error {
 	backend := viper.GetString("tracker.backend")
 
@@ -0,0 +1,131 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Observe is called with the outcome of every search, episode listing and video
+// resolution made through this package. Calls abandoned through their context
+// and calls answered from the cache of the source are not reported.
+var Observe func(s Source, latency time.Duration, err error)
+
+type cacheHitKey struct{}
+
+// FromCache is called by a source that answers the call made with ctx from its cache,
+// without reaching the site, so that the call does not count towards its health.
+func FromCache(ctx context.Context) {
+	if hit, ok := ctx.Value(cacheHitKey{}).(*atomic.Bool); ok {
+		hit.Store(true)
+	}
+}
+
+// observed returns the context to call s with and the function reporting the call to Observe.
+func observed(ctx context.Context, s Source) (context.Context, func(err error)) {
+	start := time.Now()
+	hit := new(atomic.Bool)
+
+	return context.WithValue(ctx, cacheHitKey{}, hit), func(err error) {
+		if Observe == nil || ctx.Err() != nil || hit.Load() {
+			return
+		}
+
+		Observe(s, time.Since(start), err)
+	}
+}
+
+// BudgetError is implemented by the errors of calls a source stopped because they ran out
+// of the time or resources they were allowed. Unlike a failing site, they mean the source is broken.
+type BudgetError interface {
+	error
+
+	// Exceeded returns the name of the source and the function whose call was stopped.
+	Exceeded() (source, function string)
+}
+
+// IsBudgetError reports whether err is, or wraps, a BudgetError.
+func IsBudgetError(err error) bool {
+	var budgetErr BudgetError
+	return errors.As(err, &budgetErr)
+}
+
+// ContextSource is implemented by sources whose in-flight work can be abandoned
+// through a context, so that navigating away or quitting stops it immediately.
+type ContextSource interface {
+	Source
+
+	// SearchContext is like Search but aborts once ctx is done.
+	SearchContext(ctx context.Context, query string) ([]*Anime, error)
+
+	// EpisodesOfContext is like EpisodesOf but aborts once ctx is done.
+	EpisodesOfContext(ctx context.Context, anime *Anime) ([]*Episode, error)
+
+	// VideosOfContext is like VideosOf but aborts once ctx is done.
+	VideosOfContext(ctx context.Context, episode *Episode) ([]*Video, error)
+}
+
+// Search queries s, honouring ctx. Sources that do not implement ContextSource
+// keep running in the background, but the caller is released as soon as ctx is done.
+func Search(ctx context.Context, s Source, query string) (animes []*Anime, err error) {
+	ctx, observe := observed(ctx, s)
+	defer func() { observe(err) }()
+
+	if cs, ok := s.(ContextSource); ok {
+		return cs.SearchContext(ctx, query)
+	}
+
+	return withContext(ctx, func() ([]*Anime, error) { return s.Search(query) })
+}
+
+// EpisodesOf lists the episodes of anime using its source, honouring ctx.
+func EpisodesOf(ctx context.Context, anime *Anime) (episodes []*Episode, err error) {
+	ctx, observe := observed(ctx, anime.Source)
+	defer func() { observe(err) }()
+
+	if cs, ok := anime.Source.(ContextSource); ok {
+		return cs.EpisodesOfContext(ctx, anime)
+	}
+
+	return withContext(ctx, func() ([]*Episode, error) { return anime.Source.EpisodesOf(anime) })
+}
+
+// VideosOf resolves the streams of episode using its source, honouring ctx.
+func VideosOf(ctx context.Context, episode *Episode) (videos []*Video, err error) {
+	s := episode.Source()
+	ctx, observe := observed(ctx, s)
+	defer func() { observe(err) }()
+
+	if cs, ok := s.(ContextSource); ok {
+		return cs.VideosOfContext(ctx, episode)
+	}
+
+	return withContext(ctx, func() ([]*Video, error) { return s.VideosOf(episode) })
+}
+
+func withContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,90 @@
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type blockingSource struct {
+	testSource
+	release chan struct{}
+}
+
+func (s blockingSource) Search(string) ([]*Anime, error) {
+	<-s.release
+	return []*Anime{{Name: "late"}}, nil
+}
+
+// cachingSource answers every search after the first from its cache.
+type cachingSource struct {
+	testSource
+	searched *int
+}
+
+func (s cachingSource) SearchContext(ctx context.Context, _ string) ([]*Anime, error) {
+	*s.searched++
+	if *s.searched > 1 {
+		FromCache(ctx)
+	}
+	return []*Anime{{Name: "cached"}}, nil
+}
+
+func (s cachingSource) EpisodesOfContext(_ context.Context, anime *Anime) ([]*Episode, error) {
+	return s.EpisodesOf(anime)
+}
+
+func (s cachingSource) VideosOfContext(_ context.Context, episode *Episode) ([]*Video, error) {
+	return s.VideosOf(episode)
+}
+
+func TestSearchContext(t *testing.T) {
+	Convey("Given a source without context support", t, func() {
+		src := blockingSource{release: make(chan struct{})}
+		defer close(src.release)
+
+		Convey("When the context is cancelled mid-search", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			go cancel()
+
+			animes, err := Search(ctx, src, "query")
+
+			Convey("Then the caller is released with the context error", func() {
+				So(err, ShouldEqual, context.Canceled)
+				So(animes, ShouldBeNil)
+			})
+		})
+
+		Convey("When the context is already done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := Search(ctx, testSource{}, "query")
+
+			Convey("Then the source is not queried at all", func() {
+				So(err, ShouldEqual, context.Canceled)
+			})
+		})
+	})
+}
+
+func TestObserve(t *testing.T) {
+	Convey("Given a source answering from its cache", t, func() {
+		observed := 0
+		Observe = func(Source, time.Duration, error) { observed++ }
+		defer func() { Observe = nil }()
+
+		src := cachingSource{searched: new(int)}
+
+		Convey("Only the calls that reached the source are observed", func() {
+			for i := 0; i < 3; i++ {
+				_, err := Search(context.Background(), src, "query")
+				So(err, ShouldBeNil)
+			}
+			So(*src.searched, ShouldEqual, 3)
+			So(observed, ShouldEqual, 1)
+		})
+	})
+}
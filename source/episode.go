@@ -1,5 +1,25 @@
 package source
 
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EpisodeKind tells regular episodes apart from the extras of a series.
+type EpisodeKind string
+
+// Kinds of episodes. Episodes without a kind are regular.
+const (
+	KindRegular EpisodeKind = "regular"
+	KindSpecial EpisodeKind = "special"
+	KindOVA     EpisodeKind = "ova"
+	KindRecap   EpisodeKind = "recap"
+	KindMovie   EpisodeKind = "movie"
+)
+
 // Episode represents a discrete media segment within an anime series.
 type Episode struct {
 	// Source ID (e.g. "1").
@@ -8,10 +28,19 @@ type Episode struct {
 	Name string `json:"name"`
 	// Direct URL to the episode page.
 	URL string `json:"url"`
-	// Episode number/index.
+	// Position of the episode in the list returned by the source, starting from 1.
 	Index uint16 `json:"index"`
+	// Number is the episode number as the source counts it, e.g. 25.5 for a special
+	// aired after episode 25. Zero if unknown.
+	Number float64 `json:"number,omitempty"`
+	// Kind of the episode, empty for regular ones.
+	Kind EpisodeKind `json:"kind,omitempty"`
 	// Volume number (mostly for consistency, often empty for anime).
 	Volume string `json:"volume"`
+	// Translation is the audio variant of the episode (e.g. "sub", "dub"), if the source distinguishes them.
+	Translation string `json:"translation,omitempty"`
+	// Language is the audio language of the episode as a BCP 47 tag (e.g. "ja", "en"), if known.
+	Language string `json:"language,omitempty"`
 
 	Anime *Anime `json:"-"`
 
@@ -32,3 +61,101 @@ func (e *Episode) Source() Source {
 	}
 	return e.Anime.Source
 }
+
+// IsRegular reports whether the episode counts towards the progress of the series.
+func (e *Episode) IsRegular() bool {
+	return e.Kind == "" || e.Kind == KindRegular
+}
+
+// Progress returns the number trackers count the episode as.
+// Specials, recaps and the like are not counted.
+func (e *Episode) Progress() (int, bool) {
+	if !e.IsRegular() || e.Number != math.Trunc(e.Number) {
+		return 0, false
+	}
+
+	if e.Number > 0 {
+		return int(e.Number), true
+	}
+
+	// Without a number, the position in the list is the best guess
+	return int(e.Index), e.Index > 0
+}
+
+// NumberString formats the episode number, e.g. "12" or "25.5".
+// Episodes without a number fall back to their position in the list.
+func (e *Episode) NumberString() string {
+	return FormatEpisodeNumber(e.order())
+}
+
+// order is the position of the episode when sorted.
+func (e *Episode) order() float64 {
+	if e.Number > 0 {
+		return e.Number
+	}
+	return float64(e.Index)
+}
+
+// FormatEpisodeNumber formats an episode number without trailing zeros.
+func FormatEpisodeNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// SortEpisodes orders the episodes by number, so that specials numbered 25.5 follow
+// episode 25. At the same number regular episodes come first.
+func SortEpisodes(episodes []*Episode) {
+	sort.SliceStable(episodes, func(i, j int) bool {
+		a, b := episodes[i], episodes[j]
+		if a.order() != b.order() {
+			return a.order() < b.order()
+		}
+		if a.IsRegular() != b.IsRegular() {
+			return a.IsRegular()
+		}
+		return a.Name < b.Name
+	})
+}
+
+// ParseEpisodeKind reads an episode kind as sources spell it, e.g. "OVA", "sp" or "recap".
+func ParseEpisodeKind(s string) (EpisodeKind, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "regular", "episode", "tv":
+		return KindRegular, true
+	case "special", "specials", "sp", "extra":
+		return KindSpecial, true
+	case "ova", "oad", "ona":
+		return KindOVA, true
+	case "recap", "summary":
+		return KindRecap, true
+	case "movie", "film":
+		return KindMovie, true
+	}
+
+	return "", false
+}
+
+var episodeKindWords = []struct {
+	pattern *regexp.Regexp
+	kind    EpisodeKind
+}{
+	{regexp.MustCompile(`(?i)\brecap\b`), KindRecap},
+	{regexp.MustCompile(`(?i)\b(ova|oad)\b`), KindOVA},
+	{regexp.MustCompile(`(?i)\b(movie|film)\b`), KindMovie},
+	{regexp.MustCompile(`(?i)\b(specials?|sp)\b`), KindSpecial},
+}
+
+// GuessEpisodeKind infers the kind of an episode from its name and number.
+// Episodes with a fractional number are specials.
+func GuessEpisodeKind(name string, number float64) EpisodeKind {
+	for _, word := range episodeKindWords {
+		if word.pattern.MatchString(name) {
+			return word.kind
+		}
+	}
+
+	if number != math.Trunc(number) {
+		return KindSpecial
+	}
+
+	return KindRegular
+}
@@ -27,6 +27,49 @@ func TestEpisode(t *testing.T) {
 			So(ep.Source().Name(), ShouldEqual, "Test Source")
 		})
 	})
+
+	Convey("Episode numbers", t, func() {
+		regular := &Episode{Name: "Episode 25", Number: 25, Index: 25}
+		special := &Episode{Name: "Episode 25.5", Number: 25.5, Index: 26, Kind: KindSpecial}
+		recap := &Episode{Name: "Recap", Number: 25, Index: 27, Kind: KindRecap}
+		unnumbered := &Episode{Name: "Finale", Index: 30}
+
+		Convey("Only regular episodes count as progress", func() {
+			progress, ok := regular.Progress()
+			So(ok, ShouldBeTrue)
+			So(progress, ShouldEqual, 25)
+
+			_, ok = special.Progress()
+			So(ok, ShouldBeFalse)
+			_, ok = recap.Progress()
+			So(ok, ShouldBeFalse)
+			_, ok = (&Episode{Number: 3.5}).Progress()
+			So(ok, ShouldBeFalse)
+
+			progress, _ = unnumbered.Progress()
+			So(progress, ShouldEqual, 30)
+		})
+
+		Convey("Specials sort after the regular episode of the same number", func() {
+			episodes := []*Episode{unnumbered, special, recap, regular}
+			SortEpisodes(episodes)
+			So(episodes, ShouldResemble, []*Episode{regular, recap, special, unnumbered})
+			So(special.NumberString(), ShouldEqual, "25.5")
+		})
+
+		Convey("Kinds are parsed and guessed", func() {
+			kind, ok := ParseEpisodeKind("OAD")
+			So(ok, ShouldBeTrue)
+			So(kind, ShouldEqual, KindOVA)
+			_, ok = ParseEpisodeKind("bonus")
+			So(ok, ShouldBeFalse)
+
+			So(GuessEpisodeKind("Episode 12", 12), ShouldEqual, KindRegular)
+			So(GuessEpisodeKind("Episode 12.5", 12.5), ShouldEqual, KindSpecial)
+			So(GuessEpisodeKind("The Movie", 0), ShouldEqual, KindMovie)
+			So(GuessEpisodeKind("Spirited", 0), ShouldEqual, KindRegular)
+		})
+	})
 }
 
 type testSource struct{}
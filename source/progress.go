@@ -0,0 +1,21 @@
+package source
+
+import "context"
+
+// ProgressSource is implemented by sources that keep track of what was watched
+// themselves, such as media servers, and want to hear how far playback got.
+type ProgressSource interface {
+	Source
+
+	// ReportProgress tells the source that episode was watched up to percentage.
+	ReportProgress(ctx context.Context, episode *Episode, percentage float64) error
+}
+
+// ReportProgress passes the progress of episode on to its source, if the source keeps track of it.
+func ReportProgress(ctx context.Context, episode *Episode, percentage float64) error {
+	if ps, ok := episode.Source().(ProgressSource); ok {
+		return ps.ReportProgress(ctx, episode, percentage)
+	}
+
+	return nil
+}
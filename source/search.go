@@ -0,0 +1,142 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anisan-cli/anisan/anilist"
+	"github.com/anisan-cli/anisan/mal"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// SearchRequest describes a single page of a search.
+type SearchRequest struct {
+	// Query is the free-text search term.
+	Query string
+	// Page is the 1-based page number. Zero is treated as the first page.
+	Page int
+	// Cursor is the opaque continuation token returned with the previous page, if the source uses one.
+	Cursor string
+	// Filters maps the names of filters declared by the source to the chosen values.
+	Filters map[string]string
+	// Anime is the anime being looked for, if the search is about one already known,
+	// e.g. from a tracker binding. Sources may use its IDs and synonyms to pick the right result.
+	Anime *Anime
+}
+
+// KnownAnime returns the tracker entry the query was bound to by an earlier search, or nil.
+// It is meant to be passed as the Anime of a SearchRequest.
+func KnownAnime(query string) *Anime {
+	anime := &Anime{Name: query}
+
+	if al := anilist.GetCachedRelation(query); al != nil {
+		anime.Anilist = mo.Some(al)
+		anime.Metadata.Title = al.Name()
+		anime.Metadata.Synonyms = al.Synonyms
+		anime.Metadata.StartDate = Date(al.StartDate)
+		return anime
+	}
+
+	if m := mal.GetCachedRelation(query); m != nil {
+		anime.Mal = mo.Some(m)
+		anime.Metadata.Title = m.Title
+		return anime
+	}
+
+	return nil
+}
+
+// Next returns the request for the page following p.
+func (r SearchRequest) Next(p *SearchPage) SearchRequest {
+	next := r
+	next.Page = r.Page + 1
+	if r.Page < 1 {
+		next.Page = 2
+	}
+	next.Cursor = p.Cursor
+	return next
+}
+
+// SearchPage is one page of search results.
+type SearchPage struct {
+	Animes []*Anime
+	// HasMore reports whether another page can be requested.
+	HasMore bool
+	// Cursor is the continuation token for the next page, if the source uses one.
+	Cursor string
+}
+
+// Filter describes a search filter accepted by a source, e.g. genre, year, type or sub/dub.
+type Filter struct {
+	Name  string `json:"name"`
+	Label string `json:"label,omitempty"`
+	// Options lists the accepted values. An empty list means any value is accepted.
+	Options []string `json:"options,omitempty"`
+}
+
+// PagedSource is implemented by sources that support pagination and search filters.
+type PagedSource interface {
+	Source
+
+	// Filters returns the search filters the source accepts.
+	Filters() []Filter
+
+	// SearchPage fetches a single page of results for the request.
+	SearchPage(ctx context.Context, request SearchRequest) (*SearchPage, error)
+}
+
+// SearchPageOf fetches a page of results from s. Sources without pagination
+// only have a first page, and ignore filters.
+func SearchPageOf(ctx context.Context, s Source, request SearchRequest) (*SearchPage, error) {
+	if ps, ok := s.(PagedSource); ok {
+		ctx, observe := observed(ctx, s)
+		page, err := ps.SearchPage(ctx, request)
+		observe(err)
+		return page, err
+	}
+
+	if request.Page > 1 || request.Cursor != "" {
+		return &SearchPage{}, nil
+	}
+
+	animes, err := Search(ctx, s, request.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchPage{Animes: animes}, nil
+}
+
+// FiltersOf returns the search filters declared by s, if any.
+func FiltersOf(s Source) []Filter {
+	if ps, ok := s.(PagedSource); ok {
+		return ps.Filters()
+	}
+
+	return nil
+}
+
+// ValidateFilters checks that every value targets a filter declared by s and is one of its options.
+func ValidateFilters(s Source, values map[string]string) error {
+	declared := lo.KeyBy(FiltersOf(s), func(f Filter) string { return f.Name })
+
+	names := lo.Keys(values)
+	sort.Strings(names)
+
+	for _, name := range names {
+		filter, ok := declared[name]
+		if !ok {
+			return fmt.Errorf("source %s has no search filter %q", s.Name(), name)
+		}
+
+		if len(filter.Options) > 0 && !lo.Contains(filter.Options, values[name]) {
+			return fmt.Errorf("invalid value %q for filter %q of %s, expected one of: %s",
+				values[name], name, s.Name(), strings.Join(filter.Options, ", "))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package source
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type pagedSource struct {
+	testSource
+}
+
+func (pagedSource) Filters() []Filter {
+	return []Filter{{Name: "translation", Options: []string{"sub", "dub"}}, {Name: "year"}}
+}
+
+func (pagedSource) SearchPage(_ context.Context, request SearchRequest) (*SearchPage, error) {
+	return &SearchPage{Animes: []*Anime{{Name: request.Query}}, HasMore: request.Page < 3}, nil
+}
+
+func TestSearchPageOf(t *testing.T) {
+	Convey("Given a source without pagination", t, func() {
+		Convey("Then only the first page has results", func() {
+			page, err := SearchPageOf(context.Background(), testSource{}, SearchRequest{Query: "q", Page: 2})
+			So(err, ShouldBeNil)
+			So(page.Animes, ShouldBeEmpty)
+			So(page.HasMore, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given a paged source", t, func() {
+		src := pagedSource{}
+		request := SearchRequest{Query: "q", Page: 1}
+
+		Convey("Then the next request advances the page", func() {
+			page, err := SearchPageOf(context.Background(), src, request)
+			So(err, ShouldBeNil)
+			So(page.HasMore, ShouldBeTrue)
+			So(request.Next(page).Page, ShouldEqual, 2)
+		})
+
+		Convey("Then declared filters are validated", func() {
+			So(ValidateFilters(src, map[string]string{"translation": "dub", "year": "1999"}), ShouldBeNil)
+			So(ValidateFilters(src, map[string]string{"translation": "raw"}), ShouldNotBeNil)
+			So(ValidateFilters(src, map[string]string{"genre": "drama"}), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,63 @@
+package source
+
+import "github.com/samber/lo"
+
+// Translation variants commonly returned by sources.
+const (
+	TranslationSub = "sub"
+	TranslationDub = "dub"
+	TranslationRaw = "raw"
+)
+
+// Translations returns the distinct translation variants of the episodes, in order of first appearance.
+// Episodes without a translation are not counted.
+func Translations(episodes []*Episode) []string {
+	var translations []string
+	for _, e := range episodes {
+		if e.Translation != "" && !lo.Contains(translations, e.Translation) {
+			translations = append(translations, e.Translation)
+		}
+	}
+
+	return translations
+}
+
+// PreferredTranslation picks preferred if it is available, otherwise the first available variant.
+// It returns an empty string when nothing is available.
+func PreferredTranslation(available []string, preferred string) string {
+	if lo.Contains(available, preferred) {
+		return preferred
+	}
+
+	if len(available) > 0 {
+		return available[0]
+	}
+
+	return ""
+}
+
+// FilterTranslation returns the episodes of the given translation variant.
+// Episodes without a translation belong to every variant, and an empty translation keeps all episodes.
+func FilterTranslation(episodes []*Episode, translation string) []*Episode {
+	if translation == "" {
+		return episodes
+	}
+
+	return lo.Filter(episodes, func(e *Episode, _ int) bool {
+		return e.Translation == "" || e.Translation == translation
+	})
+}
+
+// NextTranslation returns the variant following current in available, wrapping around.
+func NextTranslation(available []string, current string) string {
+	if len(available) == 0 {
+		return ""
+	}
+
+	_, i, ok := lo.FindIndexOf(available, func(t string) bool { return t == current })
+	if !ok {
+		return available[0]
+	}
+
+	return available[(i+1)%len(available)]
+}
@@ -0,0 +1,45 @@
+package source
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTranslation(t *testing.T) {
+	Convey("Given episodes in sub and dub", t, func() {
+		episodes := []*Episode{
+			{Name: "Episode 1", Translation: TranslationSub},
+			{Name: "Episode 1", Translation: TranslationDub},
+			{Name: "Episode 2", Translation: TranslationSub},
+			{Name: "Recap"},
+		}
+
+		Convey("Translations lists each variant once", func() {
+			So(Translations(episodes), ShouldResemble, []string{TranslationSub, TranslationDub})
+		})
+
+		Convey("FilterTranslation keeps the variant and untagged episodes", func() {
+			dub := FilterTranslation(episodes, TranslationDub)
+			So(len(dub), ShouldEqual, 2)
+			So(dub[0].Translation, ShouldEqual, TranslationDub)
+			So(dub[1].Name, ShouldEqual, "Recap")
+
+			So(len(FilterTranslation(episodes, "")), ShouldEqual, len(episodes))
+		})
+
+		Convey("PreferredTranslation falls back to the first available variant", func() {
+			available := Translations(episodes)
+			So(PreferredTranslation(available, TranslationDub), ShouldEqual, TranslationDub)
+			So(PreferredTranslation(available, TranslationRaw), ShouldEqual, TranslationSub)
+			So(PreferredTranslation(nil, TranslationDub), ShouldEqual, "")
+		})
+
+		Convey("NextTranslation cycles through the variants", func() {
+			available := Translations(episodes)
+			So(NextTranslation(available, TranslationSub), ShouldEqual, TranslationDub)
+			So(NextTranslation(available, TranslationDub), ShouldEqual, TranslationSub)
+			So(NextTranslation(nil, TranslationSub), ShouldEqual, "")
+		})
+	})
+}
@@ -11,6 +11,31 @@ type Video struct {
 	Headers map[string]string `json:"headers"`
 	// Ordering index.
 	Index uint16 `json:"index"`
+	// Translation is the audio variant of the stream (e.g. "sub", "dub"), if known.
+	Translation string `json:"translation,omitempty"`
+	// Language is the audio language of the stream as a BCP 47 tag (e.g. "ja", "en"), if known.
+	Language string `json:"language,omitempty"`
+	// Width and Height of the picture in pixels, if known.
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// Bandwidth is the peak bit rate in bits per second, if known.
+	Bandwidth int `json:"bandwidth,omitempty"`
+	// Codecs lists the RFC 6381 codecs of the stream (e.g. "avc1.640028,mp4a.40.2"), if known.
+	Codecs string `json:"codecs,omitempty"`
+	// Subtitles are external (soft) subtitle tracks shipped alongside the stream.
+	Subtitles []*Subtitle `json:"subtitles,omitempty"`
+}
+
+// Subtitle is an external subtitle track for a video.
+type Subtitle struct {
+	// Direct URL to the subtitle file.
+	URL string `json:"url"`
+	// Language of the track as a BCP 47 tag (e.g. "en", "pt-BR"), if known.
+	Language string `json:"language,omitempty"`
+	// File format (e.g. "vtt", "srt", "ass").
+	Format string `json:"format,omitempty"`
+	// Human-readable track name (e.g. "English [CC]").
+	Label string `json:"label,omitempty"`
 }
 
 func (v *Video) String() string {
@@ -0,0 +1,124 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/anisan-cli/anisan/log"
+	"github.com/anisan-cli/anisan/player"
+	"github.com/anisan-cli/anisan/source"
+)
+
+// demoted holds the hosts whose links failed to play during this session.
+var demoted = struct {
+	sync.Mutex
+	hosts map[string]bool
+}{hosts: make(map[string]bool)}
+
+// Demote ranks the host of the video below every working host for the rest of the session.
+func Demote(v *source.Video) {
+	host := Host(v)
+	if host == "" {
+		return
+	}
+
+	demoted.Lock()
+	defer demoted.Unlock()
+	demoted.hosts[host] = true
+}
+
+// Demoted reports whether links from the host of the video have failed before.
+func Demoted(v *source.Video) bool {
+	demoted.Lock()
+	defer demoted.Unlock()
+	return demoted.hosts[Host(v)]
+}
+
+// Fetch returns the videos of the episode ranked by the configured policy,
+// with the HLS master playlists of allowed hosts expanded if enabled. When the source of the episode
+// fails, the same episode is looked up in the other sources the anime was found in.
+func Fetch(ctx context.Context, episode *source.Episode) ([]*source.Video, error) {
+	videos, err := source.VideosOfAny(ctx, episode)
+	if err != nil {
+		return nil, err
+	}
+
+	return PolicyFromConfig().Rank(ProbeFromConfig(ctx, videos)), nil
+}
+
+// PlayFirst calls play with each video in turn until one of them plays.
+// Videos whose links are dead get their host demoted and the next one is tried,
+// any other error ends playback. Once every video has failed, refresh is called
+// once for a new set of links, since the old ones may have expired.
+func PlayFirst(
+	ctx context.Context,
+	videos []*source.Video,
+	play func(*source.Video) error,
+	refresh func(context.Context) ([]*source.Video, error),
+) error {
+	var lastErr error
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if attempt > 0 {
+			if refresh == nil {
+				break
+			}
+
+			log.Infof("every stream failed, fetching fresh links")
+			fresh, err := refresh(ctx)
+			if err != nil {
+				if lastErr == nil {
+					return fmt.Errorf("refreshing links: %w", err)
+				}
+				return fmt.Errorf("%w (refreshing links: %w)", lastErr, err)
+			}
+			videos = fresh
+		}
+
+		for _, video := range videos {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			err := play(video)
+			if err == nil || !player.IsDeadLink(err) {
+				return err
+			}
+
+			log.Warnf("stream from %s failed, demoting host: %v", Host(video), err)
+			Demote(video)
+			lastErr = err
+		}
+	}
+
+	if lastErr == nil {
+		return ErrNoStream
+	}
+
+	return fmt.Errorf("every stream failed, last error: %w", lastErr)
+}
+
+// Refresher resolves a fresh link for the video of the episode once it has expired,
+// preferring a stream of the same host and quality.
+func Refresher(episode *source.Episode, current *source.Video) player.Refresher {
+	return func(ctx context.Context) (string, map[string]string, error) {
+		videos, err := Fetch(ctx, episode)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(videos) == 0 {
+			return "", nil, ErrNoStream
+		}
+
+		fresh := videos[0]
+		for _, v := range videos {
+			if Host(v) == Host(current) && v.Quality == current.Quality {
+				fresh = v
+				break
+			}
+		}
+
+		return fresh.URL, fresh.Headers, nil
+	}
+}
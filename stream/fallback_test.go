@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/anisan-cli/anisan/player"
+	"github.com/anisan-cli/anisan/source"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPlayFirst(t *testing.T) {
+	Convey("Given three videos from different hosts", t, func() {
+		demoted.hosts = make(map[string]bool)
+		defer func() { demoted.hosts = make(map[string]bool) }()
+
+		videos := []*source.Video{
+			{URL: "https://dead.example.com/1080.m3u8", Quality: "1080p"},
+			{URL: "https://alive.example.org/720.mp4", Quality: "720p"},
+			{URL: "https://other.example.net/480.mp4", Quality: "480p"},
+		}
+
+		var played []string
+		dead := map[string]bool{videos[0].URL: true}
+		play := func(v *source.Video) error {
+			played = append(played, v.URL)
+			if dead[v.URL] {
+				return &player.DeadLinkError{URL: v.URL, Reason: "loading failed"}
+			}
+			return nil
+		}
+
+		Convey("A dead link falls through to the next video and demotes its host", func() {
+			err := PlayFirst(context.Background(), videos, play, nil)
+			So(err, ShouldBeNil)
+			So(played, ShouldResemble, []string{videos[0].URL, videos[1].URL})
+			So(Demoted(videos[0]), ShouldBeTrue)
+			So(Demoted(videos[1]), ShouldBeFalse)
+
+			Convey("Then the demoted host ranks last for the rest of the session", func() {
+				ranked := Policy{Qualities: []string{"1080p", "720p", "480p"}}.Rank(videos)
+				So(urls(ranked), ShouldResemble, []string{videos[1].URL, videos[2].URL, videos[0].URL})
+			})
+		})
+
+		Convey("Other errors end playback without trying more videos", func() {
+			boom := errors.New("mpv not installed")
+			err := PlayFirst(context.Background(), videos, func(v *source.Video) error {
+				played = append(played, v.URL)
+				return boom
+			}, nil)
+			So(err, ShouldEqual, boom)
+			So(played, ShouldHaveLength, 1)
+		})
+
+		Convey("When every link is dead", func() {
+			for _, v := range videos {
+				dead[v.URL] = true
+			}
+			fresh := &source.Video{URL: "https://alive.example.org/fresh.mp4"}
+			refreshed := 0
+			refresh := func(context.Context) ([]*source.Video, error) {
+				refreshed++
+				return []*source.Video{fresh}, nil
+			}
+
+			Convey("Fresh links are fetched once and played", func() {
+				err := PlayFirst(context.Background(), videos, play, refresh)
+				So(err, ShouldBeNil)
+				So(refreshed, ShouldEqual, 1)
+				So(played[len(played)-1], ShouldEqual, fresh.URL)
+			})
+
+			Convey("The last failure is reported if the fresh links are dead too", func() {
+				dead[fresh.URL] = true
+				err := PlayFirst(context.Background(), videos, play, refresh)
+				So(player.IsDeadLink(err), ShouldBeTrue)
+				So(refreshed, ShouldEqual, 1)
+				So(played, ShouldHaveLength, 4)
+			})
+
+			Convey("Both the last failure and the failed refresh are reported", func() {
+				expired := errors.New("episode page gone")
+				err := PlayFirst(context.Background(), videos, play, func(context.Context) ([]*source.Video, error) {
+					return nil, expired
+				})
+				So(player.IsDeadLink(err), ShouldBeTrue)
+				So(errors.Is(err, expired), ShouldBeTrue)
+			})
+		})
+
+		Convey("Without videos the failed refresh is reported", func() {
+			expired := errors.New("episode page gone")
+			err := PlayFirst(context.Background(), nil, play, func(context.Context) ([]*source.Video, error) {
+				return nil, expired
+			})
+			So(errors.Is(err, expired), ShouldBeTrue)
+			So(err.Error(), ShouldEqual, "refreshing links: episode page gone")
+		})
+	})
+}
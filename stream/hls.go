@@ -0,0 +1,317 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/anisan-cli/anisan/key"
+	"github.com/anisan-cli/anisan/log"
+	"github.com/anisan-cli/anisan/network"
+	"github.com/anisan-cli/anisan/source"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+)
+
+// maxPlaylistSize caps how much of a playlist is read when probing.
+const maxPlaylistSize = 1 << 20
+
+// probeConcurrency is how many master playlists are fetched at once when probing.
+const probeConcurrency = 4
+
+// Variant is one rendition listed in an HLS master playlist.
+type Variant struct {
+	URL       string
+	Bandwidth int
+	Width     int
+	Height    int
+	Codecs    string
+	FrameRate float64
+	// Audio is the GROUP-ID of the audio renditions the variant plays with, if any.
+	Audio string
+	// SeparateAudio is set when those renditions have their own playlist:
+	// the variant playlist then holds the picture only and must be played through the master.
+	SeparateAudio bool
+}
+
+// Quality returns a quality label for the variant, e.g. "1080p", falling back to the bandwidth.
+func (v Variant) Quality() string {
+	switch {
+	case v.Height > 0:
+		return fmt.Sprintf("%dp", v.Height)
+	case v.Bandwidth > 0:
+		return fmt.Sprintf("%.1f Mbps", float64(v.Bandwidth)/1e6)
+	default:
+		return ""
+	}
+}
+
+// audioOnly reports whether every codec of the variant is an audio codec.
+func (v Variant) audioOnly() bool {
+	if v.Codecs == "" || v.Height > 0 {
+		return false
+	}
+
+	for _, c := range strings.Split(v.Codecs, ",") {
+		c = strings.TrimSpace(c)
+		if !strings.HasPrefix(c, "mp4a") && !strings.HasPrefix(c, "ac-3") && !strings.HasPrefix(c, "ec-3") && !strings.HasPrefix(c, "opus") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ErrNotPlaylist is returned when the input does not start with #EXTM3U.
+var ErrNotPlaylist = errors.New("not an HLS playlist")
+
+// ParseMasterPlaylist reads the variants of an HLS master playlist, resolving their
+// URLs against base. A media playlist has no variants and yields an empty list.
+// Variants whose audio group is listed by #EXT-X-MEDIA with a URI are marked SeparateAudio.
+func ParseMasterPlaylist(r io.Reader, base *url.URL) ([]Variant, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxPlaylistSize)
+
+	var (
+		variants []Variant
+		pending  *Variant
+		header   bool
+		// Audio groups by GROUP-ID, true when one of their renditions has its own playlist
+		audio = make(map[string]bool)
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if !header {
+			if !strings.HasPrefix(line, "#EXTM3U") {
+				return nil, ErrNotPlaylist
+			}
+			header = true
+			continue
+		}
+
+		if attrs, ok := strings.CutPrefix(line, "#EXT-X-STREAM-INF:"); ok {
+			v := variantFromAttributes(parseAttributes(attrs))
+			pending = &v
+			continue
+		}
+
+		if list, ok := strings.CutPrefix(line, "#EXT-X-MEDIA:"); ok {
+			attrs := parseAttributes(list)
+			if attrs["TYPE"] == "AUDIO" {
+				audio[attrs["GROUP-ID"]] = audio[attrs["GROUP-ID"]] || attrs["URI"] != ""
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// The first URI line after #EXT-X-STREAM-INF belongs to it
+		if pending != nil {
+			ref, err := url.Parse(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid variant URI %q: %w", line, err)
+			}
+			if base != nil {
+				ref = base.ResolveReference(ref)
+			}
+			pending.URL = ref.String()
+			variants = append(variants, *pending)
+			pending = nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !header {
+		return nil, ErrNotPlaylist
+	}
+
+	// #EXT-X-MEDIA may follow the variants that refer to it
+	for i := range variants {
+		variants[i].SeparateAudio = audio[variants[i].Audio]
+	}
+
+	return variants, nil
+}
+
+func variantFromAttributes(attrs map[string]string) Variant {
+	var v Variant
+	v.Bandwidth, _ = strconv.Atoi(attrs["BANDWIDTH"])
+	v.Codecs = attrs["CODECS"]
+	v.FrameRate, _ = strconv.ParseFloat(attrs["FRAME-RATE"], 64)
+	v.Audio = attrs["AUDIO"]
+
+	if w, h, ok := strings.Cut(attrs["RESOLUTION"], "x"); ok {
+		v.Width, _ = strconv.Atoi(w)
+		v.Height, _ = strconv.Atoi(h)
+	}
+
+	return v
+}
+
+// parseAttributes parses an HLS attribute list such as
+// BANDWIDTH=1280000,RESOLUTION=854x480,CODECS="avc1.4d401e,mp4a.40.2".
+func parseAttributes(list string) map[string]string {
+	attrs := make(map[string]string)
+
+	for list != "" {
+		name, rest, ok := strings.Cut(list, "=")
+		if !ok {
+			break
+		}
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+			rest = strings.TrimPrefix(rest, ",")
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		attrs[strings.ToUpper(strings.TrimSpace(name))] = value
+		list = rest
+	}
+
+	return attrs
+}
+
+// ProbeFromConfig probes videos unless disabled with stream.probe_hls.
+// Videos the configured policy does not allow are kept unprobed, so that their hosts are never contacted.
+func ProbeFromConfig(ctx context.Context, videos []*source.Video) []*source.Video {
+	if !viper.GetBool(key.StreamProbeHLS) {
+		return videos
+	}
+
+	policy := PolicyFromConfig()
+	allowed, denied := lo.FilterReject(videos, func(v *source.Video, _ int) bool { return policy.Allowed(v) })
+
+	return append(Probe(ctx, network.Client, allowed), denied...)
+}
+
+// Probe replaces every HLS master playlist without a quality among videos with one
+// video per variant, followed by the master playlist itself as the "auto" quality.
+// Variants with separate audio keep the URL of the master, so that the sound is not lost,
+// and are told apart by their Bandwidth, which players use to pick the rendition.
+// Videos that cannot be probed are kept as they are.
+// Up to probeConcurrency playlists are fetched at once.
+func Probe(ctx context.Context, client *http.Client, videos []*source.Video) []*source.Video {
+	if client == nil {
+		client = network.Client
+	}
+
+	var (
+		variants = make([][]Variant, len(videos))
+		slots    = make(chan struct{}, probeConcurrency)
+		wg       sync.WaitGroup
+	)
+
+	for i, v := range videos {
+		if v.Quality != "" || Container(v) != "m3u8" {
+			continue
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, v *source.Video) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			var err error
+			variants[i], err = fetchVariants(ctx, client, v)
+			if err != nil {
+				log.Warnf("probing %s: %v", v.URL, err)
+			}
+		}(i, v)
+	}
+	wg.Wait()
+
+	expanded := make([]*source.Video, 0, len(videos))
+	for i, v := range videos {
+		if len(variants[i]) == 0 {
+			expanded = append(expanded, v)
+			continue
+		}
+
+		for _, variant := range variants[i] {
+			expanded = append(expanded, variantVideo(v, variant))
+		}
+
+		auto := *v
+		auto.Quality = "auto"
+		expanded = append(expanded, &auto)
+	}
+
+	return expanded
+}
+
+func fetchVariants(ctx context.Context, client *http.Client, v *source.Video) ([]Variant, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, val := range v.Headers {
+		req.Header.Set(k, val)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	variants, err := ParseMasterPlaylist(io.LimitReader(res.Body, maxPlaylistSize), res.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Audio-only renditions are not worth offering as a video quality
+	playable := variants[:0]
+	for _, variant := range variants {
+		if !variant.audioOnly() {
+			playable = append(playable, variant)
+		}
+	}
+
+	return playable, nil
+}
+
+func variantVideo(master *source.Video, variant Variant) *source.Video {
+	v := *master
+	if !variant.SeparateAudio {
+		v.URL = variant.URL
+	}
+	v.Quality = variant.Quality()
+	v.Extension = "m3u8"
+	v.Width = variant.Width
+	v.Height = variant.Height
+	v.Bandwidth = variant.Bandwidth
+	v.Codecs = variant.Codecs
+	return &v
+}
@@ -0,0 +1,175 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/anisan-cli/anisan/key"
+	"github.com/anisan-cli/anisan/source"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+)
+
+func TestParseMasterPlaylist(t *testing.T) {
+	Convey("Given a master playlist fixture", t, func() {
+		file, err := os.Open("testdata/master.m3u8")
+		So(err, ShouldBeNil)
+		defer file.Close()
+
+		base, _ := url.Parse("https://example.com/hls/master.m3u8")
+		variants, err := ParseMasterPlaylist(file, base)
+		So(err, ShouldBeNil)
+
+		Convey("Every variant is read with its attributes", func() {
+			So(variants, ShouldHaveLength, 4)
+			So(variants[1], ShouldResemble, Variant{
+				URL:           "https://cdn.example.com/1080/index.m3u8?token=abc",
+				Bandwidth:     5000000,
+				Width:         1920,
+				Height:        1080,
+				Codecs:        "avc1.640028,mp4a.40.2",
+				FrameRate:     23.976,
+				Audio:         "aac",
+				SeparateAudio: true,
+			})
+			So(variants[0].Quality(), ShouldEqual, "480p")
+		})
+
+		Convey("Variant URIs are resolved against the playlist URL", func() {
+			So(variants[0].URL, ShouldEqual, "https://example.com/hls/480/index.m3u8")
+			So(variants[2].URL, ShouldEqual, "https://example.com/hls/720/index.m3u8")
+		})
+
+		Convey("Variants without an audio group have their sound muxed in", func() {
+			So(variants[3].Audio, ShouldBeEmpty)
+			So(variants[3].SeparateAudio, ShouldBeFalse)
+		})
+
+		Convey("Audio-only variants are recognised", func() {
+			So(variants[3].audioOnly(), ShouldBeTrue)
+			So(variants[0].audioOnly(), ShouldBeFalse)
+		})
+	})
+
+	Convey("A media playlist has no variants", t, func() {
+		file, err := os.Open("testdata/media.m3u8")
+		So(err, ShouldBeNil)
+		defer file.Close()
+
+		variants, err := ParseMasterPlaylist(file, nil)
+		So(err, ShouldBeNil)
+		So(variants, ShouldBeEmpty)
+	})
+
+	Convey("Anything else is rejected", t, func() {
+		_, err := ParseMasterPlaylist(strings.NewReader("<html></html>"), nil)
+		So(errors.Is(err, ErrNotPlaylist), ShouldBeTrue)
+	})
+}
+
+func TestProbe(t *testing.T) {
+	Convey("Given a server hosting a master playlist with muxed audio", t, func() {
+		var referer string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/master.m3u8" {
+				http.NotFound(w, r)
+				return
+			}
+			referer = r.Header.Get("Referer")
+			http.ServeFile(w, r, "testdata/muxed.m3u8")
+		}))
+		defer server.Close()
+
+		master := &source.Video{
+			URL:     server.URL + "/master.m3u8",
+			Headers: map[string]string{"Referer": "https://example.com"},
+		}
+		mp4 := &source.Video{URL: server.URL + "/video.mp4"}
+
+		videos := Probe(context.Background(), server.Client(), []*source.Video{mp4, master})
+
+		Convey("The playlist is fetched with the video headers", func() {
+			So(referer, ShouldEqual, "https://example.com")
+		})
+
+		Convey("The master is expanded into its video variants, then kept as auto", func() {
+			So(videos, ShouldHaveLength, 5)
+			So(videos[0], ShouldEqual, mp4)
+			So(videos[1].Quality, ShouldEqual, "480p")
+			So(videos[1].URL, ShouldEqual, server.URL+"/480/index.m3u8")
+			So(videos[1].Headers["Referer"], ShouldEqual, "https://example.com")
+			So(videos[2].Height, ShouldEqual, 1080)
+			So(videos[2].Bandwidth, ShouldEqual, 5000000)
+			So(videos[4].Quality, ShouldEqual, "auto")
+			So(videos[4].URL, ShouldEqual, master.URL)
+		})
+
+		Convey("The policy can now rank by real resolution", func() {
+			best, err := Policy{Qualities: []string{"720p"}}.Select(videos)
+			So(err, ShouldBeNil)
+			So(best.Height, ShouldEqual, 720)
+		})
+
+		Convey("Playlists that cannot be fetched are kept as they are", func() {
+			missing := &source.Video{URL: server.URL + "/missing.m3u8"}
+			So(Probe(context.Background(), server.Client(), []*source.Video{missing}), ShouldResemble, []*source.Video{missing})
+		})
+	})
+}
+
+func TestProbeSeparateAudio(t *testing.T) {
+	Convey("Given a master playlist whose audio has its own rendition", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, "testdata/master.m3u8")
+		}))
+		defer server.Close()
+
+		master := &source.Video{URL: server.URL + "/master.m3u8"}
+		videos := Probe(context.Background(), server.Client(), []*source.Video{master})
+
+		Convey("Then its variants are offered through the master, so the audio is kept", func() {
+			So(videos, ShouldHaveLength, 4)
+			for _, v := range videos {
+				So(v.URL, ShouldEqual, master.URL)
+			}
+		})
+
+		Convey("Then the picked variant is told apart by its bit rate", func() {
+			best, err := Policy{}.Select(videos)
+			So(err, ShouldBeNil)
+			So(best.Quality, ShouldEqual, "1080p")
+			So(best.URL, ShouldEqual, master.URL)
+			So(best.Bandwidth, ShouldEqual, 5000000)
+		})
+	})
+}
+
+func TestProbeFromConfig(t *testing.T) {
+	Convey("Given a master playlist on a denied host", t, func() {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			http.ServeFile(w, r, "testdata/master.m3u8")
+		}))
+		defer server.Close()
+
+		viper.Set(key.StreamProbeHLS, true)
+		viper.Set(key.StreamDenyHosts, []string{"127.0.0.1"})
+		defer viper.Set(key.StreamDenyHosts, nil)
+
+		master := &source.Video{URL: server.URL + "/master.m3u8"}
+		videos := ProbeFromConfig(context.Background(), []*source.Video{master})
+
+		Convey("Then the host is not contacted and the policy drops the video", func() {
+			So(requests, ShouldEqual, 0)
+			So(videos, ShouldResemble, []*source.Video{master})
+			So(PolicyFromConfig().Rank(videos), ShouldBeEmpty)
+		})
+	})
+}
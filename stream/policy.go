@@ -0,0 +1,222 @@
+// Package stream decides which of the videos returned by a source gets played.
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anisan-cli/anisan/key"
+	"github.com/anisan-cli/anisan/player"
+	"github.com/anisan-cli/anisan/source"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+)
+
+// ErrNoStream is returned when none of the videos of an episode may be played.
+var ErrNoStream = errors.New("no playable stream")
+
+// Policy ranks the videos of an episode according to the user's preferences.
+type Policy struct {
+	// Qualities is the ordered quality preference, e.g. ["1080p", "720p"].
+	// Unlisted qualities come after the listed ones, highest resolution first.
+	Qualities []string
+	// Container is the preferred container, e.g. "mp4" or "m3u8". Empty means no preference.
+	Container string
+	// AllowHosts, if not empty, restricts playback to these hosts and their subdomains.
+	AllowHosts []string
+	// DenyHosts are hosts, and their subdomains, that are never played.
+	DenyHosts []string
+	// Ask makes interactive front ends let the user pick when several streams are playable.
+	Ask bool
+}
+
+// PolicyFromConfig builds the policy from the stream.* configuration keys.
+func PolicyFromConfig() Policy {
+	return Policy{
+		Qualities:  viper.GetStringSlice(key.StreamQuality),
+		Container:  strings.ToLower(strings.TrimPrefix(viper.GetString(key.StreamContainer), ".")),
+		AllowHosts: viper.GetStringSlice(key.StreamAllowHosts),
+		DenyHosts:  viper.GetStringSlice(key.StreamDenyHosts),
+		Ask:        viper.GetBool(key.StreamAsk),
+	}
+}
+
+// Allowed reports whether the host of v passes the allow and deny lists.
+// Local files have no host to filter by and are always allowed.
+func (p Policy) Allowed(v *source.Video) bool {
+	if isLocal(v) {
+		return true
+	}
+
+	host := Host(v)
+
+	if lo.SomeBy(p.DenyHosts, func(h string) bool { return hostMatches(host, h) }) {
+		return false
+	}
+
+	return len(p.AllowHosts) == 0 || lo.SomeBy(p.AllowHosts, func(h string) bool { return hostMatches(host, h) })
+}
+
+// isLocal reports whether v is a file on disk: an absolute path or a file url.
+// Scheme-relative urls such as "//example.com/video.mp4" name a host and are not local.
+func isLocal(v *source.Video) bool {
+	if u, err := url.Parse(v.URL); err == nil {
+		if u.Scheme == "file" {
+			return true
+		}
+		if u.Host != "" {
+			return false
+		}
+	}
+
+	return filepath.IsAbs(v.URL)
+}
+
+// Rank returns the allowed videos, most preferred first.
+// Hosts demoted during this session come last.
+// Videos the policy cannot tell apart keep the order of the source.
+func (p Policy) Rank(videos []*source.Video) []*source.Video {
+	ranked := lo.Filter(videos, func(v *source.Video, _ int) bool { return p.Allowed(v) })
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+
+		if da, db := Demoted(a), Demoted(b); da != db {
+			return db
+		}
+
+		if qa, qb := p.qualityRank(a), p.qualityRank(b); qa != qb {
+			return qa < qb
+		}
+
+		if ra, rb := height(a), height(b); ra != rb {
+			return ra > rb
+		}
+
+		if p.Container != "" {
+			if ca, cb := Container(a) == p.Container, Container(b) == p.Container; ca != cb {
+				return ca
+			}
+		}
+
+		return a.Bandwidth > b.Bandwidth
+	})
+
+	return ranked
+}
+
+// Select returns the most preferred allowed video.
+func (p Policy) Select(videos []*source.Video) (*source.Video, error) {
+	ranked := p.Rank(videos)
+	if len(ranked) == 0 {
+		if len(videos) > 0 {
+			return nil, fmt.Errorf("%w: all %d streams are excluded by %s/%s", ErrNoStream, len(videos), key.StreamAllowHosts, key.StreamDenyHosts)
+		}
+		return nil, ErrNoStream
+	}
+
+	return ranked[0], nil
+}
+
+// qualityRank is the position of the video's quality in the preference list,
+// or the length of the list if it is not listed.
+func (p Policy) qualityRank(v *source.Video) int {
+	quality := strings.ToLower(strings.TrimSpace(v.Quality))
+	resolution := height(v)
+
+	for i, q := range p.Qualities {
+		q = strings.ToLower(strings.TrimSpace(q))
+		if q == quality || (resolution > 0 && Resolution(q) == resolution) {
+			return i
+		}
+	}
+
+	return len(p.Qualities)
+}
+
+var (
+	resolutionPattern = regexp.MustCompile(`(?:\d+x)?(\d{3,4})`)
+	namedResolutions  = map[string]int{"4k": 2160, "2k": 1440, "fhd": 1080, "hd": 720, "sd": 480}
+)
+
+// Resolution extracts the vertical resolution from a quality label such as
+// "1080p", "1920x1080" or "FHD". It returns 0 if the label has none.
+func Resolution(quality string) int {
+	quality = strings.ToLower(strings.TrimSpace(quality))
+	if r, ok := namedResolutions[quality]; ok {
+		return r
+	}
+
+	match := resolutionPattern.FindStringSubmatch(quality)
+	if match == nil {
+		return 0
+	}
+
+	r, _ := strconv.Atoi(match[1])
+	return r
+}
+
+// height is the vertical resolution of v, probed or taken from its quality label.
+func height(v *source.Video) int {
+	if v.Height > 0 {
+		return v.Height
+	}
+
+	return Resolution(v.Quality)
+}
+
+// Container returns the container of v, e.g. "mp4" or "m3u8", from its extension or URL.
+func Container(v *source.Video) string {
+	if v.Extension != "" {
+		return strings.ToLower(strings.TrimPrefix(v.Extension, "."))
+	}
+
+	u, err := url.Parse(v.URL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+}
+
+// Host returns the lower-cased host name of the video URL.
+func Host(v *source.Video) string {
+	u, err := url.Parse(v.URL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
+
+// Label describes v for stream pickers, e.g. "1080p • m3u8 • cdn.example.com".
+func Label(v *source.Video) string {
+	parts := []string{lo.Ternary(v.Quality != "", v.Quality, "unknown quality")}
+	if c := Container(v); c != "" {
+		parts = append(parts, c)
+	}
+	if h := Host(v); h != "" {
+		parts = append(parts, h)
+	}
+
+	return strings.Join(parts, " • ")
+}
+
+func hostMatches(host, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(pattern), "*."))
+	return pattern != "" && (host == pattern || strings.HasSuffix(host, "."+pattern))
+}
+
+// Subtitles converts the subtitle tracks of v for the player.
+func Subtitles(v *source.Video) []player.Subtitle {
+	return lo.Map(v.Subtitles, func(s *source.Subtitle, _ int) player.Subtitle {
+		return player.Subtitle{URL: s.URL, Language: s.Language}
+	})
+}
@@ -0,0 +1,106 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/anisan-cli/anisan/source"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func urls(videos []*source.Video) []string {
+	out := make([]string, len(videos))
+	for i, v := range videos {
+		out[i] = v.URL
+	}
+	return out
+}
+
+func TestPolicy(t *testing.T) {
+	Convey("Given the videos of an episode", t, func() {
+		videos := []*source.Video{
+			{URL: "https://a.example.com/480.mp4", Quality: "480p"},
+			{URL: "https://b.example.org/1080.m3u8", Quality: "1080p"},
+			{URL: "https://cdn.bad.net/1080.mp4", Quality: "1920x1080"},
+			{URL: "https://a.example.com/720.mp4", Quality: "720p"},
+			{URL: "https://a.example.com/unknown"},
+		}
+
+		Convey("Rank follows the quality preference, then resolution", func() {
+			p := Policy{Qualities: []string{"720p"}}
+			So(urls(p.Rank(videos)), ShouldResemble, []string{
+				"https://a.example.com/720.mp4",
+				"https://b.example.org/1080.m3u8",
+				"https://cdn.bad.net/1080.mp4",
+				"https://a.example.com/480.mp4",
+				"https://a.example.com/unknown",
+			})
+		})
+
+		Convey("The preferred container breaks ties", func() {
+			p := Policy{Qualities: []string{"1080p"}, Container: "mp4"}
+			So(urls(p.Rank(videos))[:2], ShouldResemble, []string{
+				"https://cdn.bad.net/1080.mp4",
+				"https://b.example.org/1080.m3u8",
+			})
+		})
+
+		Convey("The preferred container wins over the bit rate of a probed variant", func() {
+			p := Policy{Qualities: []string{"1080p"}, Container: "mp4"}
+			probed := []*source.Video{
+				{URL: "https://b.example.org/1080.m3u8", Quality: "1080p", Height: 1080, Bandwidth: 5000000},
+				{URL: "https://a.example.com/1080.mp4", Quality: "1080p", Height: 1080},
+			}
+			So(urls(p.Rank(probed)), ShouldResemble, []string{
+				"https://a.example.com/1080.mp4",
+				"https://b.example.org/1080.m3u8",
+			})
+		})
+
+		Convey("Denied hosts and their subdomains are never selected", func() {
+			p := Policy{Qualities: []string{"1080p"}, Container: "mp4", DenyHosts: []string{"bad.net"}}
+			v, err := p.Select(videos)
+			So(err, ShouldBeNil)
+			So(v.URL, ShouldEqual, "https://b.example.org/1080.m3u8")
+		})
+
+		Convey("An allow list keeps only the listed hosts", func() {
+			p := Policy{AllowHosts: []string{"example.com"}}
+			So(urls(p.Rank(videos)), ShouldResemble, []string{
+				"https://a.example.com/720.mp4",
+				"https://a.example.com/480.mp4",
+				"https://a.example.com/unknown",
+			})
+		})
+
+		Convey("Local files pass the host lists", func() {
+			p := Policy{AllowHosts: []string{"example.com"}}
+			So(p.Allowed(&source.Video{URL: "/anime/Title - 01.mkv"}), ShouldBeTrue)
+		})
+
+		Convey("Scheme-relative urls do not pass as local files", func() {
+			p := Policy{DenyHosts: []string{"denied.host"}}
+			So(p.Allowed(&source.Video{URL: "//denied.host/x"}), ShouldBeFalse)
+			So(p.Allowed(&source.Video{URL: "file:///anime/Title%20-%2001.mkv"}), ShouldBeTrue)
+		})
+
+		Convey("Select fails when every stream is excluded", func() {
+			p := Policy{AllowHosts: []string{"nowhere.invalid"}}
+			_, err := p.Select(videos)
+			So(errors.Is(err, ErrNoStream), ShouldBeTrue)
+		})
+	})
+
+	Convey("Resolution", t, func() {
+		So(Resolution("1080p"), ShouldEqual, 1080)
+		So(Resolution("1280x720"), ShouldEqual, 720)
+		So(Resolution("720p60"), ShouldEqual, 720)
+		So(Resolution("FHD"), ShouldEqual, 1080)
+		So(Resolution("auto"), ShouldEqual, 0)
+	})
+
+	Convey("Container", t, func() {
+		So(Container(&source.Video{URL: "https://x/master.M3U8?token=1"}), ShouldEqual, "m3u8")
+		So(Container(&source.Video{URL: "https://x/video", Extension: ".mp4"}), ShouldEqual, "mp4")
+	})
+}
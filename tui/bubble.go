@@ -2,6 +2,7 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sync"
@@ -49,6 +50,7 @@ type statefulBubble struct {
 	episodesC  list.Model
 	trackerC   list.Model
 	postWatchC list.Model
+	streamsC   list.Model
 	progressC  progress.Model
 	helpC      help.Model
 	idInputC   textinput.Model // idInputC handles manual overrides for MyAnimeList or AniList IDs
@@ -65,15 +67,22 @@ type statefulBubble struct {
 	episodesD  list.DefaultDelegate
 	trackerD   list.DefaultDelegate
 	postWatchD list.DefaultDelegate
+	streamsD   list.DefaultDelegate
 	lastSearchID int
 
 	selectedProviders map[*provider.Provider]struct{}
 	selectedSources   []source.Source
+	openedSources     []source.Source                        // Every source created this session, closed on exit
+	searchNext        map[source.Source]source.SearchRequest // Next page of the current search, per source
 	selectedAnime     *source.Anime
 	selectedEpisodes  map[*source.Episode]struct{} // Set of episodes selected for batch operations
+	loadedEpisodes    []*source.Episode            // Every audio variant of the selected anime's episodes
+	translation       string                       // Audio variant shown in the episodes list (e.g. "sub", "dub")
+
+	operationCancel context.CancelFunc // Aborts the search, episode or video request in flight
 
 	sourcesLoadedChannel        chan []source.Source
-	foundAnimesChannel          chan []*source.Anime
+	foundAnimesChannel          chan searchResultsMsg
 	foundEpisodesChannel        chan []*source.Episode
 	fetchedTrackerAnimesChannel chan any // Transports []mal.Anime or []*anilist.Anime
 	closestTrackerAnimeChannel  chan any // Transports *mal.Anime or *anilist.Anime
@@ -209,6 +218,10 @@ func (b *statefulBubble) resize(width, height int) tea.Cmd {
 	b.postWatchC.SetSize(listWidth, listHeight)
 	b.postWatchC.Help.Width = listWidth
 
+	updateDelegate(&b.streamsC, &b.streamsD)
+	b.streamsC.SetSize(listWidth, listHeight)
+	b.streamsC.Help.Width = listWidth
+
 	b.progressC.Width = listWidth
 	b.idInputC.Width = listWidth
 
@@ -263,7 +276,7 @@ func newBubble(options *Options) *statefulBubble {
 		keymap:        keymap,
 
 		sourcesLoadedChannel:        make(chan []source.Source),
-		foundAnimesChannel:          make(chan []*source.Anime),
+		foundAnimesChannel:          make(chan searchResultsMsg),
 		foundEpisodesChannel:        make(chan []*source.Episode),
 		fetchedTrackerAnimesChannel: make(chan any),
 		closestTrackerAnimeChannel:  make(chan any),
@@ -332,7 +345,7 @@ func newBubble(options *Options) *statefulBubble {
 
 	bubble.idInputC = textinput.New()
 	bubble.idInputC.Placeholder = "Enter Manual ID"
-	bubble.idInputC.CharLimit = 20
+	bubble.idInputC.CharLimit = 32
 	bubble.idInputC.Prompt = "MAL/Anilist ID: "
 
 	bubble.sourcesC, bubble.sourcesD = makeList("Anime Sources", false, &listOptions{
@@ -386,6 +399,13 @@ func newBubble(options *Options) *statefulBubble {
 	})
 	bubble.postWatchC.SetStatusBarItemName("option", "options")
 
+	bubble.streamsC, bubble.streamsD = makeList("Streams", true, &listOptions{
+		TitleStyle: mo.Some(
+			lipgloss.NewStyle().Foreground(style.Base).Background(style.Green).Padding(0, 1),
+		),
+	})
+	bubble.streamsC.SetStatusBarItemName("stream", "streams")
+
 	if w, h, err := util.TerminalSize(); err == nil {
 		bubble.resize(w, h)
 	}
@@ -2,22 +2,22 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 
-	"runtime"
-
 	"github.com/anisan-cli/anisan/anilist"
+	"github.com/anisan-cli/anisan/health"
 	"github.com/anisan-cli/anisan/history"
-	"github.com/anisan-cli/anisan/key"
 	"github.com/anisan-cli/anisan/log"
 	"github.com/anisan-cli/anisan/mal"
 	"github.com/anisan-cli/anisan/player"
 	"github.com/anisan-cli/anisan/provider"
 	"github.com/anisan-cli/anisan/source"
+	"github.com/anisan-cli/anisan/stream"
 	"github.com/anisan-cli/anisan/util"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -25,6 +25,27 @@ import (
 	"github.com/spf13/viper"
 )
 
+// beginOperation cancels any source call still in flight and returns the context
+// for the next one, so that only the most recent request can deliver results.
+func (b *statefulBubble) beginOperation() context.Context {
+	b.cancelOperation()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.operationCancel = cancel
+	return ctx
+}
+
+// cancelOperation aborts the source call in flight, if any, and reports whether there was one.
+func (b *statefulBubble) cancelOperation() bool {
+	if b.operationCancel == nil {
+		return false
+	}
+
+	b.operationCancel()
+	b.operationCancel = nil
+	return true
+}
+
 func (b *statefulBubble) loadProviders() tea.Cmd {
 	providers := provider.Builtins()
 	customProviders := provider.Customs()
@@ -38,6 +59,7 @@ func (b *statefulBubble) loadProviders() tea.Cmd {
 	sort.Slice(items, func(i, j int) bool {
 		return strings.Compare(items[i].FilterValue(), items[j].FilterValue()) < 0
 	})
+	health.Sort(items, list.Item.FilterValue)
 
 	var customItems []list.Item
 	for _, p := range customProviders {
@@ -48,10 +70,25 @@ func (b *statefulBubble) loadProviders() tea.Cmd {
 	sort.Slice(customItems, func(i, j int) bool {
 		return strings.Compare(customItems[i].FilterValue(), customItems[j].FilterValue()) < 0
 	})
+	health.Sort(customItems, list.Item.FilterValue)
 
 	return b.sourcesC.SetItems(append(items, customItems...))
 }
 
+// animeItems wraps search results as list items, followed by a "load more" entry when more pages exist.
+func animeItems(animes []*source.Anime, hasMore bool) []list.Item {
+	items := make([]list.Item, 0, len(animes)+1)
+	for _, a := range animes {
+		items = append(items, &listItem{internal: a})
+	}
+
+	if hasMore {
+		items = append(items, &listItem{internal: loadMoreItem{}})
+	}
+
+	return items
+}
+
 // metadataPopulatedMsg is sent by batchPopulateMetadata after each anime's metadata has been fetched.
 // Delivering this through the Bubbletea event loop triggers a proper UI re-render.
 type metadataPopulatedMsg struct {
@@ -233,43 +270,138 @@ func (b *statefulBubble) waitForSourcesLoaded() tea.Cmd {
 	}
 }
 
-func (b *statefulBubble) searchAnime(query string) tea.Cmd {
+// searchResultsMsg carries one page of search results from every queried source.
+type searchResultsMsg struct {
+	animes []*source.Anime
+	next   map[source.Source]source.SearchRequest // Follow-up requests for sources with more pages
+	more   bool                                   // Results extend the current list rather than replace it
+	failed []source.Source                        // Sources that failed while others answered
+	// Listed animes whose group grew with the new page, replaced by a copy of the group
+	regrouped map[*source.Anime]*source.Anime
+}
+
+// failedSourcesNotice tells that the named sources could not be searched.
+func failedSourcesNotice(failed []source.Source) string {
+	names := lo.Map(failed, func(s source.Source, _ int) string { return s.Name() })
+	sort.Strings(names)
+	return fmt.Sprintf("%s failed, showing results of the other sources", strings.Join(names, ", "))
+}
+
+func (b *statefulBubble) searchAnime(ctx context.Context, query string) tea.Cmd {
+	known := source.KnownAnime(query)
+	requests := make(map[source.Source]source.SearchRequest, len(b.selectedSources))
+	for _, s := range b.selectedSources {
+		requests[s] = source.SearchRequest{Query: query, Page: 1, Anime: known}
+	}
+
+	return b.searchPages(ctx, requests, false)
+}
+
+// searchMore fetches the next page from every source that reported more results.
+func (b *statefulBubble) searchMore(ctx context.Context) tea.Cmd {
+	return b.searchPages(ctx, b.searchNext, true)
+}
+
+func (b *statefulBubble) searchPages(ctx context.Context, requests map[source.Source]source.SearchRequest, more bool) tea.Cmd {
+	// Further pages are grouped with the results already listed, read here since the list belongs to the update loop
+	var listed []*source.Anime
+	if more {
+		for _, item := range b.animesC.Items() {
+			if anime, ok := item.(*listItem).internal.(*source.Anime); ok {
+				listed = append(listed, anime)
+			}
+		}
+	}
+
 	// Search across all active providers.
 	return func() tea.Msg {
-		log.Info("searching for " + query)
-
-		var animes = make([]*source.Anime, 0)
-		var mutex sync.Mutex
+		var (
+			animes    = make([]*source.Anime, 0)
+			next      = make(map[source.Source]source.SearchRequest)
+			failed    []source.Source
+			lastErr   error
+			mutex     sync.Mutex
+			regrouped = make(map[*source.Anime]*source.Anime)
+		)
 
 		wg := sync.WaitGroup{}
-		wg.Add(len(b.selectedSources))
-		for _, s := range b.selectedSources {
-			go func(s source.Source) {
+		wg.Add(len(requests))
+		for s, request := range requests {
+			go func(s source.Source, request source.SearchRequest) {
 				defer wg.Done()
-				sourceAnimes, err := s.Search(query)
+				log.Infof("searching for %s (page %d) in %s", request.Query, request.Page, s.Name())
+				page, err := source.SearchPageOf(ctx, s, request)
 
 				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+
+					// One failing source must not hide the results of the others
 					log.Error(err)
-					b.errorChannel <- err
+					mutex.Lock()
+					failed = append(failed, s)
+					lastErr = err
+					mutex.Unlock()
 					return
 				}
 
-				log.Infof("found %s from source %s", util.Quantify(len(sourceAnimes), "anime", "animes"), s.Name())
+				log.Infof("found %s from source %s", util.Quantify(len(page.Animes), "anime", "animes"), s.Name())
 				mutex.Lock()
-				animes = append(animes, sourceAnimes...)
+				animes = append(animes, page.Animes...)
+				if page.HasMore {
+					next[s] = request.Next(page)
+				}
 				mutex.Unlock()
-			}(s)
+			}(s, request)
 		}
 
 		wg.Wait()
 
-		log.Infof("found %d animes from %d sources", len(animes), len(b.selectedSources))
-		b.foundAnimesChannel <- animes
+		if ctx.Err() != nil {
+			log.Info("search cancelled")
+			return nil
+		}
+
+		if len(requests) > 0 && len(failed) == len(requests) {
+			select {
+			case b.errorChannel <- lastErr:
+			case <-ctx.Done():
+			}
+			return nil
+		}
+
+		log.Infof("found %d animes from %d sources", len(animes), len(requests))
+
+		// The same show found by several sources is listed once, its other results kept as alternatives
+		if len(b.selectedSources) > 1 {
+			var query string
+			for _, request := range requests {
+				query = request.Query
+				break
+			}
+			if more {
+				// The listed animes are read by the update loop, so grown groups come back as copies
+				var merged []*source.Anime
+				merged, animes = source.MergeAnimes(query, listed, animes)
+				for i, anime := range merged {
+					if anime != listed[i] {
+						regrouped[listed[i]] = anime
+					}
+				}
+			} else {
+				animes = source.GroupAnimes(query, animes)
+			}
+		}
+		select {
+		case b.foundAnimesChannel <- searchResultsMsg{animes: animes, next: next, more: more, failed: failed, regrouped: regrouped}:
+		case <-ctx.Done():
+		}
 		return nil
 	}
 }
 
-func (b *statefulBubble) waitForAnimes() tea.Cmd {
+func (b *statefulBubble) waitForAnimes(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
 		select {
 		case found := <-b.foundAnimesChannel:
@@ -277,28 +409,67 @@ func (b *statefulBubble) waitForAnimes() tea.Cmd {
 		case err := <-b.errorChannel:
 			b.lastError = err
 			return err
+		case <-ctx.Done():
+			return nil
 		}
 	}
 }
 
-func (b *statefulBubble) getEpisodes(anime *source.Anime) tea.Cmd {
+func (b *statefulBubble) getEpisodes(ctx context.Context, anime *source.Anime) tea.Cmd {
 	// Get episodes from source.
 	return func() tea.Msg {
 		log.Info("getting episodes of " + anime.Name)
-		episodes, err := anime.Source.EpisodesOf(anime)
+		episodes, err := source.EpisodesOfAny(ctx, anime)
+		if ctx.Err() != nil {
+			log.Infof("loading episodes of %s cancelled", anime.Name)
+			return nil
+		}
+
 		if err != nil {
 			log.Error(err)
-			b.errorChannel <- err
+			select {
+			case b.errorChannel <- err:
+			case <-ctx.Done():
+			}
 		} else {
 			log.Infof("found %s", util.Quantify(len(episodes), "episode", "episodes"))
-			b.foundEpisodesChannel <- episodes
+			select {
+			case b.foundEpisodesChannel <- episodes:
+			case <-ctx.Done():
+			}
 		}
 
 		return nil
 	}
 }
 
-func (b *statefulBubble) waitForEpisodes() tea.Cmd {
+// setEpisodes shows the episodes of the given audio variant, falling back to the first
+// variant the source offers, and returns the episodes now listed.
+func (b *statefulBubble) setEpisodes(episodes []*source.Episode, translation string) ([]*source.Episode, tea.Cmd) {
+	source.SortEpisodes(episodes)
+
+	b.loadedEpisodes = episodes
+	b.translation = source.PreferredTranslation(source.Translations(episodes), translation)
+	shown := source.FilterTranslation(episodes, b.translation)
+
+	// Marks never carry over to another variant
+	b.selectedEpisodes = make(map[*source.Episode]struct{})
+
+	if b.translation != "" {
+		b.episodesC.Title = fmt.Sprintf("Episodes (%s)", strings.ToUpper(b.translation))
+	} else {
+		b.episodesC.Title = "Episodes"
+	}
+
+	items := make([]list.Item, len(shown))
+	for i, e := range shown {
+		items[i] = &listItem{internal: e}
+	}
+
+	return shown, b.episodesC.SetItems(items)
+}
+
+func (b *statefulBubble) waitForEpisodes(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
 		select {
 		case found := <-b.foundEpisodesChannel:
@@ -306,61 +477,118 @@ func (b *statefulBubble) waitForEpisodes() tea.Cmd {
 		case err := <-b.errorChannel:
 			b.lastError = err
 			return err
+		case <-ctx.Done():
+			return nil
 		}
 	}
 }
 
-func (b *statefulBubble) readEpisode(episode *source.Episode) tea.Cmd {
+// readEpisode plays the video of the episode preferred by the stream policy,
+// or lets the user pick one when the policy asks to.
+func (b *statefulBubble) readEpisode(ctx context.Context, episode *source.Episode) tea.Cmd {
+	return b.fetchStreams(ctx, episode, false)
+}
+
+// pickStream lets the user choose which video of the episode to play.
+func (b *statefulBubble) pickStream(ctx context.Context, episode *source.Episode) tea.Cmd {
+	return b.fetchStreams(ctx, episode, true)
+}
+
+func (b *statefulBubble) fetchStreams(ctx context.Context, episode *source.Episode, pick bool) tea.Cmd {
 	return func() tea.Msg {
 		b.currentPlayingEpisode = episode
 
 		// Save to history.
 		_ = history.Save(episode, 0.0)
 
-		title := fmt.Sprintf("%s - %s", episode.Anime.Name, episode.Name)
-		log.Infof("Playing %s via mpv IPC", title)
-
-		if b.mpvPlayer == nil {
-			if viper.GetString(key.Player) == "iina" && runtime.GOOS == "darwin" {
-				b.mpvPlayer = player.NewIINA()
-			} else {
-				b.mpvPlayer = player.NewMPV()
-			}
+		log.Infof("Fetching videos for episode %s", episode.Name)
+		videos, err := source.VideosOfAny(ctx, episode)
+		if ctx.Err() != nil {
+			log.Infof("playback of %s cancelled", episode.Name)
+			return nil
 		}
 
-		videoURL := episode.URL
-		var headers map[string]string
+		// A script out of budget is broken, playing the episode page instead would only hide it
+		if source.IsBudgetError(err) {
+			log.Error(err)
+			return err
+		}
 
-		log.Infof("Fetching videos for episode %s", episode.Name)
-		videos, err := episode.Source().VideosOf(episode)
-		if err == nil && len(videos) > 0 {
-			videoURL = videos[0].URL
-			headers = videos[0].Headers
-			if videos[0].Quality != "" {
-				log.Infof("Selected video: %s (%s)", videoURL, videos[0].Quality)
-			} else {
-				log.Infof("Selected video: %s", videoURL)
-			}
-		} else {
+		if err != nil || len(videos) == 0 {
 			if err != nil {
 				log.Warnf("VideosOf failed: %v, falling back to episode URL", err)
 			} else {
 				log.Warnf("VideosOf returned no videos, falling back to episode URL")
 			}
+			return b.playStream(episode, nil)
 		}
 
-		b.syncGuard = &atomic.Bool{}
-		if activeTracker, err := b.getActiveTracker(); err == nil && activeTracker != nil {
-			trackerID, totalEpisodes := b.getTrackerMetadata(episode.Anime)
-			b.mpvPlayer.SetTrackerContext(activeTracker, trackerID, int(episode.Index), totalEpisodes, b.syncGuard)
+		videos = stream.ProbeFromConfig(ctx, videos)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		policy := stream.PolicyFromConfig()
+		ranked := policy.Rank(videos)
+		if len(ranked) == 0 {
+			_, err := policy.Select(videos)
+			return err
 		}
 
-		return playSyncMsg{
-			url:     videoURL,
-			title:   title,
-			headers: headers,
+		if pick || (policy.Ask && len(ranked) > 1) {
+			return streamChoicesMsg{episode: episode, videos: ranked}
+		}
+
+		return b.playStream(episode, ranked)
+	}
+}
+
+// playStream prepares the player for the episode and hands the terminal over to it.
+// The videos are tried in order until one plays; none plays the episode URL itself.
+func (b *statefulBubble) playStream(episode *source.Episode, videos []*source.Video) tea.Msg {
+	if b.mpvPlayer == nil {
+		b.mpvPlayer = player.New()
+	}
+
+	b.syncGuard = &atomic.Bool{}
+	if activeTracker, err := b.getActiveTracker(); err == nil && activeTracker != nil {
+		if trackerID, progress, totalEpisodes, ok := b.getTrackerTarget(episode); ok {
+			b.mpvPlayer.SetTrackerContext(activeTracker, trackerID, progress, totalEpisodes, b.syncGuard)
 		}
 	}
+
+	return playSyncMsg{
+		episode: episode,
+		videos:  videos,
+	}
+}
+
+// playVideos plays the first of the videos that works, falling back to the next one
+// when a link is dead and fetching fresh links once if all of them are.
+func (b *statefulBubble) playVideos(episode *source.Episode, videos []*source.Video) error {
+	title := fmt.Sprintf("%s - %s", episode.Anime.Name, episode.Name)
+	log.Infof("Playing %s via mpv", title)
+
+	if len(videos) == 0 {
+		b.mpvPlayer.SetSubtitles(nil)
+		b.mpvPlayer.SetMaxBitrate(0)
+		b.mpvPlayer.SetRefresher(nil)
+		return b.mpvPlayer.PlaySync(episode.URL, title, nil)
+	}
+
+	play := func(video *source.Video) error {
+		log.Infof("Selected video: %s (%s)", video.URL, stream.Label(video))
+		b.mpvPlayer.SetSubtitles(stream.Subtitles(video))
+		b.mpvPlayer.SetMaxBitrate(video.Bandwidth)
+		b.mpvPlayer.SetRefresher(stream.Refresher(episode, video))
+		return b.mpvPlayer.PlaySync(video.URL, title, video.Headers)
+	}
+
+	refresh := func(ctx context.Context) ([]*source.Video, error) {
+		return stream.Fetch(ctx, episode)
+	}
+
+	return stream.PlayFirst(context.Background(), videos, play, refresh)
 }
 
 func (b *statefulBubble) waitForEpisodeRead() tea.Cmd {
@@ -375,6 +603,12 @@ func (b *statefulBubble) waitForEpisodeRead() tea.Cmd {
 	}
 }
 
+// streamChoicesMsg asks the user to pick one of the playable videos of an episode.
+type streamChoicesMsg struct {
+	episode *source.Episode
+	videos  []*source.Video // Ranked by the stream policy, most preferred first
+}
+
 type mpvExitMsg struct {
 	Percentage float64
 }
@@ -4,6 +4,7 @@ package tui
 import (
 	"fmt"
 
+	"github.com/anisan-cli/anisan/health"
 	"github.com/anisan-cli/anisan/key"
 	"github.com/anisan-cli/anisan/provider"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -27,6 +28,9 @@ func (b *statefulBubble) Init() tea.Cmd {
 			providers = append(providers, p)
 		}
 
+		// Sources that have been working lately come first
+		health.Sort(providers, func(p *provider.Provider) string { return p.Name })
+
 		// If exactly one source is loaded, inject it into the Anime list title
 		if len(providers) == 1 {
 			b.animesC.Title = fmt.Sprintf("Anime Results - %s", providers[0].Name)
@@ -6,14 +6,17 @@ import (
 	"strings"
 
 	"github.com/anisan-cli/anisan/anilist"
+	"github.com/anisan-cli/anisan/health"
 	"github.com/anisan-cli/anisan/history"
 	"github.com/anisan-cli/anisan/icon"
 	"github.com/anisan-cli/anisan/key"
 	"github.com/anisan-cli/anisan/mal"
 	"github.com/anisan-cli/anisan/provider"
 	"github.com/anisan-cli/anisan/source"
+	"github.com/anisan-cli/anisan/stream"
 	"github.com/anisan-cli/anisan/style"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/samber/lo"
 	"github.com/spf13/viper"
 )
 
@@ -23,6 +26,9 @@ type listItem struct {
 	marked   bool
 }
 
+// loadMoreItem is appended to the anime results while sources report further pages.
+type loadMoreItem struct{}
+
 func (t *listItem) toggleMark() {
 	t.marked = !t.marked
 }
@@ -47,6 +53,10 @@ func (t *listItem) Title() (title string) {
 		var sb = strings.Builder{}
 
 		sb.WriteString(t.FilterValue())
+		if !e.IsRegular() {
+			sb.WriteString(" ")
+			sb.WriteString(lipgloss.NewStyle().Foreground(style.Peach).Render(string(e.Kind)))
+		}
 		if e.Volume != "" {
 			sb.WriteString(" ")
 			sb.WriteString(style.Faint(e.Volume))
@@ -63,6 +73,10 @@ func (t *listItem) Title() (title string) {
 		title = e.AnimeName
 	case *mal.Anime:
 		title = e.Title
+	case *source.Video:
+		title = lo.Ternary(e.Quality != "", e.Quality, "Unknown quality")
+	case loadMoreItem:
+		title = style.Faint("Load more results...")
 	case string:
 		title = e
 	default:
@@ -119,13 +133,22 @@ func (t *listItem) Description() (description string) {
 			parts = append(parts, lipgloss.NewStyle().Foreground(style.FaintColor).Render(fmt.Sprintf("%d eps", e.Metadata.Episodes)))
 		}
 
+		// Number of sources the anime was found in, when grouped
+		if len(e.Alternatives) > 0 {
+			parts = append(parts, lipgloss.NewStyle().Foreground(style.FaintColor).Render(fmt.Sprintf("%d sources", len(e.Alternatives)+1)))
+		}
+
 		description = strings.Join(parts, " • ")
 
 	case *history.SavedEpisode:
 		var parts []string
 
 		// Display the specific episode index for historical reference.
-		parts = append(parts, fmt.Sprintf("Ep: %d", e.Index))
+		if e.Translation != "" {
+			parts = append(parts, fmt.Sprintf("Ep: %s (%s)", e.EpisodeNumber(), e.Translation))
+		} else {
+			parts = append(parts, fmt.Sprintf("Ep: %s", e.EpisodeNumber()))
+		}
 
 		// Status indicator.
 		if e.Status != "" {
@@ -178,11 +201,34 @@ func (t *listItem) Description() (description string) {
 			sb.WriteString("Built-in Provider")
 		}
 
+		if e.Version != "" {
+			sb.WriteString(" v" + e.Version)
+		}
+
+		if e.Language != "" {
+			sb.WriteString(" • " + e.Language)
+		}
+
 		if e.UsesHeadless {
 			sb.WriteString(" (Requires Headless Chrome)")
 		}
 
+		if e.Err != nil {
+			sb.WriteString(" " + lipgloss.NewStyle().Foreground(style.Red).Render("(Unavailable)"))
+		} else if record := health.Get(e.Name); record.Failing() {
+			sb.WriteString(" " + lipgloss.NewStyle().Foreground(style.WarningColor).Render("(Failing: "+record.LastError+")"))
+		}
+
 		description = sb.String()
+	case *source.Video:
+		parts := []string{stream.Container(e), stream.Host(e)}
+		if e.Bandwidth > 0 {
+			parts = append(parts, fmt.Sprintf("%.1f Mbps", float64(e.Bandwidth)/1e6))
+		}
+		if len(e.Subtitles) > 0 {
+			parts = append(parts, fmt.Sprintf("%d subtitles", len(e.Subtitles)))
+		}
+		description = strings.Join(lo.Compact(parts), " • ")
 	case *anilist.Anime:
 		description = e.SiteURL
 	case *mal.Anime:
@@ -216,6 +262,8 @@ func (t *listItem) FilterValue() string {
 		return e.Name()
 	case *provider.Provider:
 		return e.Name
+	case *source.Video:
+		return stream.Label(e)
 	case *mal.Anime:
 		return e.Title
 	case string:
@@ -26,6 +26,7 @@ type statefulKeymap struct {
 	top, bottom,
 	nextEp, prevEp, playPause, replay,
 	manualID, saveAsDefault, changeSource,
+	toggleAudio, chooseStream,
 	showHelp key.Binding // Bound exclusively to '?' to prevent h/j/k/l navigation conflicts.
 }
 
@@ -132,6 +133,14 @@ func newStatefulKeymap() *statefulKeymap {
 			key.WithKeys("r"),
 			key.WithHelp("r", "replay"),
 		),
+		toggleAudio: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "sub/dub"),
+		),
+		chooseStream: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "choose stream"),
+		),
 		showHelp: key.NewBinding(
 			key.WithKeys("?"),
 			key.WithHelp("?", "help"),
@@ -173,9 +182,11 @@ func (k *statefulKeymap) help() ([]key.Binding, []key.Binding) {
 	case animesState:
 		return to2(h(k.confirm, k.changeSource, k.back))
 	case episodesState:
-		return h(k.confirm, k.trackerSelect, k.manualID, k.back), h(k.confirm, k.selectOne, k.selectAll, k.clearSelection, k.openURL, k.selectVolume, k.trackerSelect, k.back)
+		return h(k.confirm, k.toggleAudio, k.chooseStream, k.trackerSelect, k.back), h(k.confirm, k.selectOne, k.selectAll, k.clearSelection, k.openURL, k.selectVolume, k.toggleAudio, k.chooseStream, k.trackerSelect, k.manualID, k.back)
 	case trackerSelectState:
 		return to2(h(k.confirm, k.openURL, k.back))
+	case streamSelectState:
+		return to2(h(withDescription(k.confirm, "play"), k.back))
 	case readState:
 		return to2(h(k.back, k.forceQuit))
 	case errorState:
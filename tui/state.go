@@ -16,4 +16,5 @@ const (
 	readState
 	postWatchState
 	manualIDState
+	streamSelectState
 )
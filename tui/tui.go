@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"io"
+
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -22,5 +24,13 @@ func Run(options *Options) error {
 	}
 
 	_, err := tea.NewProgram(bubble, tea.WithAltScreen(), tea.WithMouseCellMotion()).Run()
+
+	// Free the Lua VMs of every source loaded during the session.
+	for _, s := range bubble.openedSources {
+		if closer, ok := s.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}
+
 	return err
 }
@@ -9,8 +9,6 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"net/http"
-	"sort"
-	"strconv"
 	"strings"
 	"time"
 
@@ -20,9 +18,9 @@ import (
 	"github.com/anisan-cli/anisan/internal/tracker"
 	"github.com/anisan-cli/anisan/internal/ui/render"
 	"github.com/anisan-cli/anisan/key"
+	"github.com/anisan-cli/anisan/log"
 	"github.com/anisan-cli/anisan/mal"
 	"github.com/anisan-cli/anisan/open"
-	"github.com/anisan-cli/anisan/player"
 	"github.com/anisan-cli/anisan/provider"
 	"github.com/anisan-cli/anisan/query"
 	"github.com/anisan-cli/anisan/source"
@@ -40,21 +38,17 @@ import (
 
 
 type playSyncMsg struct {
-	url     string
-	title   string
-	headers map[string]string
+	episode *source.Episode
+	videos  []*source.Video // Candidates, most preferred first. Empty plays the episode URL.
 }
 
 // playbackCmd implements tea.ExecCommand for seamless terminal handoff.
 type playbackCmd struct {
-	player  player.Player
-	url     string
-	title   string
-	headers map[string]string
+	play func() error
 }
 
 func (p playbackCmd) Run() error {
-	return p.player.PlaySync(p.url, p.title, p.headers)
+	return p.play()
 }
 
 func (p playbackCmd) SetStdin(r io.Reader)  {}
@@ -87,20 +81,38 @@ func (b *statefulBubble) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case playSyncMsg:
 		b.progressStatus = "" // clear "Launching..." before TTY handoff
 		return b, tea.Exec(playbackCmd{
-			player:  b.mpvPlayer,
-			url:     msg.url,
-			title:   msg.title,
-			headers: msg.headers,
+			play: func() error { return b.playVideos(msg.episode, msg.videos) },
 		}, func(err error) tea.Msg {
 			if err != nil {
 				return err
 			}
 			return nil
 		})
+	case streamChoicesMsg:
+		items := make([]list.Item, len(msg.videos))
+		for i, v := range msg.videos {
+			items[i] = &listItem{internal: v}
+		}
+		b.streamsC.Title = fmt.Sprintf("Streams - %s", msg.episode.Name)
+		b.streamsC.ResetSelected()
+		b.stopLoading()
+		b.newState(streamSelectState)
+		return b, b.streamsC.SetItems(items)
 	case *mal.Anime:
 		return b, b.applyManualTrackerUpdate(msg)
 	case *anilist.Anime:
 		return b, b.applyManualTrackerUpdate(msg)
+	case trackerSegmentMsg:
+		b.stopLoading()
+		if b.state == loadingState {
+			b.previousState()
+		}
+		notification := b.showNotification(msg.String(), 3*time.Second)
+		if msg.entry == nil {
+			return b, notification
+		}
+		// The whole anime is bound, its metadata comes from the entry.
+		return b, tea.Batch(notification, b.applyManualTrackerUpdate(msg.entry))
 	case error:
 		if msg.Error() == "sync_queued" {
 			return b, b.showNotification("Tracking offline. Queued for background sync.", 3*time.Second)
@@ -131,6 +143,8 @@ func (b *statefulBubble) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				l = &b.trackerC
 			case postWatchState:
 				l = &b.postWatchC
+			case streamSelectState:
+				l = &b.streamsC
 			}
 			if l != nil {
 				if msg.Type == tea.MouseWheelUp {
@@ -145,6 +159,7 @@ func (b *statefulBubble) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch {
 		case bubblesKey.Matches(msg, b.keymap.forceQuit):
+			b.cancelOperation()
 			return b, tea.Quit
 		case bubblesKey.Matches(msg, b.keymap.showHelp):
 			if b.state != searchState && b.state != manualIDState {
@@ -154,6 +169,13 @@ func (b *statefulBubble) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		if b.busy && b.state != readState && b.state != errorState {
+			// Going back while a source call is in flight abandons it instead of waiting it out.
+			if bubblesKey.Matches(msg, b.keymap.back) && b.cancelOperation() {
+				b.stopLoading()
+				if b.state == loadingState {
+					b.previousState()
+				}
+			}
 			return b, nil
 		}
 
@@ -199,8 +221,15 @@ func (b *statefulBubble) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return b, cmd
 				}
 				cmd = onListBack(&b.sourcesC)
+			case streamSelectState:
+				if b.streamsC.FilterState() != list.Unfiltered {
+					b.streamsC, cmd = b.streamsC.Update(msg)
+					return b, cmd
+				}
+				cmd = onListBack(&b.streamsC)
 			}
 
+			b.cancelOperation()
 			b.previousState()
 			b.stopLoading()
 			return b, cmd
@@ -228,6 +257,8 @@ func (b *statefulBubble) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return b.updatePostWatch(msg)
 	case manualIDState:
 		return b.updateManualID(msg)
+	case streamSelectState:
+		return b.updateStreamSelect(msg)
 	case errorState:
 		return b.updateError(msg)
 	}
@@ -285,20 +316,20 @@ func (b *statefulBubble) updateLoading(msg tea.Msg) (tea.Model, tea.Cmd) {
 		b.newState(trackerSelectState)
 		b.trackerC.Select(marked)
 		return b, tea.Batch(cmd, b.stopLoading())
-	case []*source.Anime:
-		items := make([]list.Item, len(msg))
-		for i, m := range msg {
-			items[i] = &listItem{internal: m}
-		}
-
-		cmds = append(cmds, b.animesC.SetItems(items))
+	case searchResultsMsg:
+		b.searchNext = msg.next
+		cmds = append(cmds, b.animesC.SetItems(animeItems(msg.animes, len(msg.next) > 0)))
 		b.newState(animesState)
 		b.stopLoading()
 
-		cmds = append(cmds, b.batchPopulateMetadata(msg))
+		cmds = append(cmds, b.batchPopulateMetadata(msg.animes))
+
+		if len(msg.animes) > 0 {
+			cmds = append(cmds, b.fetchCoverArt(msg.animes[0]))
+		}
 
-		if len(msg) > 0 {
-			cmds = append(cmds, b.fetchCoverArt(msg[0]))
+		if len(msg.failed) > 0 {
+			cmds = append(cmds, b.showNotification(failedSourcesNotice(msg.failed), 3*time.Second))
 		}
 	case []*source.Episode:
 		if b.statesHistory.Peek() == historyState {
@@ -310,6 +341,7 @@ func (b *statefulBubble) updateLoading(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case []source.Source:
 		b.selectedSources = msg
+		b.openedSources = append(b.openedSources, msg...)
 
 		if b.statesHistory.Peek() == historyState {
 			b.newState(historyState)
@@ -347,29 +379,24 @@ func (b *statefulBubble) updateHistory(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		b.progressStatus = fmt.Sprintf("Loading episodes for %s...", anime.Name)
 		b.newState(loadingState)
-		return b, tea.Batch(b.getEpisodes(anime), b.waitForEpisodes(), b.startLoading())
+		ctx := b.beginOperation()
+		return b, tea.Batch(b.getEpisodes(ctx, anime), b.waitForEpisodes(ctx), b.startLoading())
 
 	case []*source.Episode:
-		sort.Slice(msg, func(i, j int) bool {
-			if msg[i].Index != msg[j].Index {
-				return msg[i].Index < msg[j].Index
-			}
-			return msg[i].Name < msg[j].Name
-		})
-
-		items := make([]list.Item, len(msg))
-		for i, c := range msg {
-			items[i] = &listItem{internal: c}
+		// Stay on the audio variant the user was watching
+		selected := b.historyC.SelectedItem().(*listItem).internal.(*history.SavedEpisode)
+		translation := selected.Translation
+		if translation == "" {
+			translation = viper.GetString(key.PlayerPreferAudio)
 		}
 
-		cmd = b.episodesC.SetItems(items)
+		msg, cmd = b.setEpisodes(msg, translation)
 
 		// Find the episode the user was watching from history
-		selected := b.historyC.SelectedItem().(*listItem).internal.(*history.SavedEpisode)
 		var epToPlay *source.Episode
 		var epIdx int
 		for i, ep := range msg {
-			if ep.URL == selected.URL {
+			if selected.Matches(ep) {
 				epToPlay = msg[i]
 				epIdx = i
 				break
@@ -409,7 +436,7 @@ func (b *statefulBubble) updateHistory(msg tea.Msg) (tea.Model, tea.Cmd) {
 					trackerCmd = b.tryLoadAnilistCache(b.selectedAnime)
 				}
 			}
-			return b, tea.Batch(cmd, b.readEpisode(epToPlay), b.startLoading(), trackerCmd)
+			return b, tea.Batch(cmd, b.readEpisode(b.beginOperation(), epToPlay), b.startLoading(), trackerCmd)
 		} else {
 			// If no episodes were found at all, just fall back to the empty episodes list view
 			b.newState(episodesState)
@@ -628,7 +655,8 @@ func (b *statefulBubble) updateSearch(msg tea.Msg) (tea.Model, tea.Cmd) {
 			b.progressStatus = fmt.Sprintf("Searching for %s...", b.inputC.Value())
 			b.newState(loadingState)
 			go query.Remember(b.inputC.Value(), 1)
-			return b, tea.Batch(b.startLoading(), b.searchAnime(b.inputC.Value()), b.waitForAnimes(), b.spinnerC.Tick)
+			ctx := b.beginOperation()
+			return b, tea.Batch(b.startLoading(), b.searchAnime(ctx, b.inputC.Value()), b.waitForAnimes(ctx), b.spinnerC.Tick)
 		case bubblesKey.Matches(msg, b.keymap.acceptSearchSuggestion):
 			// Tab only fills the suggestion into the input — does not trigger a search.
 			if s := b.inputC.AvailableSuggestions(); len(s) > 0 {
@@ -681,28 +709,54 @@ func (b *statefulBubble) updateAnimes(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if b.animesC.SelectedItem() == nil {
 				break
 			}
+			if _, ok := b.animesC.SelectedItem().(*listItem).internal.(loadMoreItem); ok {
+				ctx := b.beginOperation()
+				return b, tea.Batch(b.searchMore(ctx), b.waitForAnimes(ctx), b.startLoading())
+			}
 			m, _ := b.animesC.SelectedItem().(*listItem).internal.(*source.Anime)
 			b.selectedAnime = m
 			b.coverArtString = "" // clear stale image
 			b.progressStatus = fmt.Sprintf("Loading episodes for %s...", m.Name)
 			go query.Remember(m.Name, 2)
-			return b, tea.Batch(b.getEpisodes(m), b.waitForEpisodes(), b.startLoading(), b.fetchCoverArt(m))
+			ctx := b.beginOperation()
+			return b, tea.Batch(b.getEpisodes(ctx, m), b.waitForEpisodes(ctx), b.startLoading(), b.fetchCoverArt(m))
 
 		}
-	case []*source.Episode:
-		sort.Slice(msg, func(i, j int) bool {
-			if msg[i].Index != msg[j].Index {
-				return msg[i].Index < msg[j].Index
+	case searchResultsMsg:
+		if !msg.more {
+			break
+		}
+		b.stopLoading()
+		b.searchNext = msg.next
+
+		// Replace the trailing "load more" entry with the new page,
+		// and the listed animes whose group grew with their copy.
+		items := lo.FilterMap(b.animesC.Items(), func(item list.Item, _ int) (list.Item, bool) {
+			switch internal := item.(*listItem).internal.(type) {
+			case loadMoreItem:
+				return nil, false
+			case *source.Anime:
+				if regrouped, ok := msg.regrouped[internal]; ok {
+					return &listItem{internal: regrouped, marked: item.(*listItem).marked}, true
+				}
 			}
-			return msg[i].Name < msg[j].Name
+			return item, true
 		})
+		first := len(items)
+		items = append(items, animeItems(msg.animes, len(msg.next) > 0)...)
 
-		items := make([]list.Item, len(msg))
-		for i, c := range msg {
-			items[i] = &listItem{internal: c}
+		cmd = b.animesC.SetItems(items)
+		if len(msg.animes) > 0 {
+			b.animesC.Select(first)
+			cmd = tea.Batch(cmd, b.fetchCoverArt(msg.animes[0]))
 		}
-
-		cmd = b.episodesC.SetItems(items)
+		if len(msg.failed) > 0 {
+			cmd = tea.Batch(cmd, b.showNotification(failedSourcesNotice(msg.failed), 3*time.Second))
+		}
+		return b, tea.Batch(cmd, b.batchPopulateMetadata(msg.animes))
+	case []*source.Episode:
+		all := msg
+		msg, cmd = b.setEpisodes(all, viper.GetString(key.PlayerPreferAudio))
 		b.coverArtString = "" // clear previous image
 		b.newState(episodesState)
 		b.stopLoading()
@@ -732,10 +786,15 @@ func (b *statefulBubble) updateAnimes(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 
+				// Continue with the same audio variant as last time
+				if lastWatched != nil && lastWatched.Translation != "" && lastWatched.Translation != b.translation {
+					msg, cmd = b.setEpisodes(all, lastWatched.Translation)
+				}
+
 				if lastWatched != nil {
 					// Find the NEXT episode in our ascending list
 					for i, e := range msg {
-						if e.URL == lastWatched.URL {
+						if lastWatched.Matches(e) {
 							if i+1 < len(msg) {
 								epToPlay = msg[i+1]
 							} else {
@@ -755,7 +814,7 @@ func (b *statefulBubble) updateAnimes(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			if epToPlay != nil {
 				b.progressStatus = fmt.Sprintf("Launching %s - %s", b.selectedAnime.Name, epToPlay.Name)
-				finalCmd = tea.Batch(cmd, b.readEpisode(epToPlay), b.fetchCoverArt(b.selectedAnime))
+				finalCmd = tea.Batch(cmd, b.readEpisode(b.beginOperation(), epToPlay), b.fetchCoverArt(b.selectedAnime))
 			} else {
 				finalCmd = tea.Batch(cmd, b.fetchCoverArt(b.selectedAnime))
 			}
@@ -860,6 +919,39 @@ func (b *statefulBubble) updateEpisodes(msg tea.Msg) (tea.Model, tea.Cmd) {
 			b.progressStatus = fmt.Sprintf("Fetching AniList for %s", b.selectedAnime.Name)
 			b.newState(loadingState)
 			return b, tea.Batch(b.startLoading(), b.fetchAnilist(b.selectedAnime), b.waitForAnilist())
+		case bubblesKey.Matches(msg, b.keymap.toggleAudio):
+			available := source.Translations(b.loadedEpisodes)
+			if len(available) < 2 {
+				return b, b.episodesC.NewStatusMessage(style.Faint("No other audio variants available"))
+			}
+
+			// Keep the cursor on the same episode number
+			var selected *source.Episode
+			if item := b.episodesC.SelectedItem(); item != nil {
+				selected = item.(*listItem).internal.(*source.Episode)
+			}
+
+			shown, cmd := b.setEpisodes(b.loadedEpisodes, source.NextTranslation(available, b.translation))
+			b.episodesC.ResetFilter()
+			if selected != nil {
+				_, i, ok := lo.FindIndexOf(shown, func(e *source.Episode) bool {
+					return e.NumberString() == selected.NumberString() && e.Kind == selected.Kind
+				})
+				if ok {
+					b.episodesC.Select(i)
+				}
+			}
+
+			return b, tea.Batch(cmd, b.episodesC.NewStatusMessage(fmt.Sprintf("Switched to %s", style.Fg(color.Orange)(b.translation))))
+		case bubblesKey.Matches(msg, b.keymap.chooseStream):
+			if b.episodesC.SelectedItem() == nil {
+				break
+			}
+			episode := b.episodesC.SelectedItem().(*listItem).internal.(*source.Episode)
+			b.progressStatus = fmt.Sprintf("Fetching streams of %s - %s", b.selectedAnime.Name, episode.Name)
+			b.currentPlayingEpisode = episode
+			b.newState(readState)
+			return b, tea.Batch(b.pickStream(b.beginOperation(), episode), b.startLoading())
 		case bubblesKey.Matches(msg, b.keymap.selectVolume):
 			if b.episodesC.SelectedItem() == nil {
 				break
@@ -920,7 +1012,7 @@ func (b *statefulBubble) updateEpisodes(msg tea.Msg) (tea.Model, tea.Cmd) {
 			b.progressStatus = fmt.Sprintf("Launching %s - %s", b.selectedAnime.Name, episode.Name)
 			b.currentPlayingEpisode = episode
 			b.newState(readState)
-			return b, tea.Batch(b.readEpisode(episode), b.waitForEpisodeRead(), b.startLoading())
+			return b, tea.Batch(b.readEpisode(b.beginOperation(), episode), b.waitForEpisodeRead(), b.startLoading())
 		case bubblesKey.Matches(msg, b.keymap.confirm):
 			if len(b.selectedEpisodes) != 0 {
 				b.newState(confirmState)
@@ -932,7 +1024,7 @@ func (b *statefulBubble) updateEpisodes(msg tea.Msg) (tea.Model, tea.Cmd) {
 				b.progressStatus = fmt.Sprintf("Launching %s - %s", b.selectedAnime.Name, episode.Name)
 				b.currentPlayingEpisode = episode
 				b.newState(readState)
-				return b, tea.Batch(b.readEpisode(episode), b.waitForEpisodeRead(), b.startLoading())
+				return b, tea.Batch(b.readEpisode(b.beginOperation(), episode), b.waitForEpisodeRead(), b.startLoading())
 			}
 		}
 	}
@@ -1064,7 +1156,7 @@ func (b *statefulBubble) updatePostWatch(msg tea.Msg) (tea.Model, tea.Cmd) {
 					b.progressStatus = fmt.Sprintf("Launching %s - %s", b.selectedAnime.Name, nextEp.Name)
 					b.currentPlayingEpisode = nextEp
 					b.newState(readState)
-					return b, tea.Batch(b.readEpisode(nextEp), b.startLoading())
+					return b, tea.Batch(b.readEpisode(b.beginOperation(), nextEp), b.startLoading())
 				}
 				// If no next episode, notify the user instead of silently returning
 				return b, b.postWatchC.NewStatusMessage("Anime completed! No further episodes.")
@@ -1073,7 +1165,7 @@ func (b *statefulBubble) updatePostWatch(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if b.currentPlayingEpisode != nil {
 					b.progressStatus = fmt.Sprintf("Launching %s - %s", b.selectedAnime.Name, b.currentPlayingEpisode.Name)
 					b.newState(readState)
-					return b, tea.Batch(b.readEpisode(b.currentPlayingEpisode), b.startLoading())
+					return b, tea.Batch(b.readEpisode(b.beginOperation(), b.currentPlayingEpisode), b.startLoading())
 				}
 
 			case "Previous":
@@ -1099,7 +1191,7 @@ func (b *statefulBubble) updatePostWatch(msg tea.Msg) (tea.Model, tea.Cmd) {
 					b.progressStatus = fmt.Sprintf("Launching %s - %s", b.selectedAnime.Name, prevEp.Name)
 					b.currentPlayingEpisode = prevEp
 					b.newState(readState)
-					return b, tea.Batch(b.readEpisode(prevEp), b.startLoading())
+					return b, tea.Batch(b.readEpisode(b.beginOperation(), prevEp), b.startLoading())
 				}
 				b.previousState()
 
@@ -1128,6 +1220,16 @@ func (b *statefulBubble) updateRead(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if b.currentPlayingEpisode != nil {
 			_ = history.Save(b.currentPlayingEpisode, msg.Percentage)
 
+			// Media servers keep their own watch state
+			go func(ep *source.Episode, percentage float64) {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				if err := source.ReportProgress(ctx, ep, percentage); err != nil {
+					log.Warnf("failed to report progress to %s: %v", ep.Source().Name(), err)
+				}
+			}(b.currentPlayingEpisode, msg.Percentage)
+
 			if activeTracker, err := b.getActiveTracker(); err == nil && activeTracker != nil {
 				// Prevent double-sync if MPVWatcher already handled it (e.g., at 100% EOF).
 				if b.syncGuard != nil && !b.syncGuard.CompareAndSwap(false, true) {
@@ -1138,9 +1240,8 @@ func (b *statefulBubble) updateRead(msg tea.Msg) (tea.Model, tea.Cmd) {
 					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 					defer cancel()
 
-					trackerID, totalEpisodes := b.getTrackerMetadata(ep.Anime)
-					if trackerID != 0 {
-						_ = activeTracker.UpdateEpisodeProgress(ctx, trackerID, int(ep.Index), totalEpisodes)
+					if trackerID, progress, totalEpisodes, ok := b.getTrackerTarget(ep); ok {
+						_ = activeTracker.UpdateEpisodeProgress(ctx, trackerID, progress, totalEpisodes)
 					}
 				}(b.currentPlayingEpisode)
 			}
@@ -1149,7 +1250,7 @@ func (b *statefulBubble) updateRead(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if b.nextEpisodeToPlay != nil {
 			ep := b.nextEpisodeToPlay
 			b.nextEpisodeToPlay = nil
-			return b, tea.Batch(b.readEpisode(ep), b.startLoading())
+			return b, tea.Batch(b.readEpisode(b.beginOperation(), ep), b.startLoading())
 		}
 
 		b.newState(postWatchState)
@@ -1164,6 +1265,7 @@ func (b *statefulBubble) updateRead(msg tea.Msg) (tea.Model, tea.Cmd) {
 			b.previousState()
 			return b, b.stopLoading()
 		case bubblesKey.Matches(msg, b.keymap.forceQuit):
+			b.cancelOperation()
 			if b.mpvPlayer != nil {
 				_ = b.mpvPlayer.Close()
 			}
@@ -1174,6 +1276,43 @@ func (b *statefulBubble) updateRead(msg tea.Msg) (tea.Model, tea.Cmd) {
 	b.spinnerC, cmd = b.spinnerC.Update(msg)
 	return b, cmd
 }
+
+func (b *statefulBubble) updateStreamSelect(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if b.streamsC.FilterState() == list.Filtering {
+			break
+		}
+
+		if bubblesKey.Matches(msg, b.keymap.confirm) {
+			item := b.streamsC.SelectedItem()
+			episode := b.currentPlayingEpisode
+			if item == nil || episode == nil {
+				break
+			}
+
+			// The chosen stream goes first, the others remain as fallbacks
+			chosen := item.(*listItem).internal.(*source.Video)
+			videos := []*source.Video{chosen}
+			for _, it := range b.streamsC.Items() {
+				if v := it.(*listItem).internal.(*source.Video); v != chosen {
+					videos = append(videos, v)
+				}
+			}
+
+			b.progressStatus = fmt.Sprintf("Launching %s - %s", b.selectedAnime.Name, episode.Name)
+			// The picker is not kept in the navigation history
+			b.setState(readState)
+			return b, tea.Batch(func() tea.Msg { return b.playStream(episode, videos) }, b.startLoading())
+		}
+	}
+
+	b.streamsC, cmd = b.streamsC.Update(msg)
+	return b, cmd
+}
+
 func (b *statefulBubble) updateError(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	switch msg := msg.(type) {
@@ -1204,9 +1343,9 @@ func (b *statefulBubble) updateManualID(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return b, nil
 			}
 
-			id, err := strconv.Atoi(idStr)
+			segment, err := tracker.ParseSegment(idStr)
 			if err != nil {
-				b.raiseError(fmt.Errorf("invalid ID: %s must be a number", idStr))
+				b.raiseError(err)
 				return b, nil
 			}
 
@@ -1214,33 +1353,51 @@ func (b *statefulBubble) updateManualID(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return b, nil
 			}
 
-			cleanName := b.selectedAnime.Name
-			if idx := strings.LastIndex(cleanName, "("); idx != -1 {
-				cleanName = strings.TrimSpace(cleanName[:idx])
-			}
+			cleanName := relationName(b.selectedAnime)
+			backend := viper.GetString("tracker.backend")
+			id, split := segment.ID, segment.Split()
 
 			b.progressStatus = "Fetching metadata..."
 			b.newState(loadingState)
 			return b, tea.Batch(b.startLoading(), func() tea.Msg {
-				if viper.GetString("tracker.backend") == "mal" {
+				var (
+					title string
+					entry any
+				)
+				if backend == "mal" {
 					m, err := mal.GetByID(id)
 					if err != nil {
 						return fmt.Errorf("failed to fetch MAL metadata for ID %d: %w", id, err)
 					}
-					if err := mal.SetRelation(cleanName, m); err != nil {
-						return err
+					if !split {
+						if err := mal.SetRelation(cleanName, m); err != nil {
+							return err
+						}
+						entry = m
+					}
+					title = m.Title
+				} else {
+					al, err := anilist.GetByID(id)
+					if err != nil {
+						return fmt.Errorf("failed to fetch Anilist metadata for ID %d: %w", id, err)
 					}
-					return m
+					if !split {
+						if err := anilist.SetRelation(cleanName, al); err != nil {
+							return err
+						}
+						entry = al
+					}
+					title = al.Name()
 				}
 
-				al, err := anilist.GetByID(id)
-				if err != nil {
-					return fmt.Errorf("failed to fetch Anilist metadata for ID %d: %w", id, err)
+				if !split {
+					segment.ID = 0
 				}
-				if err := anilist.SetRelation(cleanName, al); err != nil {
+				if err := tracker.SetSegment(backend, cleanName, segment); err != nil {
 					return err
 				}
-				return al
+
+				return trackerSegmentMsg{segment: segment, title: title, entry: entry}
 			})
 
 		case msg.Type == tea.KeyEsc:
@@ -1496,3 +1653,61 @@ func (b *statefulBubble) getTrackerMetadata(anime *source.Anime) (trackerID int,
 	}
 	return 0, 0
 }
+
+// getTrackerTarget resolves the tracker entry and episode number the episode counts as,
+// following the episode ranges bound to the anime. Specials and recaps count as nothing.
+func (b *statefulBubble) getTrackerTarget(episode *source.Episode) (trackerID, progress, totalEpisodes int, ok bool) {
+	progress, counted := episode.Progress()
+	if !counted {
+		return 0, 0, 0, false
+	}
+
+	baseID, baseTotal := b.getTrackerMetadata(episode.Anime)
+	backend := viper.GetString("tracker.backend")
+	trackerID, progress, ok = tracker.Resolve(backend, relationName(episode.Anime), baseID, progress)
+	if !ok {
+		return 0, 0, 0, false
+	}
+
+	if trackerID == baseID {
+		return trackerID, progress, baseTotal, true
+	}
+
+	if backend == "mal" {
+		if m, err := mal.GetByID(trackerID); err == nil {
+			totalEpisodes = m.NumEpisodes
+		}
+	} else if al, err := anilist.GetByID(trackerID); err == nil {
+		totalEpisodes = al.Episodes
+	}
+
+	return trackerID, progress, totalEpisodes, true
+}
+
+// relationName is the name the anime is bound to tracker entries by.
+func relationName(anime *source.Anime) string {
+	name := anime.Name
+	if idx := strings.LastIndex(name, "("); idx != -1 {
+		name = strings.TrimSpace(name[:idx])
+	}
+	return name
+}
+
+// trackerSegmentMsg reports a range of episodes bound to a tracker entry.
+type trackerSegmentMsg struct {
+	segment tracker.Segment
+	title   string
+	entry   any // The *anilist.Anime or *mal.Anime, when the whole anime is bound to it
+}
+
+func (m trackerSegmentMsg) String() string {
+	s := m.segment
+	switch {
+	case s.From == 0 && s.To == 0 && s.Offset == 0:
+		return "Linked to " + m.title
+	case s.To == 0:
+		return fmt.Sprintf("Episodes %d+ count as %d+ of %s", max(s.From, 1), max(s.From, 1)-s.Offset, m.title)
+	default:
+		return fmt.Sprintf("Episodes %d-%d count as %d-%d of %s", max(s.From, 1), s.To, max(s.From, 1)-s.Offset, s.To-s.Offset, m.title)
+	}
+}
@@ -42,6 +42,8 @@ func (b *statefulBubble) View() string {
 		output = b.viewPostWatch()
 	case manualIDState:
 		output = b.viewManualID()
+	case streamSelectState:
+		output = b.viewStreams()
 	case errorState:
 		output = b.viewError()
 	default:
@@ -146,6 +148,10 @@ func (b *statefulBubble) viewPostWatch() string {
 	return postWatchView
 }
 
+func (b *statefulBubble) viewStreams() string {
+	return listExtraPaddingStyle.Render(b.streamsC.View())
+}
+
 func (b *statefulBubble) viewRead() string {
 	var episodeName string
 
@@ -207,6 +213,8 @@ func (b *statefulBubble) viewManualID() string {
 		"",
 		b.idInputC.View(),
 		"",
+		style.Faint("Add a range for episodes of another season, e.g. 12345 25-48"),
+		style.Faint("or an offset for absolute numbering, e.g. 12345 24"),
 		style.Faint("(Enter to confirm, Esc to cancel)"),
 	}
 
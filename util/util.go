@@ -47,6 +47,11 @@ func TerminalSize() (width, height int, err error) {
 	return term.GetSize(int(os.Stdout.Fd()))
 }
 
+// IsTerminal reports whether f is a terminal, e.g. whether os.Stdin can be prompted.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
 func FileStem(path string) string {
 	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 }
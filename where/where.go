@@ -62,6 +62,26 @@ func AnilistBinds() string {
 	return filepath.Join(Config(), "anilist.json")
 }
 
+// TrackerOffsets resolves the absolute path to the registry of episode ranges bound to tracker entries.
+func TrackerOffsets() string {
+	return filepath.Join(Config(), "tracker_offsets.json")
+}
+
+// Grants resolves the absolute path to the registry of permissions granted to custom sources.
+func Grants() string {
+	return filepath.Join(Config(), "grants.json")
+}
+
+// SourceStore resolves the absolute path to the key/value store custom sources keep their state in.
+func SourceStore() string {
+	return filepath.Join(Config(), "source_store.json")
+}
+
+// Cassettes resolves the absolute path to the directory of recorded source test fixtures.
+func Cassettes() string {
+	return ensureDir(filepath.Join(Config(), "cassettes"))
+}
+
 // MalBinds returns the absolute path to the directory containing cached MyAnimeList relation mappings.
 func MalBinds() string {
 	// Assumes Config() resolves the base configuration directory (e.g., ~/.config/anisan)
@@ -73,6 +93,11 @@ func Queries() string {
 	return filepath.Join(Cache(), "queries.json")
 }
 
+// SourceHealth resolves the absolute path to the registry of source reliability records.
+func SourceHealth() string {
+	return filepath.Join(Cache(), "health.json")
+}
+
 // Temp resolves a unique, volatile filesystem path for transient application artifacts.
 func Temp() string {
 	return ensureDir(filepath.Join(os.TempDir(), constant.Anisan))